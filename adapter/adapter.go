@@ -0,0 +1,87 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package adapter wires an evaluator of a configuration language, a patch and an exporter
+// into one step: the source is evaluated to json, patched, and optionally exported back.
+//
+// The package does not evaluate or export any configuration language itself,
+// CUE and Jsonnet are not supported out of the box, since the module has no dependencies.
+// Callers provide the language support by implementing Evaluator and Exporter,
+// e.g. with cuelang.org/go or github.com/google/go-jsonnet in their own module.
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// Evaluator evaluates a configuration source to a json document.
+type Evaluator interface {
+	Evaluate(ctx context.Context, src []byte) ([]byte, error)
+}
+
+// Exporter encodes a json document back to the configuration language.
+type Exporter interface {
+	Export(ctx context.Context, doc []byte) ([]byte, error)
+}
+
+// EvaluatorFunc is a function implements Evaluator.
+type EvaluatorFunc func(ctx context.Context, src []byte) ([]byte, error)
+
+// Evaluate implements Evaluator.
+func (f EvaluatorFunc) Evaluate(ctx context.Context, src []byte) ([]byte, error) {
+	return f(ctx, src)
+}
+
+// ExporterFunc is a function implements Exporter.
+type ExporterFunc func(ctx context.Context, doc []byte) ([]byte, error)
+
+// Export implements Exporter.
+func (f ExporterFunc) Export(ctx context.Context, doc []byte) ([]byte, error) {
+	return f(ctx, doc)
+}
+
+// Adapter applies patches to configuration sources.
+type Adapter struct {
+	// Patch is used to apply patches, jsonpatch.New() is used if it is nil.
+	Patch     *jsonpatch.Patch
+	Evaluator Evaluator
+	// Exporter is required by ApplyExport only.
+	Exporter Exporter
+}
+
+// Apply evaluates src to json and applies ops to it.
+func (a *Adapter) Apply(ctx context.Context, src []byte, ops []jsonpatch.Operation) ([]byte, error) {
+	if a.Evaluator == nil {
+		return nil, errors.New("adapter has no evaluator")
+	}
+	doc, err := a.Evaluator.Evaluate(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate source: %w", err)
+	}
+	p := a.Patch
+	if p == nil {
+		p = jsonpatch.New()
+	}
+	return p.Apply(doc, ops)
+}
+
+// ApplyExport is Apply and exports the result back to the configuration language.
+func (a *Adapter) ApplyExport(ctx context.Context, src []byte, ops []jsonpatch.Operation) ([]byte, error) {
+	if a.Exporter == nil {
+		return nil, errors.New("adapter has no exporter")
+	}
+	doc, err := a.Apply(ctx, src, ops)
+	if err != nil {
+		return nil, err
+	}
+	out, err := a.Exporter.Export(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("export document: %w", err)
+	}
+	return out, nil
+}
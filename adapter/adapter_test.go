@@ -0,0 +1,36 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func TestAdapter(t *testing.T) {
+	// a fake language whose sources are json documents prefixed by "cfg ".
+	a := &Adapter{
+		Evaluator: EvaluatorFunc(func(_ context.Context, src []byte) ([]byte, error) {
+			return bytes.TrimPrefix(src, []byte("cfg ")), nil
+		}),
+		Exporter: ExporterFunc(func(_ context.Context, doc []byte) ([]byte, error) {
+			return append([]byte("cfg "), bytes.TrimSpace(doc)...), nil
+		}),
+	}
+	var ops []jsonpatch.Operation
+	if err := json.Unmarshal([]byte(`[{"op":"add","path":"/b","value":2}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	out, err := a.ApplyExport(context.Background(), []byte(`cfg {"a":1}`), ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `cfg {"a":1,"b":2}` {
+		t.Fatal("bad output", string(out))
+	}
+}
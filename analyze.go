@@ -0,0 +1,80 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+// Analysis is the statistics of a patch.
+type Analysis struct {
+	// Counts is the number of operations by op name.
+	Counts map[string]int
+	// MaxDepth is the max number of tokens of all path and from pointers.
+	MaxDepth int
+	// Subtrees is the sorted list of top-level pointers touched by the patch,
+	// it contains "" if an operation touches the whole document.
+	Subtrees []string
+	// NodesWritten is the estimated number of nodes written by the patch.
+	// Values of add and replace are counted node by node, other writes count one node each,
+	// a move counts as a remove and an add.
+	NodesWritten int
+	// WriteAmplification is NodesWritten per operation.
+	WriteAmplification float64
+}
+
+// Analyze returns the statistics of ops without a document.
+func Analyze(ops []Operation) *Analysis {
+	a := &Analysis{Counts: map[string]int{}}
+	subtrees := map[string]bool{}
+	for _, op := range ops {
+		if op.OP == nil || op.Path == nil {
+			continue
+		}
+		a.Counts[*op.OP]++
+		for _, ptr := range []*string{op.Path, op.From} {
+			if ptr == nil {
+				continue
+			}
+			tokens := NewJSONPointer(*ptr).Path()
+			if len(tokens) > a.MaxDepth {
+				a.MaxDepth = len(tokens)
+			}
+			if len(tokens) == 0 {
+				subtrees[""] = true
+			} else {
+				subtrees["/"+EscapeToken(tokens[0])] = true
+			}
+		}
+		a.NodesWritten += nodesWritten(op)
+	}
+	a.Subtrees = setToSortedSlice(subtrees)
+	if len(ops) > 0 {
+		a.WriteAmplification = float64(a.NodesWritten) / float64(len(ops))
+	}
+	return a
+}
+
+func nodesWritten(op Operation) int {
+	if isTestOP(*op.OP) {
+		return 0
+	}
+	switch *op.OP {
+	case opMove:
+		return 2
+	case opAdd, opReplace:
+		if op.Value == nil {
+			return 1
+		}
+		return countNodes(*op.Value)
+	default:
+		return 1
+	}
+}
+
+func countNodes(o any) int {
+	var n int
+	_ = walk(o, "", func(string, any) error {
+		n++
+		return nil
+	})
+	return n
+}
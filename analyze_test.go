@@ -0,0 +1,35 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[
+		{"op":"add","path":"/a/b/c","value":{"x":[1,2]}},
+		{"op":"test","path":"/d","value":1},
+		{"op":"move","path":"/e~1f","from":"/a/b"},
+		{"op":"remove","path":"/a/0"}
+	]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	a := Analyze(ops)
+	if !reflect.DeepEqual(a.Counts, map[string]int{"add": 1, "test": 1, "move": 1, "remove": 1}) {
+		t.Fatal("bad counts", a.Counts)
+	}
+	if a.MaxDepth != 3 {
+		t.Fatal("expected max depth 3, got", a.MaxDepth)
+	}
+	if !reflect.DeepEqual(a.Subtrees, []string{"/a", "/d", "/e~1f"}) {
+		t.Fatal("bad subtrees", a.Subtrees)
+	}
+	if a.NodesWritten != 7 || a.WriteAmplification != 7.0/4 {
+		t.Fatal("bad write estimation", a.NodesWritten, a.WriteAmplification)
+	}
+}
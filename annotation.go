@@ -0,0 +1,51 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import "fmt"
+
+// WithAnnotations set the Annotations option.
+// The default value is false.
+// The "comment" and "label" members of operations are always decoded and encoded,
+// if Annotations is true, they are also rendered by Describe and errors,
+// and reported in the Result of Simulate.
+func WithAnnotations(on bool) Option {
+	return func(o *Patch) {
+		o.Annotations = on
+	}
+}
+
+// StripAnnotations returns a copy of ops without comments and labels,
+// so it can be emitted as a strict RFC6902 document.
+func StripAnnotations(ops []Operation) []Operation {
+	r := make([]Operation, len(ops))
+	for i, op := range ops {
+		r[i] = op
+		r[i].Comment = nil
+		r[i].Label = nil
+	}
+	return r
+}
+
+// annotate appends the label and comment of op to desc if Annotations is true.
+func (p *Patch) annotate(desc string, op Operation) string {
+	if !p.Annotations {
+		return desc
+	}
+	if op.Label != nil {
+		desc = fmt.Sprintf("%s label=%s", desc, *op.Label)
+	}
+	if op.Comment != nil {
+		desc = fmt.Sprintf("%s comment=%q", desc, *op.Comment)
+	}
+	return desc
+}
+
+func annotation(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
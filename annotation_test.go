@@ -0,0 +1,44 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnnotations(t *testing.T) {
+	ops := mustOperations(t, `[
+		{"op":"add","path":"/a","value":1,"label":"init","comment":"add a"},
+		{"op":"remove","path":"/b","comment":"b is deprecated"}
+	]`)
+	if got := strings.TrimSpace(jsonstring(ops)); got != `[{"op":"add","path":"/a","value":1,"comment":"add a","label":"init"},{"op":"remove","path":"/b","comment":"b is deprecated"}]` {
+		t.Fatal("annotations must be preserved", got)
+	}
+	if got := strings.TrimSpace(jsonstring(StripAnnotations(ops))); got != `[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/b"}]` {
+		t.Fatal("annotations must be stripped", got)
+	}
+	if ops[0].Label == nil {
+		t.Fatal("StripAnnotations must not change ops")
+	}
+
+	if got := New().Describe(ops[0]); got != "add /a value=1" {
+		t.Fatal("bad description", got)
+	}
+	p := New(WithAnnotations(true))
+	if got := p.Describe(ops[0]); got != `add /a value=1 label=init comment="add a"` {
+		t.Fatal("bad description", got)
+	}
+	_, r, err := p.Simulate(map[string]any{"b": 1.0}, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Operations[0].Label != "init" || r.Operations[1].Comment != "b is deprecated" {
+		t.Fatal("bad result", r.Operations)
+	}
+	_, err = p.Apply([]byte(`{}`), ops)
+	if err == nil || !strings.Contains(err.Error(), "b is deprecated") {
+		t.Fatal("expected error with comment, got", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"strconv"
+)
+
+// Anonymize replaces the strings and numbers of doc and ops with synthetic values,
+// so a failing document and patch can be shared without leaking data.
+// Equal values are replaced by equal values, so test operations still behave the same.
+// If keys is true, object keys are replaced too and the pointers of ops are rewritten accordingly,
+// keys look like array indices or "-" are kept for the pointers are ambiguous.
+// Booleans, nulls and the structure are kept. Neither doc nor ops is modified.
+func Anonymize(doc any, ops []Operation, keys bool) (any, []Operation) {
+	a := &anonymizer{
+		keys:    keys,
+		strings: map[string]string{},
+		numbers: map[float64]float64{},
+		names:   map[string]string{},
+	}
+	doc = a.value(doc)
+	r := copyOperations(ops)
+	for i := range r {
+		op := &r[i]
+		if op.Path != nil {
+			path := a.pointer(*op.Path)
+			op.Path = &path
+		}
+		if op.From != nil {
+			from := a.pointer(*op.From)
+			op.From = &from
+		}
+		if op.Value != nil {
+			v := a.value(*op.Value)
+			op.Value = &v
+		}
+	}
+	return doc, r
+}
+
+type anonymizer struct {
+	keys    bool
+	strings map[string]string
+	numbers map[float64]float64
+	names   map[string]string
+}
+
+func (a *anonymizer) value(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for _, k := range sortedKeys(v) {
+			m[a.key(k)] = a.value(v[k])
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = a.value(e)
+		}
+		return s
+	case string:
+		s, ok := a.strings[v]
+		if !ok {
+			s = "s" + strconv.Itoa(len(a.strings)+1)
+			a.strings[v] = s
+		}
+		return s
+	case float64:
+		n, ok := a.numbers[v]
+		if !ok {
+			n = float64(len(a.numbers) + 1)
+			a.numbers[v] = n
+		}
+		return n
+	default:
+		return v
+	}
+}
+
+func (a *anonymizer) key(k string) string {
+	if !a.keys || k == "-" {
+		return k
+	}
+	if _, err := strconv.ParseUint(k, 10, 64); err == nil {
+		return k
+	}
+	s, ok := a.names[k]
+	if !ok {
+		s = "k" + strconv.Itoa(len(a.names)+1)
+		a.names[k] = s
+	}
+	return s
+}
+
+func (a *anonymizer) pointer(ptr string) string {
+	if !a.keys {
+		return ptr
+	}
+	var s string
+	for _, token := range NewJSONPointer(ptr).Path() {
+		s += "/" + EscapeToken(a.key(token))
+	}
+	return s
+}
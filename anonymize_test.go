@@ -0,0 +1,54 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"name":"alice","tags":["x","alice"],"age":30,"0":true,"a/b":null}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	origin := deepCopy(doc)
+	ops := mustOperations(t, `[
+		{"op":"test","path":"/tags/1","value":"alice"},
+		{"op":"copy","from":"/a~1b","path":"/secret"},
+		{"op":"replace","path":"/age","value":31},
+		{"op":"test","path":"/0","value":false}
+	]`)
+	adoc, aops := Anonymize(doc, ops, true)
+	expected := map[string]any{"k1": nil, "k2": 1.0, "k3": "s1", "k4": []any{"s2", "s1"}, "0": true}
+	if !reflect.DeepEqual(adoc, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(adoc))
+	}
+	expectedOps := mustOperations(t, `[
+		{"op":"test","path":"/k4/1","value":"s1"},
+		{"op":"copy","from":"/k1","path":"/k5"},
+		{"op":"replace","path":"/k2","value":2},
+		{"op":"test","path":"/0","value":false}
+	]`)
+	if !reflect.DeepEqual(aops, expectedOps) {
+		t.Fatal("expected", jsonstring(expectedOps), "got", jsonstring(aops))
+	}
+	if !reflect.DeepEqual(doc, origin) {
+		t.Fatal("document is modified")
+	}
+
+	err := New().ApplyAny(&adoc, aops)
+	if !errors.Is(err, ErrStop) {
+		t.Fatal("expected the same failure, got", err)
+	}
+	err = New().ApplyAny(&adoc, aops[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := Anonymize(doc, nil, false); !reflect.DeepEqual(d.(map[string]any)["name"], "s1") {
+		t.Fatal("expected keys kept", jsonstring(d))
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+// WithIntermediateAppend set the IntermediateAppend option.
+// The default value is false.
+// If IntermediateAppend is true, a "-" token in the middle of the path of an add operation
+// appends a new element to the array and continues in it, e.g. add "/matrix/-/0" appends a new row.
+// The new element is an array if the next token is an array index or "-", or an object otherwise.
+// The raw engine is not used if IntermediateAppend is true.
+func WithIntermediateAppend(on bool) Option {
+	return func(o *Patch) {
+		o.IntermediateAppend = on
+	}
+}
+
+// visitAddParent visits the parent of path for add, appends the intermediate elements if IntermediateAppend is true.
+func (p *Patch) visitAddParent(o *any, path CompiledPointer) (any, Setter, error) {
+	parts := path.ParentPath()
+	if !p.IntermediateAppend {
+		return p.VisitPath(o, parts...)
+	}
+	var (
+		node any    = *o
+		set  Setter = func(n any) { *o = n }
+		err  error
+	)
+	for i, part := range parts {
+		a, ok := node.([]any)
+		if part != "-" || !ok {
+			if node, set, err = p.visitPathPart(node, part); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		next := path.LastToken()
+		if i+1 < len(parts) {
+			next = parts[i+1]
+		}
+		var child any = map[string]any{}
+		if isIndexToken(next) || p.isSymbolicIndex(next) {
+			child = []any{}
+		}
+		a = append(a, child)
+		set(a)
+		node, set = child, elementSetter(a, len(a)-1)
+	}
+	return node, set, nil
+}
+
+func elementSetter(a []any, i int) Setter {
+	return func(n any) { a[i] = n }
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package benchmarks provides representative documents and helpers to benchmark jsonpatch.
+package benchmarks
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// WideObject returns an object with n members.
+func WideObject(n int) map[string]any {
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		m["key"+strconv.Itoa(i)] = float64(i)
+	}
+	return m
+}
+
+// DeepObject returns an object nested depth levels.
+func DeepObject(depth int) map[string]any {
+	m := map[string]any{"value": float64(depth)}
+	for i := depth - 1; i >= 0; i-- {
+		m = map[string]any{"value": float64(i), "child": m}
+	}
+	return m
+}
+
+// LongArray returns an array with n elements.
+func LongArray(n int) []any {
+	a := make([]any, n)
+	for i := range a {
+		a[i] = float64(i)
+	}
+	return a
+}
+
+// MixedDocument returns a document that mixes objects and arrays.
+// size controls the number of members of every container.
+func MixedDocument(size int) map[string]any {
+	items := make([]any, size)
+	for i := range items {
+		items[i] = map[string]any{
+			"id":   float64(i),
+			"name": "item" + strconv.Itoa(i),
+			"tags": []any{"a", "b", "c"},
+		}
+	}
+	return map[string]any{
+		"meta":  WideObject(size),
+		"items": items,
+		"tree":  DeepObject(size),
+		"list":  LongArray(size),
+	}
+}
+
+// OpMix is the weight of every operation kind to generate.
+type OpMix struct {
+	Add     int
+	Remove  int
+	Replace int
+	Move    int
+	Copy    int
+	Test    int
+}
+
+// DefaultOpMix is a write heavy operation mix, most operations are replaces, adds and tests.
+var DefaultOpMix = OpMix{Add: 2, Remove: 1, Replace: 3, Move: 1, Copy: 1, Test: 2}
+
+func (m OpMix) weights() map[string]int {
+	return map[string]int{
+		"add": m.Add, "remove": m.Remove, "replace": m.Replace,
+		"move": m.Move, "copy": m.Copy, "test": m.Test,
+	}
+}
+
+// Operations generates n operations that can be applied to doc in order by jsonpatch.GenerateWeightedPatch.
+// The kinds of operation follow the weights of mix, the result is deterministic for the same seed.
+// It always returns n operations, some of them may be tests of the whole document
+// if no operation of mix can be applied, e.g. removes of an empty document.
+func Operations(doc any, mix OpMix, n int, seed int64) []jsonpatch.Operation {
+	return jsonpatch.GenerateWeightedPatch(doc, n, seed, mix.weights())
+}
+
+func copyDocument(o any) any {
+	b, err := json.Marshal(o)
+	if err != nil {
+		panic(err)
+	}
+	var c any
+	if err := json.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// copyOperations returns a deep copy of ops, values of ops are inserted into the document by reference,
+// so every iteration applies a fresh copy that is not modified by the earlier iterations.
+func copyOperations(ops []jsonpatch.Operation) []jsonpatch.Operation {
+	b, err := json.Marshal(ops)
+	if err != nil {
+		panic(err)
+	}
+	var c []jsonpatch.Operation
+	if err := json.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Apply benchmarks p.Apply of ops against the encoded doc.
+// Copying ops is not counted.
+func Apply(b *testing.B, p *jsonpatch.Patch, doc any, ops []jsonpatch.Operation) {
+	b.Helper()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c := copyOperations(ops)
+		b.StartTimer()
+		if _, err := p.Apply(data, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// ApplyAny benchmarks p.ApplyAny of ops against a fresh copy of doc.
+// Copying the document and ops is not counted.
+func ApplyAny(b *testing.B, p *jsonpatch.Patch, doc any, ops []jsonpatch.Operation) {
+	b.Helper()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		o := copyDocument(doc)
+		c := copyOperations(ops)
+		b.StartTimer()
+		if err := p.ApplyAny(&o, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Mix benchmarks p.ApplyAny of n operations generated by mix against doc.
+func Mix(b *testing.B, p *jsonpatch.Patch, doc any, mix OpMix, n int) {
+	b.Helper()
+	ApplyAny(b, p, doc, Operations(doc, mix, n, 1))
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package benchmarks
+
+import (
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func TestOperations(t *testing.T) {
+	doc := MixedDocument(10)
+	ops := Operations(doc, DefaultOpMix, 50, 1)
+	if len(ops) != 50 {
+		t.Fatal("expected 50 operations, got", len(ops))
+	}
+	o := copyDocument(doc)
+	if err := jsonpatch.New().ApplyAny(&o, ops); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkWideObject(b *testing.B) {
+	Mix(b, jsonpatch.New(), WideObject(10000), DefaultOpMix, 100)
+}
+
+func BenchmarkDeepObject(b *testing.B) {
+	Mix(b, jsonpatch.New(), DeepObject(100), DefaultOpMix, 100)
+}
+
+func BenchmarkLongArray(b *testing.B) {
+	Mix(b, jsonpatch.New(), map[string]any{"a": LongArray(10000)}, OpMix{Add: 1, Remove: 1}, 100)
+}
+
+func BenchmarkMixedDocument(b *testing.B) {
+	Mix(b, jsonpatch.New(), MixedDocument(100), DefaultOpMix, 100)
+}
+
+func BenchmarkApplyBytes(b *testing.B) {
+	doc := MixedDocument(100)
+	Apply(b, jsonpatch.New(), doc, Operations(doc, DefaultOpMix, 100, 1))
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PatchBundle is a patch with the metadata of its provenance and intent.
+type PatchBundle struct {
+	ID          string    `json:"id"`
+	Author      string    `json:"author,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Description string    `json:"description,omitempty"`
+	// Target selects the documents the patch is intended for, its format is defined by the application.
+	Target string `json:"target,omitempty"`
+	// IdempotencyKey identifies the patch across deliveries, see Idempotent.
+	IdempotencyKey string      `json:"idempotencyKey,omitempty"`
+	Operations     []Operation `json:"operations"`
+}
+
+// Validate validates the metadata and the operations of the bundle with p.
+func (b *PatchBundle) Validate(p *Patch) error {
+	if b.ID == "" {
+		return errors.New("patch bundle must contains an id")
+	}
+	if b.CreatedAt.IsZero() {
+		return fmt.Errorf("patch bundle %s must contains a createdAt", b.ID)
+	}
+	if len(b.Operations) == 0 {
+		return fmt.Errorf("patch bundle %s must contains operations", b.ID)
+	}
+	if err := p.Check(b.Operations); err != nil {
+		return fmt.Errorf("patch bundle %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+// EncodeBundle writes the json encoding of b to w.
+func EncodeBundle(w io.Writer, b *PatchBundle) error {
+	return json.NewEncoder(w).Encode(b)
+}
+
+// DecodeBundle reads a bundle from r and validates it with p.
+func DecodeBundle(r io.Reader, p *Patch) (*PatchBundle, error) {
+	var b PatchBundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, err
+	}
+	if err := b.Validate(p); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
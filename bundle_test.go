@@ -0,0 +1,42 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatchBundle(t *testing.T) {
+	b := &PatchBundle{
+		ID:          "b1",
+		Author:      "alice",
+		CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Description: "add a",
+		Target:      "kind=config",
+		Operations:  []Operation{newOperation(opAdd, "/a", 1.0, nil)},
+	}
+	var buf bytes.Buffer
+	if err := EncodeBundle(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeBundle(&buf, New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Fatal("expected", b, "got", got)
+	}
+	_, err = DecodeBundle(strings.NewReader(`{"id":"b2","createdAt":"2024-01-02T03:04:05Z","operations":[{"op":"bad","path":""}]}`), New())
+	if err == nil || !strings.Contains(err.Error(), "b2") {
+		t.Fatal("expected error of bad operation, got", err)
+	}
+	_, err = DecodeBundle(strings.NewReader(`{"createdAt":"2024-01-02T03:04:05Z","operations":[]}`), New())
+	if err == nil {
+		t.Fatal("expected error of missing id")
+	}
+}
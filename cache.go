@@ -0,0 +1,132 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// Cache caches the output of Apply.
+// It must be safe for concurrent use.
+type Cache interface {
+	// Get returns the cached value of key.
+	Get(key string) ([]byte, bool)
+	// Set caches the value of key.
+	Set(key string, value []byte)
+}
+
+// WithCache set the cache of Apply.
+// Apply looks up the output by the hash of the document, the hash of the operations
+// and the fingerprint of the Patch, and caches the output on miss.
+// The fingerprint covers the exported options and the identity of the Patch, since extensions
+// and other options can not be compared, so a cache shared by Patches never returns
+// the output of another Patch.
+// The operations are always checked, so a cached output is returned only if the ownership
+// and the policy allow the operations now.
+func WithCache(c Cache) Option {
+	return func(o *Patch) {
+		o.cache = c
+	}
+}
+
+func (p *Patch) applyCached(b []byte, ops []Operation) ([]byte, error) {
+	key, err := p.cacheKey(b, ops)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := p.cache.Get(key); ok {
+		// the ownership and the policy may be changed after the output is cached.
+		if err := p.Check(ops); err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), v...), nil
+	}
+	v, err := p.applyBytes(b, ops)
+	if err != nil {
+		return nil, err
+	}
+	p.cache.Set(key, append([]byte(nil), v...))
+	return v, nil
+}
+
+func (p *Patch) cacheKey(b []byte, ops []Operation) (string, error) {
+	h, err := canonicalHash(ops)
+	if err != nil {
+		return "", err
+	}
+	// the exported options may be changed after New.
+	options, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	d := sha256.Sum256(b)
+	o := sha256.Sum256(options)
+	return hex.EncodeToString(d[:]) + ":" + h + ":" + strconv.FormatUint(p.id, 10) + ":" + hex.EncodeToString(o[:]), nil
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// LRUCache is a Cache keeps the most recently used entries.
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+// NewLRUCache creates a LRUCache holds at most size entries.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// Set implements Cache.
+func (c *LRUCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value})
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
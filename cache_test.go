@@ -0,0 +1,76 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type countingCache struct {
+	*LRUCache
+	hits int
+}
+
+func (c *countingCache) Get(key string) ([]byte, bool) {
+	v, ok := c.LRUCache.Get(key)
+	if ok {
+		c.hits++
+	}
+	return v, ok
+}
+
+func TestCache(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"add","path":"/a","value":1}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	c := &countingCache{LRUCache: NewLRUCache(1)}
+	p := New(WithCache(c))
+	for i := 0; i < 3; i++ {
+		b, err := p.Apply([]byte(`{}`), ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "{\"a\":1}\n" {
+			t.Fatal("bad output", string(b))
+		}
+		b[0] = 'x'
+	}
+	if c.hits != 2 {
+		t.Fatal("expected 2 hits, got", c.hits)
+	}
+	if _, err := p.Apply([]byte(`{"b":1}`), ops); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 1 {
+		t.Fatal("expected 1 entry, got", c.Len())
+	}
+	if _, err := p.Apply([]byte(`[]`), ops); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCacheSharedByPatches(t *testing.T) {
+	c := NewLRUCache(10)
+	doc := []byte(`{"a":[1]}`)
+	ops := mustOperations(t, `[{"op":"add","path":"/a/-1","value":0}]`)
+	if _, err := New(WithCache(c), WithSupportNegativeArrayIndex(true)).Apply(doc, ops); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := New(WithCache(c)).Apply(doc, ops); err == nil {
+		t.Fatal("expected bad array index, got", string(out))
+	}
+	p := New(WithCache(c))
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"add","path":"/b","value":"<"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	// the exported options may be changed after New.
+	p.JSONEscapeHTML = true
+	out, err := p.Apply(doc, mustOperations(t, `[{"op":"add","path":"/b","value":"<"}]`))
+	if err != nil || !strings.Contains(string(out), `\u003c`) {
+		t.Fatal("bad output", string(out), err)
+	}
+}
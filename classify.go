@@ -0,0 +1,130 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrorClass is the kind of an apply failure, which decides whether to retry it.
+type ErrorClass int
+
+// Error classes.
+const (
+	// ClassUnknown is an error not from this library, e.g. an error of a Store.
+	ClassUnknown ErrorClass = iota
+	// ClassConflict is a concurrent update, retry with the latest document.
+	ClassConflict
+	// ClassPrecondition is a failed test or a missing path, the patch may succeed
+	// once the document reaches the expected state.
+	ClassPrecondition
+	// ClassMalformed is a bad patch or document, it never succeeds.
+	ClassMalformed
+	// ClassRejected is a patch rejected by a policy, an ownership or a limit, it never succeeds.
+	ClassRejected
+	// ClassAborted is an apply given up by the caller, e.g. all attempts are used
+	// or the context is canceled, retrying it is up to the caller.
+	ClassAborted
+	// ClassInternal is a bug of this library, an extension or a hook, e.g. a job panics,
+	// it says nothing about the patch and retrying it is up to the caller.
+	ClassInternal
+)
+
+// String implements fmt.Stringer.
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassConflict:
+		return "conflict"
+	case ClassPrecondition:
+		return "precondition"
+	case ClassMalformed:
+		return "malformed"
+	case ClassRejected:
+		return "rejected"
+	case ClassAborted:
+		return "aborted"
+	case ClassInternal:
+		return "internal"
+	default:
+		return "unknown"
+	}
+}
+
+var errorClasses = []struct {
+	target error
+	class  ErrorClass
+}{
+	// ErrTooManyAttempts wraps the last error, which is usually a conflict.
+	{ErrTooManyAttempts, ClassAborted},
+	{context.DeadlineExceeded, ClassAborted},
+	{context.Canceled, ClassAborted},
+	{ErrJobPanic, ClassInternal},
+	{ErrVersionConflict, ClassConflict},
+	{ErrPatchConflict, ClassConflict},
+	{ErrPolicyViolation, ClassRejected},
+	{ErrNotOwner, ClassRejected},
+	{ErrClaimConflict, ClassRejected},
+	{ErrFanoutExceeded, ClassRejected},
+	{ErrDocumentTooLarge, ClassRejected},
+	{ErrTypeMismatch, ClassRejected},
+	{ErrNotInvertible, ClassRejected},
+	{ErrBadSignature, ClassMalformed},
+	{ErrBadResumeToken, ClassMalformed},
+	{ErrUnknownDocument, ClassMalformed},
+	{ErrBadJSONPath, ClassMalformed},
+	{ErrStop, ClassPrecondition},
+	{ErrNotExists, ClassPrecondition},
+}
+
+var codeClasses = map[ErrorCode]ErrorClass{
+	CodeMissingMember:    ClassMalformed,
+	CodeBadPointer:       ClassMalformed,
+	CodeBadEscape:        ClassMalformed,
+	CodeUnknownOperation: ClassMalformed,
+	CodeInvalidOperation: ClassMalformed,
+	CodeBadArrayIndex:    ClassMalformed,
+	CodeIndexOutOfRange:  ClassPrecondition,
+	CodeBadType:          ClassPrecondition,
+	CodePathNotExists:    ClassPrecondition,
+	CodeOperationStopped: ClassPrecondition,
+}
+
+// Class returns the class of an error returned by this library.
+// Wrapped errors are classified by the first known error in their chain,
+// sentinel errors take precedence over error codes.
+func Class(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+	for _, c := range errorClasses {
+		if errors.Is(err, c.target) {
+			return c.class
+		}
+	}
+	var (
+		syntax    *json.SyntaxError
+		typeError *json.UnmarshalTypeError
+	)
+	if errors.As(err, &syntax) || errors.As(err, &typeError) {
+		return ClassMalformed
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if v, ok := e.(*Error); ok {
+			if c, ok := codeClasses[v.Code]; ok {
+				return c
+			}
+		}
+	}
+	return ClassUnknown
+}
+
+// Retryable returns true if err is a conflict or a failed precondition,
+// which may succeed if the patch is applied again later or against the latest document.
+func Retryable(err error) bool {
+	c := Class(err)
+	return c == ClassConflict || c == ClassPrecondition
+}
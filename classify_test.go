@@ -0,0 +1,51 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClass(t *testing.T) {
+	doc := []byte(`{"a":[1],"version":1}`)
+	apply := func(p *Patch, s string) error {
+		_, err := p.Apply(doc, mustOperations(t, s))
+		return err
+	}
+	cases := []struct {
+		err   error
+		class ErrorClass
+	}{
+		{nil, ClassUnknown},
+		{errors.New("io"), ClassUnknown},
+		{fmt.Errorf("save: %w", &ConflictError{Expected: 1, Actual: 2}), ClassConflict},
+		{apply(New(), `[{"op":"test","path":"/version","value":2}]`), ClassPrecondition},
+		{apply(New(), `[{"op":"remove","path":"/missing"}]`), ClassPrecondition},
+		{apply(New(), `[{"op":"add","path":"/a/5","value":1}]`), ClassPrecondition},
+		{apply(New(), `[{"op":"bad","path":"/a"}]`), ClassMalformed},
+		{apply(New(), `[{"op":"add","path":"a","value":1}]`), ClassMalformed},
+		{apply(New(), `[{"op":"add","path":"/a"}]`), ClassMalformed},
+		{apply(New(), `[{"op":"add","path":"/a/x","value":1}]`), ClassMalformed},
+		{apply(New(WithPolicy(&Policy{Deny: []string{"/a"}})), `[{"op":"remove","path":"/a"}]`), ClassRejected},
+		{apply(New(WithMaxDocumentSize(5)), `[{"op":"add","path":"/b","value":1}]`), ClassRejected},
+		{&attemptsError{err: &ConflictError{Expected: 1, Actual: 2}}, ClassAborted},
+		{fmt.Errorf("load: %w", context.DeadlineExceeded), ClassAborted},
+		{context.Canceled, ClassAborted},
+		{fmt.Errorf("%w: boom", ErrJobPanic), ClassInternal},
+	}
+	for i, c := range cases {
+		if got := Class(c.err); got != c.class {
+			t.Fatal(i, c.err, "expected", c.class, "got", got)
+		}
+		if expect := c.class == ClassConflict || c.class == ClassPrecondition; Retryable(c.err) != expect {
+			t.Fatal(i, c.err, "expected retryable", expect)
+		}
+	}
+	if _, err := New().Apply([]byte(`{`), nil); Class(err) != ClassMalformed {
+		t.Fatal("expected malformed document, got", Class(err), err)
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Operations of the composite extensions shipped with the package.
+// They are not registered by default, register them by WithExtension.
+// The paths of their nested operations are relative to the path of the composite operation.
+const (
+	OpIf      = "if"
+	OpForeach = "foreach"
+)
+
+// subOperations decodes the nested operations of the member name of a composite operation,
+// path and from of them are prefixed by base.
+func subOperations(op Operation, name, base string, required bool) ([]Operation, error) {
+	m, ok := (*op.Value).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("value of %s must be an object", *op.OP)
+	}
+	v, ok := m[name]
+	if !ok {
+		if required {
+			return nil, errMissingMember(*op.OP, "value."+name)
+		}
+		return nil, nil
+	}
+	ops, err := decodeOperations(v, base)
+	if err != nil {
+		return nil, fmt.Errorf("%s of %s %w", name, *op.OP, err)
+	}
+	return ops, nil
+}
+
+// decodeOperations decodes the nested operations v, path and from of them must be json pointers
+// and are prefixed by base.
+func decodeOperations(v any, base string) ([]Operation, error) {
+	a, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("must be an array of operations")
+	}
+	ops := make([]Operation, len(a))
+	for i, e := range a {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, errors.New("must be an array of operations")
+		}
+		setValueFromMap(&ops[i].OP, m, "op")
+		setValueFromMap(&ops[i].Path, m, "path")
+		setAnyFromMap(&ops[i].Value, m, "value")
+		setValueFromMap(&ops[i].From, m, "from")
+		setValueFromMap(&ops[i].Comment, m, "comment")
+		setValueFromMap(&ops[i].Label, m, "label")
+		for _, s := range []*string{ops[i].Path, ops[i].From} {
+			if s == nil {
+				continue
+			}
+			if err := NewJSONPointer(*s).Check(); err != nil {
+				return nil, err
+			}
+			*s = base + *s
+		}
+	}
+	return ops, nil
+}
+
+// rewriteNested returns a copy of the composite operation op whose nested operations are replaced by fn.
+// fn is called with every nested operation whose path and from are prefixed as they are applied,
+// the elements of foreach are "*", and only the value of the returned operation is kept.
+// The value of op is kept as is if it is malformed, which is reported by Check.
+func rewriteNested(op Operation, fn func(sub Operation) (Operation, error)) (Operation, error) {
+	if op.OP == nil || op.Path == nil || op.Value == nil {
+		return op, nil
+	}
+	var (
+		v   any
+		err error
+	)
+	switch *op.OP {
+	case OpIf:
+		m, ok := (*op.Value).(map[string]any)
+		if !ok {
+			return op, nil
+		}
+		c := make(map[string]any, len(m))
+		for k, e := range m {
+			c[k] = e
+		}
+		for _, name := range []string{"test", "then", "else"} {
+			if e, ok := m[name]; ok {
+				if c[name], err = rewriteOperations(e, *op.Path, fn); err != nil {
+					return op, err
+				}
+			}
+		}
+		v = c
+	case OpForeach:
+		if v, err = rewriteOperations(*op.Value, *op.Path+"/*", fn); err != nil {
+			return op, err
+		}
+	default:
+		return op, nil
+	}
+	op.Value = &v
+	return op, nil
+}
+
+// rewriteOperations returns a copy of the nested operations v whose values are replaced by fn, see rewriteNested.
+func rewriteOperations(v any, base string, fn func(sub Operation) (Operation, error)) (any, error) {
+	ops, err := decodeOperations(v, base)
+	if err != nil {
+		return v, nil
+	}
+	a := v.([]any)
+	r := make([]any, len(a))
+	for i, e := range a {
+		sub, err := fn(ops[i])
+		if err != nil {
+			return nil, err
+		}
+		m := e.(map[string]any)
+		c := make(map[string]any, len(m))
+		for k, e := range m {
+			c[k] = e
+		}
+		if sub.Value != nil {
+			c["value"] = *sub.Value
+		}
+		r[i] = c
+	}
+	return r, nil
+}
+
+// isCompositeOP returns true if op is one of the composite extensions.
+func isCompositeOP(op string) bool {
+	return op == OpIf || op == OpForeach
+}
+
+// nestedOperations returns the nested operations of a composite operation,
+// the paths of the nested operations of foreach are relative to its first element.
+// It returns nil if op is not a composite operation or its value is malformed.
+func nestedOperations(op Operation) []Operation {
+	if op.OP == nil || op.Path == nil || op.Value == nil {
+		return nil
+	}
+	var r []Operation
+	switch *op.OP {
+	case OpIf:
+		for _, name := range []string{"test", "then", "else"} {
+			ops, _ := subOperations(op, name, *op.Path, false)
+			r = append(r, ops...)
+		}
+	case OpForeach:
+		r, _ = decodeOperations(*op.Value, *op.Path+"/0")
+	}
+	return r
+}
+
+// checkSubOperations checks the nested operations as checkOne does.
+// Their ownership and policy are checked when they are applied,
+// since the paths of foreach are not known until then.
+func (p *Patch) checkSubOperations(ops []Operation) error {
+	for _, op := range ops {
+		if err := p.checkOne(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNestedAccess checks an operation nested in a composite operation, or a concrete target
+// of an operation with patterns, by the ownership and the paths of the policy.
+func (p *Patch) checkNestedAccess(op Operation) error {
+	if p.ownership != nil {
+		if err := p.ownership.Check(p.writer, []Operation{op}); err != nil {
+			return err
+		}
+	}
+	if p.policy != nil && !isTestOP(*op.OP) && !isCompositeOP(*op.OP) {
+		return p.policy.checkPaths(op)
+	}
+	return nil
+}
+
+// applySubOperations applies the nested operations as Apply does, they are checked already.
+func (p *Patch) applySubOperations(o *any, ops []Operation) error {
+	for _, op := range ops {
+		ext := p.extensions[*op.OP]
+		if err := p.checkNestedAccess(op); err != nil {
+			return err
+		}
+		err := p.applyOne(o, ext, op, nil)
+		if err != nil && !(!p.StrictPathExists && errors.Is(err, ErrNotExists)) {
+			return p.operationError(ext, op, err)
+		}
+	}
+	return nil
+}
+
+// IfExtension is the "if" operation applies the "then" operations if all "test" operations pass,
+// or the "else" operations otherwise, e.g.
+// {"op":"if","path":"/user","value":{"test":[{"op":"test","path":"/role","value":"admin"}],
+// "then":[{"op":"add","path":"/admin","value":true}],"else":[{"op":"remove","path":"/admin"}]}}.
+// A test fails if it stops the patch or its path not exists,
+// and the test operations should not modify the document.
+// "then" and "else" are optional.
+type IfExtension struct{}
+
+// OP implements Extension.
+func (IfExtension) OP() string {
+	return OpIf
+}
+
+// Apply implements Extension.
+func (IfExtension) Apply(p *Patch, o *any, op Operation) error {
+	tests, err := subOperations(op, "test", *op.Path, true)
+	if err != nil {
+		return err
+	}
+	branch := "then"
+	for _, t := range tests {
+		ext := p.extensions[*t.OP]
+		if err := p.checkNestedAccess(t); err != nil {
+			return err
+		}
+		err := p.applyOne(o, ext, t, nil)
+		if errors.Is(err, ErrStop) || errors.Is(err, ErrNotExists) {
+			branch = "else"
+			break
+		}
+		if err != nil {
+			return p.operationError(ext, t, err)
+		}
+	}
+	ops, err := subOperations(op, branch, *op.Path, false)
+	if err != nil {
+		return err
+	}
+	return p.applySubOperations(o, ops)
+}
+
+// Check implements Extension.
+func (IfExtension) Check(p *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpIf, "value")
+	}
+	for _, name := range []string{"test", "then", "else"} {
+		ops, err := subOperations(op, name, *op.Path, name == "test")
+		if err != nil {
+			return err
+		}
+		if err := p.checkSubOperations(ops); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForeachExtension is the "foreach" operation applies the operations of value to every element
+// of the array at path, the paths of the operations are relative to the element, e.g.
+// {"op":"foreach","path":"/items","value":[{"op":"add","path":"/done","value":true}]}.
+// The elements are visited from the last one to the first one,
+// so removing an element does not shift the others.
+type ForeachExtension struct{}
+
+// OP implements Extension.
+func (ForeachExtension) OP() string {
+	return OpForeach
+}
+
+// Apply implements Extension.
+func (ForeachExtension) Apply(p *Patch, o *any, op Operation) error {
+	a, _, err := visitArray(p, o, op)
+	if err != nil {
+		return err
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		ops, err := decodeOperations(*op.Value, *op.Path+"/"+strconv.Itoa(i))
+		if err != nil {
+			return err
+		}
+		if err := p.applySubOperations(o, ops); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check implements Extension.
+func (ForeachExtension) Check(p *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpForeach, "value")
+	}
+	ops, err := decodeOperations(*op.Value, *op.Path+"/0")
+	if err != nil {
+		return fmt.Errorf("value of %s %w", OpForeach, err)
+	}
+	return p.checkSubOperations(ops)
+}
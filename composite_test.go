@@ -0,0 +1,154 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIfExtension(t *testing.T) {
+	testExtensionCases(t, IfExtension{}, []extensionCase{
+		{
+			`{"user":{"role":"admin"}}`,
+			`[{"op":"if","path":"/user","value":{"test":[{"op":"test","path":"/role","value":"admin"}],"then":[{"op":"add","path":"/admin","value":true}],"else":[{"op":"add","path":"/admin","value":false}]}}]`,
+			`{"user":{"role":"admin","admin":true}}`,
+		},
+		{
+			`{"user":{"role":"guest"}}`,
+			`[{"op":"if","path":"/user","value":{"test":[{"op":"test","path":"/role","value":"admin"}],"then":[{"op":"add","path":"/admin","value":true}],"else":[{"op":"add","path":"/admin","value":false}]}}]`,
+			`{"user":{"role":"guest","admin":false}}`,
+		},
+		{
+			`{"a":1}`,
+			`[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/missing","value":1}],"then":[{"op":"remove","path":"/a"}]}}]`,
+			`{"a":1}`,
+		},
+		{
+			`{"a":1,"b":2}`,
+			`[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/a","value":1},{"op":"test","path":"/b","value":2}],"then":[{"op":"move","from":"/a","path":"/c"}]}}]`,
+			`{"b":2,"c":1}`,
+		},
+		{
+			`{"a":1,"b":2}`,
+			`[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/a","value":1},{"op":"test","path":"/b","value":3}],"then":[{"op":"remove","path":"/a"}],"else":[{"op":"remove","path":"/b"}]}}]`,
+			`{"a":1}`,
+		},
+		{
+			`{"a":{"b":1}}`,
+			`[{"op":"if","path":"/a","value":{"test":[],"then":[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/b","value":1}],"then":[{"op":"replace","path":"/b","value":2}]}}]}}]`,
+			`{"a":{"b":2}}`,
+		},
+		{
+			`{"a":1}`,
+			`[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/a","value":1}],"then":[{"op":"remove","path":"/a/b/c"},{"op":"test","path":"/a","value":2}]}}]`,
+			``,
+		},
+		{`{}`, `[{"op":"if","path":""}]`, ``},
+		{`{}`, `[{"op":"if","path":"","value":[]}]`, ``},
+		{`{}`, `[{"op":"if","path":"","value":{"then":[]}}]`, ``},
+		{`{}`, `[{"op":"if","path":"","value":{"test":[1]}}]`, ``},
+		{`{}`, `[{"op":"if","path":"","value":{"test":[],"then":{}}}]`, ``},
+		{`{}`, `[{"op":"if","path":"","value":{"test":[],"then":[{"op":"unknown","path":""}]}}]`, ``},
+		{`{}`, `[{"op":"if","path":"","value":{"test":[],"else":[{"op":"add","path":"a","value":1}]}}]`, ``},
+	})
+}
+
+func TestIfExtensionStop(t *testing.T) {
+	p := New(WithExtension(IfExtension{}))
+	ops := mustOperations(t, `[{"op":"if","path":"","value":{"test":[],"then":[{"op":"test","path":"/a","value":2}]}}]`)
+	if _, err := p.Apply([]byte(`{"a":1}`), ops); !errors.Is(err, ErrStop) {
+		t.Fatal("expected stop, got", err)
+	}
+}
+
+func TestForeachExtension(t *testing.T) {
+	testExtensionCases(t, ForeachExtension{}, []extensionCase{
+		{
+			`{"items":[{"n":1},{"n":2}]}`,
+			`[{"op":"foreach","path":"/items","value":[{"op":"add","path":"/done","value":true}]}]`,
+			`{"items":[{"n":1,"done":true},{"n":2,"done":true}]}`,
+		},
+		{
+			`{"items":[]}`,
+			`[{"op":"foreach","path":"/items","value":[{"op":"add","path":"/done","value":true}]}]`,
+			`{"items":[]}`,
+		},
+		{
+			`[{"a":1,"b":2},{"a":3,"b":4}]`,
+			`[{"op":"foreach","path":"","value":[{"op":"move","from":"/a","path":"/c"},{"op":"remove","path":"/b"}]}]`,
+			`[{"c":1},{"c":3}]`,
+		},
+		{
+			`{"items":[1,2,3]}`,
+			`[{"op":"foreach","path":"/items","value":[{"op":"remove","path":""}]}]`,
+			`{"items":[]}`,
+		},
+		{
+			`{"rows":[[1,2],[3]]}`,
+			`[{"op":"foreach","path":"/rows","value":[{"op":"foreach","path":"","value":[{"op":"replace","path":"","value":0}]}]}]`,
+			`{"rows":[[0,0],[0]]}`,
+		},
+		{
+			`{"items":[{"n":1},{"n":2}]}`,
+			`[{"op":"foreach","path":"/items","value":[{"op":"test","path":"/n","value":1}]}]`,
+			``,
+		},
+		{`{"items":{}}`, `[{"op":"foreach","path":"/items","value":[]}]`, ``},
+		{`{}`, `[{"op":"foreach","path":"/items","value":[]}]`, ``},
+		{`{"items":[]}`, `[{"op":"foreach","path":"/items"}]`, ``},
+		{`{"items":[]}`, `[{"op":"foreach","path":"/items","value":{}}]`, ``},
+		{`{"items":[]}`, `[{"op":"foreach","path":"/items","value":[{"op":"add","path":"a","value":1}]}]`, ``},
+	})
+}
+
+func TestForeachExtensionIf(t *testing.T) {
+	p := New(WithExtension(ForeachExtension{}), WithExtension(IfExtension{}))
+	ops := mustOperations(t, `[{"op":"foreach","path":"/items","value":[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/n","value":2}],"then":[{"op":"remove","path":""}]}}]}]`)
+	b, err := p.Apply([]byte(`{"items":[{"n":1},{"n":2},{"n":3}]}`), ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Equal(b, []byte(`{"items":[{"n":1},{"n":3}]}`)); err != nil || !ok {
+		t.Fatal("unexpected document", string(b))
+	}
+}
+
+func TestCompositePolicy(t *testing.T) {
+	doc := []byte(`{"items":[{"n":1},{"n":2}],"secret":{"k":1}}`)
+	pol := &Policy{AllowedOps: []string{"if", "foreach", "test", "replace"}, Deny: []string{"/items/1/n", "/secret"}, MaxOperations: 4}
+	p := New(WithExtension(IfExtension{}), WithExtension(ForeachExtension{}), WithPolicy(pol))
+	cases := []struct {
+		ops string
+		ok  bool
+	}{
+		{`[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/items/0/n","value":1}],"then":[{"op":"replace","path":"/items/0/n","value":2}]}}]`, true},
+		{`[{"op":"if","path":"","value":{"test":[],"then":[{"op":"remove","path":"/items/0/n"}]}}]`, false},
+		{`[{"op":"if","path":"","value":{"test":[],"then":[{"op":"replace","path":"/secret/k","value":2}]}}]`, false},
+		{`[{"op":"if","path":"","value":{"test":[],"then":[{"op":"replace","path":"/items/0/n","value":2},{"op":"replace","path":"/items/0/n","value":3},{"op":"replace","path":"/items/0/n","value":4},{"op":"replace","path":"/items/0/n","value":5}]}}]`, false},
+		{`[{"op":"foreach","path":"/items","value":[{"op":"replace","path":"/n","value":0}]}]`, false},
+		{`[{"op":"foreach","path":"/items","value":[{"op":"if","path":"","value":{"test":[{"op":"test","path":"/n","value":1}],"then":[{"op":"replace","path":"/n","value":0}]}}]}]`, true},
+	}
+	for _, c := range cases {
+		_, err := p.Apply(doc, mustOperations(t, c.ops))
+		if c.ok && err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if !c.ok && !errors.Is(err, ErrPolicyViolation) {
+			t.Fatal(c.ops, "expected policy violation, got", err)
+		}
+	}
+
+	o := NewOwnership()
+	if err := o.Claim("alice", "/items/0"); err != nil {
+		t.Fatal(err)
+	}
+	p = New(WithExtension(IfExtension{}), WithExtension(ForeachExtension{}), WithOwnership(o, "alice"))
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"if","path":"","value":{"test":[],"then":[{"op":"replace","path":"/items/0/n","value":2}]}}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"foreach","path":"/items","value":[{"op":"replace","path":"/n","value":0}]}]`)); !errors.Is(err, ErrNotOwner) {
+		t.Fatal("expected not owner, got", err)
+	}
+}
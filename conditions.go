@@ -0,0 +1,371 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Operations of the test extensions shipped with the package.
+// They are not registered by default, register them by WithExtension.
+// As the test operation, they stop the patch with an error wraps ErrStop if the test fails.
+const (
+	OpTestNot      = "test-not"
+	OpTestMatch    = "test-match"
+	OpTestType     = "test-type"
+	OpTestLength   = "test-length"
+	OpTestLT       = "test-lt"
+	OpTestLTE      = "test-lte"
+	OpTestGT       = "test-gt"
+	OpTestGTE      = "test-gte"
+	OpTestContains = "test-contains"
+)
+
+// isTestOP returns true if op is the test operation or one of the test extensions,
+// which only read the document at path.
+// They are not writes for the ownership, the policy and the analyses of patches.
+func isTestOP(op string) bool {
+	switch op {
+	case opTest, OpTestNot, OpTestMatch, OpTestType, OpTestLength,
+		OpTestLT, OpTestLTE, OpTestGT, OpTestGTE, OpTestContains:
+		return true
+	default:
+		return false
+	}
+}
+
+// testOperand returns the node at the path of op for a test operation,
+// it is an error like the test operation if path not exists.
+func testOperand(p *Patch, o *any, op Operation) (any, error) {
+	v, _, err := p.VisitPath(o, p.pointer(*op.Path).Path()...)
+	if err != nil {
+		if p.StrictPathExists {
+			return nil, errPathNotExists(*op.Path, err)
+		}
+		return nil, ErrStop
+	}
+	return v, nil
+}
+
+// TestNotExtension is the "test-not" operation succeeds only if the value at path
+// does not equal to the value or path not exists,
+// e.g. {"op":"test-not","path":"/status","value":"done"}.
+type TestNotExtension struct{}
+
+// OP implements Extension.
+func (TestNotExtension) OP() string {
+	return OpTestNot
+}
+
+// Apply implements Extension.
+func (TestNotExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, _, err := p.VisitPath(o, p.pointer(*op.Path).Path()...)
+	if err != nil || !EqualAny(v, *op.Value) {
+		return nil
+	}
+	return ErrStop
+}
+
+// Check implements Extension.
+func (TestNotExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpTestNot, "value")
+	}
+	return nil
+}
+
+// TestMatchExtension is the "test-match" operation succeeds only if the value at path is a string
+// matches the regular expression value, e.g. {"op":"test-match","path":"/email","value":".*@corp\\.com$"}.
+// The expression is not anchored, use ^ and $ to match the whole string.
+type TestMatchExtension struct{}
+
+// OP implements Extension.
+func (TestMatchExtension) OP() string {
+	return OpTestMatch
+}
+
+// Apply implements Extension.
+func (TestMatchExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, err := testOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile((*op.Value).(string))
+	if err != nil {
+		return err
+	}
+	if s, ok := v.(string); ok && re.MatchString(s) {
+		return nil
+	}
+	return ErrStop
+}
+
+// Check implements Extension.
+func (TestMatchExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpTestMatch, "value")
+	}
+	s, ok := (*op.Value).(string)
+	if !ok {
+		return errors.New("value of test-match must be a string")
+	}
+	if _, err := regexp.Compile(s); err != nil {
+		return fmt.Errorf("bad value of test-match: %w", err)
+	}
+	return nil
+}
+
+// TestTypeExtension is the "test-type" operation succeeds only if the JSONType of the value at path
+// is the value, e.g. {"op":"test-type","path":"/tags","value":"array"}.
+type TestTypeExtension struct{}
+
+// OP implements Extension.
+func (TestTypeExtension) OP() string {
+	return OpTestType
+}
+
+// Apply implements Extension.
+func (TestTypeExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, err := testOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	if TypeOf(v) == JSONType((*op.Value).(string)) {
+		return nil
+	}
+	return ErrStop
+}
+
+// Check implements Extension.
+func (TestTypeExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpTestType, "value")
+	}
+	s, _ := (*op.Value).(string)
+	switch JSONType(s) {
+	case TypeNull, TypeBoolean, TypeNumber, TypeString, TypeObject, TypeArray:
+		return nil
+	default:
+		return fmt.Errorf("value of test-type must be a json type: %v", *op.Value)
+	}
+}
+
+// TestLengthExtension is the "test-length" operation succeeds only if the length of the value at path
+// satisfies all the comparisons of the value, e.g. {"op":"test-length","path":"/queue","value":{"lt":10}}.
+// The comparisons are eq, ne, lt, lte, gt and gte, and a number value is the same as {"eq":value}.
+// The length is the number of elements of an array, members of an object or characters of a string,
+// other values have no length and fail the test.
+type TestLengthExtension struct{}
+
+// OP implements Extension.
+func (TestLengthExtension) OP() string {
+	return OpTestLength
+}
+
+// Apply implements Extension.
+func (TestLengthExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, err := testOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	var n int
+	switch x := v.(type) {
+	case []any:
+		n = len(x)
+	case map[string]any:
+		n = len(x)
+	case string:
+		n = utf8.RuneCountInString(x)
+	default:
+		return ErrStop
+	}
+	comparisons, _ := lengthComparisons(*op.Value)
+	for cmp, limit := range comparisons {
+		if !compareNumber(cmp, float64(n), limit) {
+			return ErrStop
+		}
+	}
+	return nil
+}
+
+// Check implements Extension.
+func (TestLengthExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpTestLength, "value")
+	}
+	_, err := lengthComparisons(*op.Value)
+	return err
+}
+
+func lengthComparisons(value any) (map[string]float64, error) {
+	if n, ok := toFloat(value); ok {
+		return map[string]float64{"eq": n}, nil
+	}
+	m, ok := value.(map[string]any)
+	if !ok || len(m) == 0 {
+		return nil, errors.New("value of test-length must be a number or an object of comparisons")
+	}
+	r := make(map[string]float64, len(m))
+	for cmp, v := range m {
+		n, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("%s of test-length must be a number", cmp)
+		}
+		switch cmp {
+		case "eq", "ne", "lt", "lte", "gt", "gte":
+			r[cmp] = n
+		default:
+			return nil, fmt.Errorf("unknown comparison of test-length: %s", cmp)
+		}
+	}
+	return r, nil
+}
+
+// compareNumber returns the result of the comparison eq, ne, lt, lte, gt or gte of a and b.
+func compareNumber(cmp string, a, b float64) bool {
+	switch cmp {
+	case "eq":
+		return a == b
+	case "ne":
+		return a != b
+	case "lt":
+		return a < b
+	case "lte":
+		return a <= b
+	case "gt":
+		return a > b
+	case "gte":
+		return a >= b
+	default:
+		return false
+	}
+}
+
+// TestCompareExtension is the numeric comparison test operations, its value is the name of the operation.
+// They succeed only if the value at path is a number less than, less than or equal to, greater than,
+// or greater than or equal to the number value, e.g. {"op":"test-lt","path":"/rev","value":10}.
+type TestCompareExtension string
+
+// Numeric comparison test extensions.
+const (
+	TestLT  TestCompareExtension = OpTestLT
+	TestLTE TestCompareExtension = OpTestLTE
+	TestGT  TestCompareExtension = OpTestGT
+	TestGTE TestCompareExtension = OpTestGTE
+)
+
+// OP implements Extension.
+func (e TestCompareExtension) OP() string {
+	return string(e)
+}
+
+// Apply implements Extension.
+func (e TestCompareExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, err := testOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	a, ok := toFloat(v)
+	if !ok {
+		return ErrStop
+	}
+	b, _ := toFloat(*op.Value)
+	if compareNumber(strings.TrimPrefix(string(e), "test-"), a, b) {
+		return nil
+	}
+	return ErrStop
+}
+
+// Check implements Extension.
+func (e TestCompareExtension) Check(_ *Patch, op Operation) error {
+	switch e {
+	case TestLT, TestLTE, TestGT, TestGTE:
+	default:
+		return fmt.Errorf("unknown comparison test: %s", string(e))
+	}
+	if op.Value == nil {
+		return errMissingMember(string(e), "value")
+	}
+	if _, ok := toFloat(*op.Value); !ok {
+		return fmt.Errorf("value of %s must be a number", string(e))
+	}
+	return nil
+}
+
+// TestContainsExtension is the "test-contains" operation succeeds only if the value at path contains the value,
+// e.g. {"op":"test-contains","path":"/user","value":{"roles":["admin"]}}.
+// An object contains an object if it has every member of it and the members contain the values,
+// an array contains an array if every element of it is contained by an element of the array,
+// an array contains other values if one of its elements contains the value,
+// and other values contain only the equal values.
+type TestContainsExtension struct{}
+
+// OP implements Extension.
+func (TestContainsExtension) OP() string {
+	return OpTestContains
+}
+
+// Apply implements Extension.
+func (TestContainsExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, err := testOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	if containsJSON(v, *op.Value) {
+		return nil
+	}
+	return ErrStop
+}
+
+// Check implements Extension.
+func (TestContainsExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpTestContains, "value")
+	}
+	return nil
+}
+
+// containsJSON returns true if v contains sub, see TestContainsExtension.
+func containsJSON(v, sub any) bool {
+	switch x := v.(type) {
+	case map[string]any:
+		m, ok := sub.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, s := range m {
+			if e, ok := x[k]; !ok || !containsJSON(e, s) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		if a, ok := sub.([]any); ok {
+			for _, s := range a {
+				if !containsElement(x, s) {
+					return false
+				}
+			}
+			return true
+		}
+		return containsElement(x, sub)
+	default:
+		return EqualAny(v, sub)
+	}
+}
+
+// containsElement returns true if an element of a contains sub.
+func containsElement(a []any, sub any) bool {
+	for _, e := range a {
+		if containsJSON(e, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testConditionCases checks the test operation ext, a case passes or stops the patch with ErrStop.
+func testConditionCases(t *testing.T, ext Extension, doc string, passes, stops []string) {
+	t.Helper()
+	p := New(WithExtension(ext))
+	for _, ops := range passes {
+		if _, err := p.Apply([]byte(doc), mustOperations(t, ops)); err != nil {
+			t.Fatal(ops, "expected pass, got", err)
+		}
+	}
+	for _, ops := range stops {
+		if _, err := p.Apply([]byte(doc), mustOperations(t, ops)); !errors.Is(err, ErrStop) {
+			t.Fatal(ops, "expected stop, got", err)
+		}
+	}
+}
+
+func TestTestNotExtension(t *testing.T) {
+	testConditionCases(t, TestNotExtension{}, `{"status":"done","n":1}`, []string{
+		`[{"op":"test-not","path":"/status","value":"todo"}]`,
+		`[{"op":"test-not","path":"/n","value":"1"}]`,
+		`[{"op":"test-not","path":"/missing","value":1}]`,
+		`[{"op":"test-not","path":"/missing/a","value":1}]`,
+	}, []string{
+		`[{"op":"test-not","path":"/status","value":"done"}]`,
+		`[{"op":"test-not","path":"/n","value":1.0}]`,
+		`[{"op":"test-not","path":"","value":{"status":"done","n":1}}]`,
+	})
+	if err := New(WithExtension(TestNotExtension{})).Check(mustOperations(t, `[{"op":"test-not","path":"/a"}]`)); err == nil {
+		t.Fatal("expected missing value error")
+	}
+}
+
+func TestTestMatchExtension(t *testing.T) {
+	doc := `{"email":"a@corp.com","n":1}`
+	testConditionCases(t, TestMatchExtension{}, doc, []string{
+		`[{"op":"test-match","path":"/email","value":".*@corp\\.com"}]`,
+		`[{"op":"test-match","path":"/email","value":"corp"}]`,
+	}, []string{
+		`[{"op":"test-match","path":"/email","value":"^corp"}]`,
+		`[{"op":"test-match","path":"/n","value":"1"}]`,
+	})
+	p := New(WithExtension(TestMatchExtension{}))
+	if _, err := p.Apply([]byte(doc), mustOperations(t, `[{"op":"test-match","path":"/missing","value":"a"}]`)); !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected not exists, got", err)
+	}
+	for _, ops := range []string{`[{"op":"test-match","path":"/email","value":"("}]`, `[{"op":"test-match","path":"/email","value":1}]`} {
+		if err := p.Check(mustOperations(t, ops)); err == nil {
+			t.Fatal(ops, "expected bad value")
+		}
+	}
+}
+
+func TestTestTypeExtension(t *testing.T) {
+	doc := `{"o":{},"a":[],"s":"","n":0,"b":false,"z":null}`
+	testConditionCases(t, TestTypeExtension{}, doc, []string{
+		`[{"op":"test-type","path":"","value":"object"},{"op":"test-type","path":"/o","value":"object"},
+		{"op":"test-type","path":"/a","value":"array"},{"op":"test-type","path":"/s","value":"string"},
+		{"op":"test-type","path":"/n","value":"number"},{"op":"test-type","path":"/b","value":"boolean"},
+		{"op":"test-type","path":"/z","value":"null"}]`,
+	}, []string{
+		`[{"op":"test-type","path":"/o","value":"array"}]`,
+		`[{"op":"test-type","path":"/n","value":"string"}]`,
+		`[{"op":"test-type","path":"/z","value":"object"}]`,
+	})
+	p := New(WithExtension(TestTypeExtension{}))
+	for _, ops := range []string{`[{"op":"test-type","path":"/o","value":"map"}]`, `[{"op":"test-type","path":"/o","value":1}]`} {
+		if err := p.Check(mustOperations(t, ops)); err == nil {
+			t.Fatal(ops, "expected bad value")
+		}
+	}
+}
+
+func TestTestLengthExtension(t *testing.T) {
+	doc := `{"queue":[1,2,3],"o":{"a":1},"s":"héllo","n":5}`
+	testConditionCases(t, TestLengthExtension{}, doc, []string{
+		`[{"op":"test-length","path":"/queue","value":3}]`,
+		`[{"op":"test-length","path":"/queue","value":{"lt":10,"gte":3}}]`,
+		`[{"op":"test-length","path":"/o","value":{"eq":1}}]`,
+		`[{"op":"test-length","path":"/s","value":{"eq":5,"ne":6}}]`,
+		`[{"op":"test-length","path":"","value":{"gt":3}}]`,
+	}, []string{
+		`[{"op":"test-length","path":"/queue","value":{"lt":3}}]`,
+		`[{"op":"test-length","path":"/queue","value":{"lt":10,"gt":3}}]`,
+		`[{"op":"test-length","path":"/s","value":6}]`,
+		`[{"op":"test-length","path":"/n","value":5}]`,
+	})
+	p := New(WithExtension(TestLengthExtension{}))
+	for _, ops := range []string{
+		`[{"op":"test-length","path":"/queue","value":{}}]`,
+		`[{"op":"test-length","path":"/queue","value":{"le":1}}]`,
+		`[{"op":"test-length","path":"/queue","value":{"lt":"1"}}]`,
+		`[{"op":"test-length","path":"/queue","value":"1"}]`,
+	} {
+		if err := p.Check(mustOperations(t, ops)); err == nil {
+			t.Fatal(ops, "expected bad value")
+		}
+	}
+}
+
+func TestTestCompareExtension(t *testing.T) {
+	doc := `{"rev":5,"s":"5"}`
+	cases := []struct {
+		ext    TestCompareExtension
+		passes []float64
+		stops  []float64
+	}{
+		{TestLT, []float64{6}, []float64{5, 4}},
+		{TestLTE, []float64{5, 6}, []float64{4}},
+		{TestGT, []float64{4}, []float64{5, 6}},
+		{TestGTE, []float64{4, 5}, []float64{6}},
+	}
+	for _, c := range cases {
+		var passes, stops []string
+		for _, v := range c.passes {
+			passes = append(passes, fmt.Sprintf(`[{"op":%q,"path":"/rev","value":%v}]`, c.ext, v))
+		}
+		for _, v := range c.stops {
+			stops = append(stops, fmt.Sprintf(`[{"op":%q,"path":"/rev","value":%v}]`, c.ext, v))
+		}
+		stops = append(stops, fmt.Sprintf(`[{"op":%q,"path":"/s","value":5}]`, c.ext))
+		testConditionCases(t, c.ext, doc, passes, stops)
+	}
+	if err := New(WithExtension(TestLT)).Check(mustOperations(t, `[{"op":"test-lt","path":"/rev","value":"10"}]`)); err == nil {
+		t.Fatal("expected bad value")
+	}
+	if err := New(WithExtension(TestCompareExtension("test-eq"))).Check(mustOperations(t, `[{"op":"test-eq","path":"/rev","value":1}]`)); err == nil {
+		t.Fatal("expected unknown comparison")
+	}
+}
+
+func TestTestContainsExtension(t *testing.T) {
+	doc := `{"user":{"name":"a","roles":["admin","dev"],"meta":{"x":1,"y":[{"id":1,"v":2}]}},"n":1,"m":[[1,2],[3]]}`
+	testConditionCases(t, TestContainsExtension{}, doc, []string{
+		`[{"op":"test-contains","path":"/user","value":{"roles":["admin"]}}]`,
+		`[{"op":"test-contains","path":"/user","value":{}}]`,
+		`[{"op":"test-contains","path":"/user/roles","value":"dev"}]`,
+		`[{"op":"test-contains","path":"/user/roles","value":["dev","admin"]}]`,
+		`[{"op":"test-contains","path":"","value":{"user":{"meta":{"y":[{"id":1}]}},"n":1}}]`,
+		`[{"op":"test-contains","path":"/n","value":1}]`,
+		`[{"op":"test-contains","path":"/m","value":[[2],[3]]}]`,
+		`[{"op":"test-contains","path":"/m","value":[[1,2]]}]`,
+	}, []string{
+		`[{"op":"test-contains","path":"/user","value":{"roles":["root"]}}]`,
+		`[{"op":"test-contains","path":"/user","value":{"missing":null}}]`,
+		`[{"op":"test-contains","path":"/user/roles","value":"ad"}]`,
+		`[{"op":"test-contains","path":"/user","value":["admin"]}]`,
+		`[{"op":"test-contains","path":"/user/meta","value":{"y":[{"id":2}]}}]`,
+		`[{"op":"test-contains","path":"/n","value":2}]`,
+		`[{"op":"test-contains","path":"/user/roles","value":[["admin"]]}]`,
+		`[{"op":"test-contains","path":"/m","value":[[[2]]]}]`,
+		`[{"op":"test-contains","path":"/m","value":[[1,3]]}]`,
+	})
+}
+
+func TestTestExtensionsRead(t *testing.T) {
+	o := NewOwnership()
+	if err := o.Claim("alice", "/status"); err != nil {
+		t.Fatal(err)
+	}
+	ops := mustOperations(t, `[{"op":"test-lt","path":"/rev","value":10},{"op":"replace","path":"/status","value":1}]`)
+	p := New(WithExtension(TestLT), WithOwnership(o, "alice"), WithPolicy(&Policy{RequireTests: []string{"/rev"}}))
+	if _, err := p.Apply([]byte(`{"rev":1,"status":0}`), ops); err != nil {
+		t.Fatal(err)
+	}
+	if c := Coverage(map[string]any{"rev": 1.0}, ops); len(c.Reads) != 1 || c.Reads[0] != "/rev" || len(c.Writes) != 1 {
+		t.Fatalf("bad coverage: %+v", c)
+	}
+	if a := Analyze(ops); a.NodesWritten != 1 {
+		t.Fatal("bad nodes written", a.NodesWritten)
+	}
+	guards := mustOperations(t, `[{"op":"test-lt","path":"/rev","value":10}]`)
+	if c := Conflicts(guards, mustOperations(t, `[{"op":"test-gt","path":"/rev","value":0}]`)); len(c) != 0 {
+		t.Fatal("test extensions conflict", c)
+	}
+}
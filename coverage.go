@@ -0,0 +1,107 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"sort"
+	"strings"
+)
+
+// CoverageReport reports which parts of a document a patch touches.
+type CoverageReport struct {
+	// Reads is the sorted list of paths that are read by test, copy and move operations.
+	Reads []string
+	// Writes is the sorted list of paths that are written by add, remove, replace, copy and move operations.
+	Writes []string
+	// Nodes is the number of nodes in the document.
+	Nodes int
+	// ReadNodes is the number of document nodes inside of a read path.
+	ReadNodes int
+	// WrittenNodes is the number of document nodes inside of a written path.
+	WrittenNodes int
+}
+
+// ReadFraction returns the fraction of document nodes read by the patch.
+func (r *CoverageReport) ReadFraction() float64 {
+	if r.Nodes == 0 {
+		return 0
+	}
+	return float64(r.ReadNodes) / float64(r.Nodes)
+}
+
+// WriteFraction returns the fraction of document nodes written by the patch.
+func (r *CoverageReport) WriteFraction() float64 {
+	if r.Nodes == 0 {
+		return 0
+	}
+	return float64(r.WrittenNodes) / float64(r.Nodes)
+}
+
+// Coverage reports the paths of doc that ops reads and writes.
+// Paths are taken from the operations as is, the patch is not applied.
+func Coverage(doc any, ops []Operation) *CoverageReport {
+	reads := map[string]bool{}
+	writes := map[string]bool{}
+	for _, op := range ops {
+		if op.OP == nil || op.Path == nil {
+			continue
+		}
+		switch {
+		case isTestOP(*op.OP):
+			reads[*op.Path] = true
+		case *op.OP == opCopy:
+			writes[*op.Path] = true
+			if op.From != nil {
+				reads[*op.From] = true
+			}
+		case *op.OP == opMove:
+			writes[*op.Path] = true
+			if op.From != nil {
+				reads[*op.From] = true
+				writes[*op.From] = true
+			}
+		default:
+			writes[*op.Path] = true
+		}
+	}
+	r := &CoverageReport{
+		Reads:  setToSortedSlice(reads),
+		Writes: setToSortedSlice(writes),
+	}
+	_ = walk(doc, "", func(ptr string, _ any) error {
+		r.Nodes++
+		if underAny(ptr, r.Reads) {
+			r.ReadNodes++
+		}
+		if underAny(ptr, r.Writes) {
+			r.WrittenNodes++
+		}
+		return nil
+	})
+	return r
+}
+
+func setToSortedSlice(m map[string]bool) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// isPathPrefix returns true if ptr equals to prefix or is a descendant of prefix.
+func isPathPrefix(prefix, ptr string) bool {
+	return prefix == "" || ptr == prefix || strings.HasPrefix(ptr, prefix+"/")
+}
+
+func underAny(ptr string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if isPathPrefix(p, ptr) {
+			return true
+		}
+	}
+	return false
+}
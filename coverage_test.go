@@ -0,0 +1,40 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	var (
+		doc any
+		ops []Operation
+	)
+	if err := json.Unmarshal([]byte(`{"a":{"b":1,"c":2},"d":[1,2],"e":3}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`[
+		{"op":"test","path":"/e","value":3},
+		{"op":"replace","path":"/a","value":{}},
+		{"op":"copy","path":"/f","from":"/d/0"}
+	]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	r := Coverage(doc, ops)
+	if !reflect.DeepEqual(r.Reads, []string{"/d/0", "/e"}) {
+		t.Fatal("bad reads", r.Reads)
+	}
+	if !reflect.DeepEqual(r.Writes, []string{"/a", "/f"}) {
+		t.Fatal("bad writes", r.Writes)
+	}
+	if r.Nodes != 8 || r.ReadNodes != 2 || r.WrittenNodes != 3 {
+		t.Fatalf("bad node counts: %+v", r)
+	}
+	if r.WriteFraction() != 3.0/8 {
+		t.Fatal("bad write fraction", r.WriteFraction())
+	}
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// DiffOption is the option of CreatePatch.
+type DiffOption func(*differ)
+
+// WithMoveDetection set whether CreatePatch emits move and copy operations.
+// The default value is false.
+// If it's true, an added object member whose value equals to a removed object member
+// becomes a move, and one equals to an unchanged object member becomes a copy.
+// Only non-empty objects and arrays outside of arrays are detected,
+// because indices of arrays are shifted by the other operations.
+func WithMoveDetection(on bool) DiffOption {
+	return func(d *differ) {
+		d.detectMoves = on
+	}
+}
+
+// ArrayDiffStrategy is the algorithm compares arrays in CreatePatch.
+type ArrayDiffStrategy int
+
+const (
+	// ArrayDiffNaive compares elements at the same index,
+	// then removes or adds the elements at the tail.
+	ArrayDiffNaive ArrayDiffStrategy = iota
+	// ArrayDiffLCS keeps the longest common subsequence of the arrays
+	// and only adds or removes the other elements.
+	// It takes O(n*m) time and space for arrays differ in the middle.
+	ArrayDiffLCS
+)
+
+// WithArrayDiffStrategy set the algorithm compares arrays.
+// The default value is ArrayDiffNaive.
+func WithArrayDiffStrategy(s ArrayDiffStrategy) DiffOption {
+	return func(d *differ) {
+		d.arrayStrategy = s
+	}
+}
+
+// WithIgnorePaths set the paths ignored by CreatePatch.
+// A "*" token matches any token, and the descendants of an ignored path are ignored too.
+// For ArrayDiffLCS, elements of arrays are compared with "*" as their index.
+func WithIgnorePaths(paths ...string) DiffOption {
+	return func(d *differ) {
+		for _, p := range paths {
+			d.ignores = append(d.ignores, NewJSONPointer(p).Path())
+		}
+	}
+}
+
+// WithNumericTolerance set the max difference of numbers treated as equal by CreatePatch.
+// The default value is 0.
+func WithNumericTolerance(tolerance float64) DiffOption {
+	return func(d *differ) {
+		d.tolerance = tolerance
+	}
+}
+
+type differ struct {
+	detectMoves   bool
+	arrayStrategy ArrayDiffStrategy
+	ignores       [][]string
+	tolerance     float64
+	ops           []Operation
+	// arrays is the number of arrays containing the current node.
+	arrays int
+	// removed are the values of removed object members, keyed by the index of the operations.
+	removed map[int]any
+	// added are the indices of the operations adding object members.
+	added []int
+	// unchanged are the pointers of unchanged values, keyed by their canonical json.
+	unchanged map[string]string
+}
+
+// CreatePatch returns the operations transform the original json document into the modified one.
+func CreatePatch(original, modified []byte, opts ...DiffOption) ([]Operation, error) {
+	var a, b any
+	if err := json.Unmarshal(original, &a); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(modified, &b); err != nil {
+		return nil, err
+	}
+	return CreatePatchAny(a, b, opts...), nil
+}
+
+// CreatePatchAny returns the operations transform original into modified.
+// Both documents must be json values decoded by encoding/json.
+// Values of the operations are copies, so modified can be changed later.
+func CreatePatchAny(original, modified any, opts ...DiffOption) []Operation {
+	d := &differ{removed: map[int]any{}, unchanged: map[string]string{}}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.diff("", original, modified)
+	if d.detectMoves {
+		d.relocate()
+	}
+	return d.ops
+}
+
+func (d *differ) diff(ptr string, a, b any) {
+	if d.ignored(ptr) {
+		return
+	}
+	if d.equal(ptr, a, b) {
+		d.keep(ptr, a)
+		return
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			d.diffObject(ptr, a, b)
+			return
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			d.arrays++
+			d.diffArray(ptr, a, b)
+			d.arrays--
+			return
+		}
+	}
+	d.add(opReplace, ptr, b)
+}
+
+func (d *differ) diffObject(ptr string, a, b map[string]any) {
+	for _, k := range sortedKeys(a) {
+		if _, ok := b[k]; !ok {
+			path := ptr + "/" + EscapeToken(k)
+			if d.ignored(path) {
+				continue
+			}
+			if d.movable(path, a[k]) {
+				d.removed[len(d.ops)] = a[k]
+			}
+			d.add(opRemove, path, nil)
+		}
+	}
+	for _, k := range sortedKeys(b) {
+		path := ptr + "/" + EscapeToken(k)
+		if v, ok := a[k]; ok {
+			d.diff(path, v, b[k])
+		} else if !d.ignored(path) {
+			if d.movable(path, b[k]) {
+				d.added = append(d.added, len(d.ops))
+			}
+			d.add(opAdd, path, b[k])
+		}
+	}
+}
+
+func (d *differ) diffArray(ptr string, a, b []any) {
+	if d.arrayStrategy == ArrayDiffLCS {
+		d.diffArrayLCS(ptr, a, b)
+		return
+	}
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		d.diff(ptr+"/"+strconv.Itoa(i), a[i], b[i])
+	}
+	for i := len(a) - 1; i >= n; i-- {
+		d.add(opRemove, ptr+"/"+strconv.Itoa(i), nil)
+	}
+	for i := n; i < len(b); i++ {
+		d.add(opAdd, ptr+"/"+strconv.Itoa(i), b[i])
+	}
+}
+
+func (d *differ) diffArrayLCS(ptr string, a, b []any) {
+	// the common prefix and suffix are kept without the table.
+	eq := func(x, y any) bool { return d.equal(ptr+"/*", x, y) }
+	start, end := commonAffix(eq, a, b)
+	ma, mb := a[start:len(a)-end], b[start:len(b)-end]
+	lcs := lcsTable(eq, ma, mb)
+	// idx is the index in the array being patched.
+	i, j, idx := 0, 0, start
+	for i < len(ma) || j < len(mb) {
+		di, dj := nextCommon(eq, lcs, ma, mb, i, j)
+		// changed elements are compared in place, the rest are removed or added.
+		for ; i < di && j < dj; i, j, idx = i+1, j+1, idx+1 {
+			d.diff(ptr+"/"+strconv.Itoa(idx), ma[i], mb[j])
+		}
+		for ; i < di; i++ {
+			d.add(opRemove, ptr+"/"+strconv.Itoa(idx), nil)
+		}
+		for ; j < dj; j, idx = j+1, idx+1 {
+			d.add(opAdd, ptr+"/"+strconv.Itoa(idx), mb[j])
+		}
+		if i < len(ma) && j < len(mb) {
+			i, j, idx = i+1, j+1, idx+1
+		}
+	}
+}
+
+// commonAffix returns the length of the common prefix and suffix of a and b.
+func commonAffix(eq func(x, y any) bool, a, b []any) (start, end int) {
+	for start < len(a) && start < len(b) && eq(a[start], b[start]) {
+		start++
+	}
+	for end < len(a)-start && end < len(b)-start && eq(a[len(a)-1-end], b[len(b)-1-end]) {
+		end++
+	}
+	return start, end
+}
+
+// lcsTable returns the table whose [i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+func lcsTable(eq func(x, y any) bool, a, b []any) [][]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case eq(a[i], b[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	return lcs
+}
+
+// nextCommon returns the indices of the next common element of a and b from i and j,
+// or the lengths of a and b if there is none.
+func nextCommon(eq func(x, y any) bool, lcs [][]int, a, b []any, i, j int) (int, int) {
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && lcs[i][j] == lcs[i+1][j+1]+1 && eq(a[i], b[j]) {
+			return i, j
+		}
+		if j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return i, j
+}
+
+func (d *differ) add(op, path string, value any) {
+	d.ops = append(d.ops, newOperation(op, path, deepCopy(value), nil))
+}
+
+// keep records the unchanged value at ptr and its object members as copy sources.
+func (d *differ) keep(ptr string, v any) {
+	if !d.movable(ptr, v) {
+		return
+	}
+	if k, err := canonicalJSON(v); err == nil {
+		if _, ok := d.unchanged[string(k)]; !ok {
+			d.unchanged[string(k)] = ptr
+		}
+	}
+	if m, ok := v.(map[string]any); ok {
+		for _, k := range sortedKeys(m) {
+			d.keep(ptr+"/"+EscapeToken(k), m[k])
+		}
+	}
+}
+
+// movable returns true if the value at ptr can be the source or the target of a move or copy.
+func (d *differ) movable(ptr string, v any) bool {
+	if !d.detectMoves || d.arrays > 0 || ptr == "" {
+		return false
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v) > 0
+	case []any:
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+// relocate replaces the added values by moves of removed values or copies of unchanged values.
+// A move takes the place of the add, the source is kept until then since no other operation
+// touches a removed member.
+func (d *differ) relocate() {
+	removed := map[string][]int{}
+	for i := range d.ops {
+		if v, ok := d.removed[i]; ok {
+			if k, err := canonicalJSON(v); err == nil {
+				removed[string(k)] = append(removed[string(k)], i)
+			}
+		}
+	}
+	drop := map[int]bool{}
+	for _, i := range d.added {
+		op := d.ops[i]
+		k, err := canonicalJSON(*op.Value)
+		if err != nil {
+			continue
+		}
+		if src := removed[string(k)]; len(src) > 0 {
+			removed[string(k)] = src[1:]
+			drop[src[0]] = true
+			from := *d.ops[src[0]].Path
+			d.ops[i] = newOperation(opMove, *op.Path, nil, &from)
+		} else if from, ok := d.unchanged[string(k)]; ok {
+			d.ops[i] = newOperation(opCopy, *op.Path, nil, &from)
+		}
+	}
+	if len(drop) == 0 {
+		return
+	}
+	ops := d.ops[:0]
+	for i, op := range d.ops {
+		if !drop[i] {
+			ops = append(ops, op)
+		}
+	}
+	d.ops = ops
+}
+
+func (d *differ) ignored(ptr string) bool {
+	if len(d.ignores) == 0 {
+		return false
+	}
+	tokens := NewJSONPointer(ptr).Path()
+	for _, pattern := range d.ignores {
+		if len(pattern) <= len(tokens) && matchTokens(pattern, tokens[:len(pattern)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares a and b with ignored paths and the numeric tolerance.
+func (d *differ) equal(ptr string, a, b any) bool {
+	if len(d.ignores) == 0 && d.tolerance == 0 {
+		return reflect.DeepEqual(a, b)
+	}
+	if d.ignored(ptr) {
+		return true
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		return ok && d.equalObject(ptr, a, b)
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !d.equal(ptr+"/"+strconv.Itoa(i), a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case float64:
+		b, ok := b.(float64)
+		return ok && math.Abs(a-b) <= d.tolerance
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func (d *differ) equalObject(ptr string, a, b map[string]any) bool {
+	for k, v := range a {
+		path := ptr + "/" + EscapeToken(k)
+		if w, ok := b[k]; !d.ignored(path) && (!ok || !d.equal(path, v, w)) {
+			return false
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok && !d.ignored(ptr+"/"+EscapeToken(k)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCreatePatch(t *testing.T) {
+	ops, err := CreatePatch(
+		[]byte(`{"a":1,"b":[1,2,3],"c":{"d":"e"},"f/g":1}`),
+		[]byte(`{"a":2,"b":[1,4],"c":{"d":"e","h":null}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mustOperations(t, `[
+		{"op":"remove","path":"/f~1g"},
+		{"op":"replace","path":"/a","value":2},
+		{"op":"replace","path":"/b/1","value":4},
+		{"op":"remove","path":"/b/2"},
+		{"op":"add","path":"/c/h","value":null}
+	]`)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(ops))
+	}
+	if ops := CreatePatchAny([]any{1.0}, []any{1.0}); len(ops) != 0 {
+		t.Fatal("expected empty patch, got", jsonstring(ops))
+	}
+	if _, err := CreatePatch([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCreatePatchRandom(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a":[1,2,{"b":"c"}],"d/e":{"f":null},"g":"h"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for seed := int64(0); seed < 100; seed++ {
+		modified := deepCopy(doc)
+		if err := New().ApplyAny(&modified, copyOperations(GenerateRandomPatch(doc, 10, seed))); err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range [][]DiffOption{
+			nil,
+			{WithMoveDetection(true)},
+			{WithArrayDiffStrategy(ArrayDiffLCS)},
+		} {
+			o := deepCopy(doc)
+			ops := CreatePatchAny(doc, modified, opts...)
+			if err := New(WithStrictPathExists(true)).applyAny(&o, ops); err != nil {
+				t.Fatal("seed", seed, err)
+			}
+			if !reflect.DeepEqual(o, modified) {
+				t.Fatal("seed", seed, "expected", jsonstring(modified), "got", jsonstring(o))
+			}
+		}
+	}
+}
+
+func TestCreatePatchMoveDetection(t *testing.T) {
+	original := []byte(`{"a":{"x":[1,2]},"b":{"c":{"d":1}},"e":[{"f":{"g":1}}],"h":1}`)
+	modified := []byte(`{"b":{"c":{"d":1}},"i":{"x":[1,2]},"j":{"d":1},"e":[{"k":{"g":1}}],"l":1}`)
+	ops, err := CreatePatch(original, modified, WithMoveDetection(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mustOperations(t, `[
+		{"op":"remove","path":"/h"},
+		{"op":"remove","path":"/e/0/f"},
+		{"op":"add","path":"/e/0/k","value":{"g":1}},
+		{"op":"move","from":"/a","path":"/i"},
+		{"op":"copy","from":"/b/c","path":"/j"},
+		{"op":"add","path":"/l","value":1}
+	]`)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(ops))
+	}
+	b, err := New(WithStrictPathExists(true)).Apply(original, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want any
+	_ = json.Unmarshal(b, &got)
+	_ = json.Unmarshal(modified, &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("expected", jsonstring(want), "got", jsonstring(got))
+	}
+}
+
+func TestCreatePatchLCS(t *testing.T) {
+	a := make([]any, 1000)
+	for i := range a {
+		a[i] = float64(i)
+	}
+	b := append(append(append([]any{}, a[:500]...), "x"), a[500:]...)
+	ops := CreatePatchAny(map[string]any{"a": a}, map[string]any{"a": b}, WithArrayDiffStrategy(ArrayDiffLCS))
+	expected := mustOperations(t, `[{"op":"add","path":"/a/500","value":"x"}]`)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(ops))
+	}
+
+	ops, err := CreatePatch([]byte(`[1,2,3,4,5]`), []byte(`[0,2,6,4,7,8]`), WithArrayDiffStrategy(ArrayDiffLCS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = mustOperations(t, `[
+		{"op":"replace","path":"/0","value":0},
+		{"op":"replace","path":"/2","value":6},
+		{"op":"replace","path":"/4","value":7},
+		{"op":"add","path":"/5","value":8}
+	]`)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(ops))
+	}
+}
+
+func TestCreatePatchIgnoreAndTolerance(t *testing.T) {
+	original := []byte(`{"a":1.0,"ts":1,"items":[{"id":1,"updated":"x"},{"id":2,"updated":"y"}],"meta":{"v":1}}`)
+	modified := []byte(`{"a":1.001,"ts":2,"items":[{"id":1,"updated":"z"},{"id":3,"updated":"w"}],"meta":{"v":2,"w":1},"b":true}`)
+	ops, err := CreatePatch(original, modified,
+		WithIgnorePaths("/ts", "/items/*/updated", "/meta"),
+		WithNumericTolerance(0.01),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mustOperations(t, `[
+		{"op":"add","path":"/b","value":true},
+		{"op":"replace","path":"/items/1/id","value":3}
+	]`)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(ops))
+	}
+	ops, err = CreatePatch(original, modified,
+		WithIgnorePaths("/ts", "/items/*/updated", "/meta"),
+		WithArrayDiffStrategy(ArrayDiffLCS),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = mustOperations(t, `[
+		{"op":"replace","path":"/a","value":1.001},
+		{"op":"add","path":"/b","value":true},
+		{"op":"replace","path":"/items/1/id","value":3}
+	]`)
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(ops))
+	}
+}
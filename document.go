@@ -0,0 +1,177 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import "strings"
+
+// Document is a decoded json document.
+// It can build an index to speed up repeated pointer resolution of a large long-lived document.
+// It is not safe for concurrent use.
+type Document struct {
+	root  any
+	index map[string]any
+}
+
+// NewDocument creates a Document of a decoded json value.
+func NewDocument(root any) *Document {
+	return &Document{root: root}
+}
+
+// Root returns the decoded json value of the document.
+func (d *Document) Root() any {
+	return d.root
+}
+
+// Index builds a pointer to node index of the whole document.
+// The index is consulted by Get and Apply, and is updated by Apply, see Apply.
+func (d *Document) Index() {
+	d.index = map[string]any{}
+	d.indexNode("", d.root)
+}
+
+func (d *Document) indexNode(ptr string, v any) {
+	_ = walk(v, ptr, func(ptr string, v any) error {
+		d.index[ptr] = v
+		return nil
+	})
+}
+
+// Indexed returns true if the document has a valid index.
+func (d *Document) Indexed() bool {
+	return d.index != nil
+}
+
+// Get returns the value at pointer.
+func (d *Document) Get(pointer string) (any, error) {
+	if d.index != nil {
+		if v, ok := d.index[pointer]; ok {
+			return v, nil
+		}
+	}
+	ptr := NewJSONPointer(pointer)
+	if err := ptr.Check(); err != nil {
+		return nil, err
+	}
+	v, _, err := New().VisitPath(&d.root, ptr.Path()...)
+	return v, err
+}
+
+// Apply applies ops to the document as p.ApplyAny does.
+// Leading test operations are resolved from the index if the document is indexed.
+// The index is updated in place for the subtrees changed by add, remove, replace, move and copy,
+// it's dropped if the patch fails, or if an operation or option of p makes the changed nodes unknown,
+// e.g. other extensions, patterns, OriginalArrayIndex and RFC6902Strict.
+func (d *Document) Apply(p *Patch, ops []Operation) error {
+	converted, err := p.fromURIFragments(ops)
+	if err != nil {
+		return p.localize(err)
+	}
+	if err := p.Check(converted); err != nil {
+		return err
+	}
+	rest := converted
+	if d.index != nil {
+		for len(rest) > 0 && *rest[0].OP == opTest {
+			v, ok := d.index[*rest[0].Path]
+			if !ok {
+				break
+			}
+			if !EqualAny(v, *rest[0].Value) {
+				return p.operationError(p.extensions[opTest], rest[0], ErrStop)
+			}
+			rest = rest[1:]
+		}
+	}
+	var dirty []string
+	if d.index != nil {
+		var ok bool
+		if dirty, ok = d.dirtyPaths(p, rest); !ok {
+			d.index = nil
+		}
+	}
+	var stale []string
+	for _, ptr := range dirty {
+		if v, ok := d.index[ptr]; ok {
+			_ = walk(v, ptr, func(ptr string, _ any) error {
+				stale = append(stale, ptr)
+				return nil
+			})
+		}
+	}
+	if err := p.applyRoot(&d.root, rest); err != nil {
+		d.index = nil
+		return err
+	}
+	if d.index != nil {
+		for _, ptr := range stale {
+			delete(d.index, ptr)
+		}
+		for _, ptr := range dirty {
+			if v, _, err := p.VisitPath(&d.root, NewJSONPointer(ptr).Path()...); err == nil {
+				d.indexNode(ptr, v)
+			}
+		}
+	}
+	p.route(ops)
+	return nil
+}
+
+// dirtyPaths returns the pointers of the subtrees may be changed by ops.
+// A write to an object member changes the member, a write to an array element
+// changes the array since the later elements are shifted.
+// It returns false if the changed subtrees are unknown.
+func (d *Document) dirtyPaths(p *Patch, ops []Operation) ([]string, bool) {
+	if p.RFC6902Strict || p.OriginalArrayIndex {
+		return nil, false
+	}
+	var dirty []string
+	for _, op := range ops {
+		if isTestOP(*op.OP) {
+			continue
+		}
+		switch *op.OP {
+		case opAdd, opRemove, opReplace, opMove, opCopy:
+		default:
+			return nil, false
+		}
+		if op.From != nil && p.hasPattern(*op.From) {
+			return nil, false
+		}
+		for _, w := range writePaths(op) {
+			if p.hasPattern(w) {
+				return nil, false
+			}
+			if w == "" {
+				return []string{""}, true
+			}
+			if coveredBy(w, dirty) {
+				continue
+			}
+			parent := w[:strings.LastIndex(w, "/")]
+			v, ok := d.index[parent]
+			if !ok {
+				// the parent is neither in the document nor changed by an earlier operation,
+				// or its pointer is not canonical, e.g. a negative array index.
+				return nil, false
+			}
+			if _, ok := v.(map[string]any); ok {
+				dirty = append(dirty, w)
+			} else {
+				dirty = append(dirty, parent)
+			}
+		}
+	}
+	return dirty, true
+}
+
+// coveredBy returns true if ptr is in a subtree of paths.
+func coveredBy(ptr string, paths []string) bool {
+	for _, p := range paths {
+		if isPathPrefix(p, ptr) {
+			return true
+		}
+	}
+	return false
+}
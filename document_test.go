@@ -0,0 +1,116 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentIndex(t *testing.T) {
+	var root any
+	if err := json.Unmarshal([]byte(`{"a":{"b":[1,2,{"c":"d"}]}}`), &root); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDocument(root)
+	d.Index()
+	v, err := d.Get("/a/b/2/c")
+	if err != nil || v != "d" {
+		t.Fatal("bad value", v, err)
+	}
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"test","path":"/a/b/0","value":1}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Apply(New(), ops); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Indexed() {
+		t.Fatal("index is dropped by test operations")
+	}
+	if err := json.Unmarshal([]byte(`[{"op":"test","path":"/a/b/0","value":2}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Apply(New(), ops); !errors.Is(err, ErrStop) {
+		t.Fatal("expected stop, got", err)
+	}
+	if err := json.Unmarshal([]byte(`[{"op":"remove","path":"/a/b/0"}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Apply(New(), ops); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Indexed() {
+		t.Fatal("index is dropped after a change")
+	}
+	v, err = d.Get("/a/b/0")
+	if err != nil || v != 2.0 {
+		t.Fatal("bad value", v, err)
+	}
+	if _, err := d.Get("/a/x"); !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected not exists, got", err)
+	}
+}
+
+func TestDocumentIndexUpdate(t *testing.T) {
+	var root any
+	if err := json.Unmarshal([]byte(`{"a":{"b":[1,{"c":"d"}],"e":{"f":1}},"g":[[1,2],[3]],"h":"i"}`), &root); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDocument(root)
+	d.Index()
+	patches := []string{
+		`[{"op":"add","path":"/a/b/0","value":{"x":1}}]`,
+		`[{"op":"remove","path":"/a/b/1"},{"op":"replace","path":"/a/e/f","value":[1,2]}]`,
+		`[{"op":"move","from":"/a/e","path":"/g/0/1"},{"op":"add","path":"/g/0/1/f/-","value":3}]`,
+		`[{"op":"copy","from":"/g/0","path":"/a/e"},{"op":"replace","path":"/a","value":[1]},{"op":"add","path":"/a/0","value":0}]`,
+		`[{"op":"add","path":"/g/1/-","value":4},{"op":"remove","path":"/h"}]`,
+	}
+	for _, ops := range patches {
+		if err := d.Apply(New(), mustOperations(t, ops)); err != nil {
+			t.Fatal(ops, err)
+		}
+		if !d.Indexed() {
+			t.Fatal(ops, "index is dropped")
+		}
+		expect := NewDocument(d.Root())
+		expect.Index()
+		if !reflect.DeepEqual(d.index, expect.index) {
+			t.Fatal(ops, "bad index", d.index, expect.index)
+		}
+	}
+	// the changed nodes of a pattern are unknown.
+	if err := d.Apply(New(WithWildcards(true)), mustOperations(t, `[{"op":"replace","path":"/g/*","value":1}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if d.Indexed() {
+		t.Fatal("index is not dropped")
+	}
+	if v, err := d.Get("/g/1"); err != nil || v != 1.0 {
+		t.Fatal("bad value", v, err)
+	}
+}
+
+func TestDocumentApply(t *testing.T) {
+	var routed []Operation
+	router := NewRouter()
+	router.Handle("/a", func(ops []Operation) { routed = append(routed, ops...) })
+	p := New(WithRouter(router))
+	d := NewDocument(map[string]any{"a": 1.0})
+	d.Index()
+	if err := d.Apply(p, mustOperations(t, `[{"op":"test","path":"/a","value":1},{"op":"replace","path":"/a","value":2}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(routed) != 2 {
+		t.Fatal("operations are not routed", routed)
+	}
+	d = NewDocument(1.0)
+	err := d.Apply(p, mustOperations(t, `[{"op":"replace","path":"","value":2}]`))
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeBadType {
+		t.Fatal("expected bad type, got", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// WithEmbeddedJSON set the paths of strings containing encoded json documents, e.g. "/payload".
+// An operation whose path or from is under such a string decodes the string, applies to the decoded
+// document, and encodes it back into the string in place, e.g. add "/payload/user/name".
+// A "*" token matches any token. Operations at the embedded path itself read and write the decoded value.
+// The raw engine is not used if any path is set.
+func WithEmbeddedJSON(paths ...string) Option {
+	return func(o *Patch) {
+		for _, path := range paths {
+			o.embedded = append(o.embedded, NewJSONPointer(path).Path())
+		}
+		// outer strings must be decoded before the inner ones.
+		sort.SliceStable(o.embedded, func(i, j int) bool {
+			return len(o.embedded[i]) < len(o.embedded[j])
+		})
+	}
+}
+
+// decodeEmbedded decodes the embedded json strings op refers to,
+// and returns their locations in the order they are decoded.
+func (p *Patch) decodeEmbedded(o *any, op Operation) ([][]string, error) {
+	if len(p.embedded) == 0 {
+		return nil, nil
+	}
+	var decoded [][]string
+	seen := map[string]bool{}
+	for _, ptr := range touchedPaths(op) {
+		tokens := NewJSONPointer(ptr).Path()
+		for _, pattern := range p.embedded {
+			if len(pattern) > len(tokens) || !matchTokens(pattern, tokens[:len(pattern)]) {
+				continue
+			}
+			loc := tokens[:len(pattern)]
+			if seen[joinPointer(loc)] {
+				continue
+			}
+			ok, err := p.decodeEmbeddedAt(o, loc)
+			if err != nil {
+				return decoded, err
+			}
+			if ok {
+				seen[joinPointer(loc)] = true
+				decoded = append(decoded, loc)
+			}
+		}
+	}
+	return decoded, nil
+}
+
+func (p *Patch) decodeEmbeddedAt(o *any, loc []string) (bool, error) {
+	v, set, err := p.VisitPath(o, loc...)
+	if err != nil {
+		// the operation reports the missing path.
+		return false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false, nil
+	}
+	var doc any
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		return false, fmt.Errorf("decode embedded json %s: %w", joinPointer(loc), err)
+	}
+	set(doc)
+	return true, nil
+}
+
+// encodeEmbedded encodes the decoded locations back into strings, the inner ones first.
+func (p *Patch) encodeEmbedded(o *any, decoded [][]string) error {
+	for i := len(decoded) - 1; i >= 0; i-- {
+		v, set, err := p.VisitPath(o, decoded[i]...)
+		if err != nil {
+			// removed or moved by the operation.
+			continue
+		}
+		b, err := p.rawEncode(v)
+		if err != nil {
+			return err
+		}
+		set(string(b))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedJSON(t *testing.T) {
+	p := New(WithEmbeddedJSON("/events/*/payload", "/events/*/payload/meta"), WithRawEngine(true))
+	doc := []byte(`{"events":[{"payload":"{\"user\":{\"name\":\"a\"},\"meta\":\"{\\\"n\\\":1}\"}"}]}`)
+	got, err := p.Apply(doc, mustOperations(t, `[
+		{"op":"test","path":"/events/0/payload/user/name","value":"a"},
+		{"op":"replace","path":"/events/0/payload/user/name","value":"b"},
+		{"op":"add","path":"/events/0/payload/meta/m","value":2},
+		{"op":"copy","from":"/events/0/payload/user","path":"/user"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"events":[{"payload":"{\"meta\":\"{\\\"m\\\":2,\\\"n\\\":1}\",\"user\":{\"name\":\"b\"}}"}],"user":{"name":"b"}}`
+	if s := strings.TrimSpace(string(got)); s != expect {
+		t.Fatal("expected", expect, "got", s)
+	}
+
+	got, err = p.Apply(doc, mustOperations(t, `[{"op":"replace","path":"/events/0/payload","value":{"x":1}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(string(got)); s != `{"events":[{"payload":"{\"x\":1}"}]}` {
+		t.Fatal("bad replace of the embedded document", s)
+	}
+
+	if _, err := p.Apply([]byte(`{"events":[{"payload":"{"}]}`), mustOperations(t, `[{"op":"remove","path":"/events/0/payload/x"}]`)); err == nil {
+		t.Fatal("expected error of bad embedded json")
+	}
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"remove","path":"/events/0/payload/missing"}]`)); err == nil {
+		t.Fatal("expected error of missing member")
+	}
+}
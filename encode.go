@@ -0,0 +1,89 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// EncodeOptions controls the json output of an apply call.
+type EncodeOptions struct {
+	// Prefix and Indent are passed to json.Encoder.SetIndent.
+	// The output is compact if both of them are empty.
+	Prefix string
+	Indent string
+	// EscapeHTML is passed to json.Encoder.SetEscapeHTML.
+	EscapeHTML bool
+	// TrailingNewline appends a newline to the output like json.Encoder does.
+	TrailingNewline bool
+}
+
+// EncodeOptions returns the encode options used by Apply.
+func (p *Patch) EncodeOptions() EncodeOptions {
+	return EncodeOptions{
+		Prefix:          p.JSONPrefix,
+		Indent:          p.JSONIndent,
+		EscapeHTML:      p.JSONEscapeHTML,
+		TrailingNewline: true,
+	}
+}
+
+// ApplyEncoded apply the operations like Apply but encodes the output with e
+// instead of the json options of p.
+// The Cache is not used. The raw engine is used only if e.EscapeHTML equals to JSONEscapeHTML,
+// and Prefix and Indent are ignored by it as Apply does.
+func (p *Patch) ApplyEncoded(b []byte, ops []Operation, e EncodeOptions) ([]byte, error) {
+	out, err := p.applyEncoded(b, ops, e)
+	if err != nil {
+		return nil, err
+	}
+	p.route(ops)
+	return out, nil
+}
+
+func (p *Patch) applyEncoded(b []byte, ops []Operation, e EncodeOptions) ([]byte, error) {
+	if p.canApplyRaw(ops) && e.EscapeHTML == p.JSONEscapeHTML {
+		out, err := p.applyRaw(b, ops)
+		if err != nil {
+			return nil, err
+		}
+		if e.TrailingNewline {
+			out = append(out, '\n')
+		}
+		return out, p.checkOutputSize(out)
+	}
+	var o any
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil, err
+	}
+	if err := p.applyAny(&o, ops); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := e.encode(&buf, o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), p.checkOutputSize(buf.Bytes())
+}
+
+// encode writes o to w.
+// w is untouched on error.
+func (e EncodeOptions) encode(w io.Writer, o any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(e.EscapeHTML)
+	enc.SetIndent(e.Prefix, e.Indent)
+	if err := enc.Encode(o); err != nil {
+		return err
+	}
+	out := buf.Bytes()
+	if !e.TrailingNewline {
+		out = bytes.TrimSuffix(out, []byte{'\n'})
+	}
+	_, err := w.Write(out)
+	return err
+}
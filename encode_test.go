@@ -0,0 +1,48 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"testing"
+)
+
+func TestApplyEncoded(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+	ops := mustOperations(t, `[{"op":"add","path":"/b","value":"<x>"}]`)
+	p := New(WithJSONIndent("", "  "), WithJSONEscapeHTML(true))
+	cases := []struct {
+		e        EncodeOptions
+		expected string
+	}{
+		{EncodeOptions{}, `{"a":1,"b":"<x>"}`},
+		{EncodeOptions{TrailingNewline: true}, "{\"a\":1,\"b\":\"<x>\"}\n"},
+		{EncodeOptions{EscapeHTML: true}, `{"a":1,"b":"\u003cx\u003e"}`},
+		{EncodeOptions{Indent: "\t"}, "{\n\t\"a\": 1,\n\t\"b\": \"<x>\"\n}"},
+		{p.EncodeOptions(), "{\n  \"a\": 1,\n  \"b\": \"\\u003cx\\u003e\"\n}\n"},
+	}
+	for _, c := range cases {
+		out, err := p.ApplyEncoded(doc, ops, c.e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != c.expected {
+			t.Fatalf("expected %q, got %q", c.expected, out)
+		}
+	}
+
+	raw := New(WithRawEngine(true))
+	for _, newline := range []bool{false, true} {
+		out, err := raw.ApplyEncoded(doc, ops, EncodeOptions{TrailingNewline: newline})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"a":1,"b":"<x>"}`
+		if newline {
+			expected += "\n"
+		}
+		if string(out) != expected {
+			t.Fatalf("expected %q, got %q", expected, out)
+		}
+	}
+}
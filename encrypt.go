@@ -0,0 +1,269 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encryptedPrefix is the prefix of an encrypted value envelope.
+const encryptedPrefix = "enc:v1:"
+
+// Cipher encrypts and decrypts values.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+type aesGCMCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMCipher creates an AES-GCM Cipher, the key must be 16, 24 or 32 bytes.
+func NewAESGCMCipher(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCMCipher{aead: aead}, nil
+}
+
+func (c aesGCMCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (c aesGCMCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errors.New("ciphertext too short")
+	}
+	return c.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+}
+
+// EncryptOperations returns a copy of ops whose values written to the sensitive paths are encrypted
+// into string envelopes, so the patch never contains the plaintext.
+// A sensitive path is a json pointer whose token may be "*" to match any member or index,
+// a sensitive path inside of another one is encrypted as a part of it.
+// Writing inside of a sensitive value is an error because the value is stored encrypted,
+// so is moving or copying a value to a sensitive path or its ancestors because the value is not in the patch.
+// Test operations and test extensions are not encrypted, they can not match an encrypted value.
+// The nested operations of if and foreach are encrypted by their paths in the document,
+// the nested paths of foreach match the sensitive paths of any element.
+func EncryptOperations(c Cipher, ops []Operation, paths ...string) ([]Operation, error) {
+	patterns := sensitivePatterns(paths)
+	r := make([]Operation, len(ops))
+	for i, op := range ops {
+		var err error
+		if r[i], err = encryptOperation(c, op, patterns); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// encryptOperation returns a copy of op whose values written to the sensitive paths are encrypted.
+// The nested operations of the composite operations are encrypted as well.
+func encryptOperation(c Cipher, op Operation, patterns [][]string) (Operation, error) {
+	if op.OP == nil || op.Path == nil || isTestOP(*op.OP) {
+		return op, nil
+	}
+	if isCompositeOP(*op.OP) {
+		return rewriteNested(op, func(sub Operation) (Operation, error) {
+			return encryptOperation(c, sub, patterns)
+		})
+	}
+	at := NewJSONPointer(*op.Path).Path()
+	if op.From != nil {
+		for _, sensitive := range patterns {
+			n := len(at)
+			if len(sensitive) < n {
+				n = len(sensitive)
+			}
+			if matchWildTokens(sensitive[:n], at[:n]) {
+				return op, fmt.Errorf("encrypt %s %s: can not %s a value to the sensitive path %s",
+					*op.OP, *op.Path, *op.OP, joinPointer(sensitive))
+			}
+		}
+	}
+	if op.Value == nil {
+		return op, nil
+	}
+	v, err := encryptValue(c, *op.Value, at, patterns)
+	if err != nil {
+		return op, fmt.Errorf("encrypt %s %s: %w", *op.OP, *op.Path, err)
+	}
+	op.Value = &v
+	return op, nil
+}
+
+// sensitivePatterns returns the tokens of the sensitive paths except the ones inside of another path,
+// which are encrypted as a part of it.
+func sensitivePatterns(paths []string) [][]string {
+	all := make([][]string, len(paths))
+	for i, s := range paths {
+		all[i] = NewJSONPointer(s).Path()
+	}
+	var r [][]string
+	for i, t := range all {
+		inside := false
+		for j, s := range all {
+			if j != i && len(t) > len(s) && matchTokens(s, t[:len(s)]) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+func encryptValue(c Cipher, v any, at []string, patterns [][]string) (any, error) {
+	for _, sensitive := range patterns {
+		if len(at) > len(sensitive) && matchWildTokens(sensitive, at[:len(sensitive)]) {
+			return nil, fmt.Errorf("can not write inside of the sensitive path %s", joinPointer(sensitive))
+		}
+	}
+	v = deepCopy(v)
+	// encrypted are the pointers of encrypted nodes, whose descendants are encrypted with them.
+	var encrypted []string
+	err := walk(v, "", func(ptr string, node any) error {
+		for _, e := range encrypted {
+			if isPathPrefix(e, ptr) {
+				return nil
+			}
+		}
+		full := append(append([]string(nil), at...), NewJSONPointer(ptr).Path()...)
+		for _, sensitive := range patterns {
+			if !matchWildTokens(sensitive, full) {
+				continue
+			}
+			e, err := encrypt(c, node)
+			if err != nil {
+				return err
+			}
+			encrypted = append(encrypted, ptr)
+			if ptr == "" {
+				v = e
+				return nil
+			}
+			return NewJSONPointer(ptr).set(&v, e)
+		}
+		return nil
+	})
+	return v, err
+}
+
+// DecryptDocument returns a copy of doc whose encrypted values at the sensitive paths are decrypted.
+func DecryptDocument(c Cipher, doc any, paths ...string) (any, error) {
+	doc = deepCopy(doc)
+	err := walk(doc, "", func(ptr string, node any) error {
+		s, ok := node.(string)
+		if !ok || !strings.HasPrefix(s, encryptedPrefix) {
+			return nil
+		}
+		tokens := NewJSONPointer(ptr).Path()
+		for _, p := range paths {
+			if !matchTokens(NewJSONPointer(p).Path(), tokens) {
+				continue
+			}
+			v, err := decrypt(c, s)
+			if err != nil {
+				return fmt.Errorf("decrypt %s: %w", ptr, err)
+			}
+			if ptr == "" {
+				doc = v
+				return nil
+			}
+			return NewJSONPointer(ptr).set(&doc, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func encrypt(c Cipher, v any) (string, error) {
+	b, err := canonicalJSON(v)
+	if err != nil {
+		return "", err
+	}
+	e, err := c.Encrypt(b)
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(e), nil
+}
+
+func decrypt(c Cipher, s string) (any, error) {
+	e, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedPrefix))
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.Decrypt(e)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// matchTokens returns true if tokens matches the pattern token by token,
+// a "*" pattern token matches any token.
+func matchTokens(pattern, tokens []string) bool {
+	if len(pattern) != len(tokens) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWildTokens is matchTokens but a "*" token of tokens matches any token of pattern as well,
+// e.g. the element of the nested operations of foreach.
+func matchWildTokens(pattern, tokens []string) bool {
+	if len(pattern) != len(tokens) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && tokens[i] != "*" && p != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// set sets the value of an existing node.
+func (p JSONPointer) set(doc *any, v any) error {
+	_, set, err := New().VisitPath(doc, p.Path()...)
+	if err != nil {
+		return err
+	}
+	set(v)
+	return nil
+}
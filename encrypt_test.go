@@ -0,0 +1,146 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptOperations(t *testing.T) {
+	c, err := NewAESGCMCipher([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[
+		{"op":"add","path":"/password","value":"p1"},
+		{"op":"add","path":"/users","value":[{"name":"a","token":{"t":1}}]},
+		{"op":"add","path":"/name","value":"n"}
+	]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"/password", "/users/*/token"}
+	encrypted, err := EncryptOperations(c, ops, paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "p1") || strings.Contains(string(b), `"t"`) {
+		t.Fatal("plaintext in encrypted patch", string(b))
+	}
+	var doc any = map[string]any{}
+	if err := New().ApplyAny(&doc, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecryptDocument(c, doc, paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]any{
+		"password": "p1",
+		"users":    []any{map[string]any{"name": "a", "token": map[string]any{"t": 1.0}}},
+		"name":     "n",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatal("expected", expect, "got", got)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"op":"add","path":"/users/0/token/t","value":2}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncryptOperations(c, ops, paths...); err == nil {
+		t.Fatal("expected error writing inside of a sensitive path")
+	}
+}
+
+func TestEncryptOperationsSensitivePaths(t *testing.T) {
+	c, err := NewAESGCMCipher([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, paths := range [][]string{{"/a", "/a/b"}, {"/a/b", "/a"}, {"/a/b", "/a/*/c"}} {
+		ops := mustOperations(t, `[{"op":"add","path":"/a","value":{"b":{"c":1},"d":2}}]`)
+		encrypted, err := EncryptOperations(c, ops, paths...)
+		if err != nil {
+			t.Fatal(paths, err)
+		}
+		var doc any = map[string]any{}
+		if err := New().ApplyAny(&doc, encrypted); err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecryptDocument(c, doc, paths...)
+		if err != nil {
+			t.Fatal(paths, err)
+		}
+		expect := map[string]any{"a": map[string]any{"b": map[string]any{"c": 1.0}, "d": 2.0}}
+		if !reflect.DeepEqual(got, expect) {
+			t.Fatal(paths, "expected", expect, "got", got)
+		}
+	}
+	for _, ops := range []string{
+		`[{"op":"move","from":"/plain","path":"/secret"}]`,
+		`[{"op":"copy","from":"/plain","path":"/secret/x"}]`,
+		`[{"op":"copy","from":"/plain","path":""}]`,
+	} {
+		if _, err := EncryptOperations(c, mustOperations(t, ops), "/secret"); err == nil {
+			t.Fatal(ops, "expected error of moving to a sensitive path")
+		}
+	}
+	if _, err := EncryptOperations(c, mustOperations(t, `[{"op":"copy","from":"/secret","path":"/plain"}]`), "/secret"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptOperationsComposite(t *testing.T) {
+	c, err := NewAESGCMCipher([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := mustOperations(t, `[
+		{"op":"foreach","path":"/users","value":[{"op":"add","path":"/password","value":"hunter2"}]},
+		{"op":"if","path":"/users/0","value":{
+			"test":[{"op":"test","path":"/name","value":"a"}],
+			"then":[{"op":"foreach","path":"/keys","value":[{"op":"replace","path":"/secret","value":"s3"}]}],
+			"else":[{"op":"replace","path":"/password","value":"hunter3"}]
+		}}
+	]`)
+	encrypted, err := EncryptOperations(c, ops, "/users/*/password", "/users/0/keys/*/secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{"hunter2", "hunter3", "s3"} {
+		if strings.Contains(string(b), plain) {
+			t.Fatal(plain, "is not encrypted", string(b))
+		}
+	}
+	if !strings.Contains(string(b), `"value":"a"`) {
+		t.Fatal("test is encrypted", string(b))
+	}
+	p := New(WithExtension(IfExtension{}), WithExtension(ForeachExtension{}))
+	var doc any = map[string]any{"users": []any{map[string]any{"name": "a", "keys": []any{map[string]any{"secret": "s0"}}}}}
+	if err := p.ApplyAny(&doc, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecryptDocument(c, doc, "/users/*/password", "/users/0/keys/*/secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]any{"users": []any{map[string]any{"name": "a", "password": "hunter2", "keys": []any{map[string]any{"secret": "s3"}}}}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatal("expected", expect, "got", got)
+	}
+	if _, err := EncryptOperations(c, mustOperations(t, `[{"op":"foreach","path":"/users","value":[{"op":"copy","from":"/name","path":"/password"}]}]`), "/users/*/password"); err == nil {
+		t.Fatal("expected error of copying to a sensitive path")
+	}
+}
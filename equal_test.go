@@ -0,0 +1,42 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		a, b  string
+		equal bool
+	}{
+		{`{"a":1,"b":[1,2]}`, `{"b":[1,2.0],"a":1e0}`, true},
+		{`{"a":1}`, `{"a":1,"b":null}`, false},
+		{`[1,2]`, `[2,1]`, false},
+		{`"1"`, `1`, false},
+		{`null`, `null`, true},
+		{`{"a":{}}`, `{"a":[]}`, false},
+	}
+	for _, c := range cases {
+		eq, err := Equal([]byte(c.a), []byte(c.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eq != c.equal {
+			t.Fatal(c.a, c.b, "expected", c.equal)
+		}
+	}
+	if _, err := Equal([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if !EqualAny(map[string]any{"a": []any{1, int64(2), json.Number("3")}}, map[string]any{"a": []any{1.0, 2.0, 3.0}}) {
+		t.Fatal("expected go numbers equal")
+	}
+	var doc any = map[string]any{"a": 1.0}
+	if err := New().ApplyAny(&doc, []Operation{newOperation(opTest, "/a", 1, nil)}); err != nil {
+		t.Fatal("expected test passes with go int value", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode identifies the kind of an Error.
+type ErrorCode string
+
+// Error codes and their parameters.
+const (
+	// CodeMissingMember: op (empty for any operation), member.
+	CodeMissingMember ErrorCode = "missing_member"
+	// CodeBadPointer: pointer.
+	CodeBadPointer ErrorCode = "bad_pointer"
+	// CodeBadEscape: pointer, index (of the token), token.
+	CodeBadEscape ErrorCode = "bad_escape"
+	// CodeUnknownOperation: op.
+	CodeUnknownOperation ErrorCode = "unknown_operation"
+	// CodeInvalidOperation: operation, cause.
+	CodeInvalidOperation ErrorCode = "invalid_operation"
+	// CodeBadArrayIndex: index.
+	CodeBadArrayIndex ErrorCode = "bad_array_index"
+	// CodeIndexOutOfRange: index, size.
+	CodeIndexOutOfRange ErrorCode = "array_index_out_of_range"
+	// CodeBadType: action, type.
+	CodeBadType ErrorCode = "bad_type"
+	// CodePathNotExists: path, cause.
+	CodePathNotExists ErrorCode = "path_not_exists"
+	// CodeOperationFailed: operation, extension, cause.
+	CodeOperationFailed ErrorCode = "operation_failed"
+	// CodeOperationStopped: operation, extension, cause.
+	CodeOperationStopped ErrorCode = "operation_stopped"
+)
+
+// ErrorRenderer renders the message of an error code with its parameters.
+// The cause parameter is an error whose message is rendered by the same renderer.
+type ErrorRenderer func(code ErrorCode, params map[string]any) string
+
+// WithErrorRenderer set the renderer of error messages,
+// so applications can translate or rewrite the messages of returned errors.
+func WithErrorRenderer(r ErrorRenderer) Option {
+	return func(o *Patch) {
+		o.errorRenderer = r
+	}
+}
+
+// Error is an error with a code and parameters returned by Patch.
+type Error struct {
+	Code   ErrorCode
+	Params map[string]any
+
+	cause  error
+	render ErrorRenderer
+}
+
+func newError(code ErrorCode, params map[string]any, cause error) *Error {
+	if cause != nil {
+		params["cause"] = cause
+	}
+	return &Error{Code: code, Params: params, cause: cause}
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.render != nil {
+		return e.render(e.Code, e.Params)
+	}
+	return DefaultErrorMessage(e.Code, e.Params)
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// DefaultErrorMessage returns the default english message of an error code.
+func DefaultErrorMessage(code ErrorCode, params map[string]any) string {
+	p := func(k string) any { return params[k] }
+	switch code {
+	case CodeMissingMember:
+		article := "a"
+		if p("member") == "op" {
+			article = "an"
+		}
+		if op, _ := params["op"].(string); op != "" {
+			return fmt.Sprintf("operation %s must contains %s %s member", op, article, p("member"))
+		}
+		return fmt.Sprintf("must contains %s %s member", article, p("member"))
+	case CodeBadPointer:
+		return "json pointer must start with /"
+	case CodeBadEscape:
+		return fmt.Sprintf("json pointer %s has an invalid escape in token %d: %s", p("pointer"), p("index"), p("token"))
+	case CodeUnknownOperation:
+		return fmt.Sprintf("unknown operation: %s", p("op"))
+	case CodeInvalidOperation:
+		return fmt.Sprintf("%v: %s", p("cause"), p("operation"))
+	case CodeBadArrayIndex:
+		return fmt.Sprintf("bad array index: %s", p("index"))
+	case CodeIndexOutOfRange:
+		return fmt.Sprintf("array index out of range: size=%d, %s", p("size"), p("index"))
+	case CodeBadType:
+		if p("action") == "visit" {
+			return fmt.Sprintf("cannot visit type: %s", p("type"))
+		}
+		return fmt.Sprintf("bad type for %s: %s", p("action"), p("type"))
+	case CodePathNotExists:
+		return fmt.Sprintf("path not exists: %s, err=%v", p("path"), p("cause"))
+	case CodeOperationFailed:
+		return fmt.Sprintf("operation failed: %s ext=%s, err=%v", p("operation"), p("extension"), p("cause"))
+	case CodeOperationStopped:
+		return fmt.Sprintf("operation stopped: %s ext=%s, err=%v", p("operation"), p("extension"), p("cause"))
+	default:
+		return fmt.Sprintf("%s: %v", code, params)
+	}
+}
+
+// localize sets the renderer of p to all errors in the chain of err.
+func (p *Patch) localize(err error) error {
+	if p.errorRenderer == nil {
+		return err
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if v, ok := e.(*Error); ok {
+			v.render = p.errorRenderer
+		}
+	}
+	return err
+}
+
+func errMissingMember(op, member string) error {
+	return newError(CodeMissingMember, map[string]any{"op": op, "member": member}, nil)
+}
+
+func errPathNotExists(path string, cause error) error {
+	return newError(CodePathNotExists, map[string]any{"path": path}, cause)
+}
+
+func errBadType(action string, o any) error {
+	return newError(CodeBadType, map[string]any{"action": action, "type": fmt.Sprintf("%T", o)}, nil)
+}
+
+func errBadArrayIndex(s string) error {
+	return newError(CodeBadArrayIndex, map[string]any{"index": s}, nil)
+}
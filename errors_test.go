@@ -0,0 +1,75 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrorDefaultMessage(t *testing.T) {
+	p := New(WithStrictPathExists(true))
+	doc := []byte(`{"a":1}`)
+	_, err := p.Apply(doc, mustOperations(t, `[{"op":"remove","path":"/b"}]`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected ErrNotExists", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeOperationFailed {
+		t.Fatal("expected operation failed error", err)
+	}
+	if !strings.HasPrefix(err.Error(), "operation failed: remove /b") {
+		t.Fatal("unexpected message", err)
+	}
+
+	_, err = p.Apply(doc, mustOperations(t, `[{"path":"/b"}]`))
+	if err == nil || err.Error() != "must contains an op member" {
+		t.Fatal("unexpected message", err)
+	}
+}
+
+func TestWithErrorRenderer(t *testing.T) {
+	var codes []ErrorCode
+	p := New(WithStrictPathExists(true), WithErrorRenderer(func(code ErrorCode, params map[string]any) string {
+		codes = append(codes, code)
+		switch code {
+		case CodePathNotExists:
+			return fmt.Sprintf("chemin inexistant: %s (%v)", params["path"], params["cause"])
+		case CodeOperationFailed:
+			return fmt.Sprintf("échec: %v", params["cause"])
+		default:
+			return DefaultErrorMessage(code, params)
+		}
+	}))
+	_, err := p.Apply([]byte(`{"a":1}`), mustOperations(t, `[{"op":"replace","path":"/b/c","value":1}]`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if err.Error() != "échec: chemin inexistant: /b/c (path member not exists)" {
+		t.Fatal("unexpected message", err)
+	}
+	if len(codes) != 2 || codes[0] != CodeOperationFailed || codes[1] != CodePathNotExists {
+		t.Fatal("unexpected codes", codes)
+	}
+
+	_, err = p.Apply([]byte(`{}`), mustOperations(t, `[{"op":"unknown","path":"/a"}]`))
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeUnknownOperation || e.Params["op"] != "unknown" {
+		t.Fatal("expected unknown operation error", err)
+	}
+}
+
+func mustOperations(t *testing.T, s string) []Operation {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(s), &ops); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// DependencyKind is the reason an operation depends on an earlier operation.
+type DependencyKind string
+
+// Dependency kinds.
+const (
+	// DependsOnRead means the operation reads, by test, copy or move, a value written by the earlier operation.
+	DependsOnRead DependencyKind = "read"
+	// DependsOnWrite means the operation writes a value written or read by the earlier operation.
+	DependsOnWrite DependencyKind = "write"
+	// DependsOnIndex means the operation refers to an array element whose index is shifted by
+	// the insert or remove of the earlier operation.
+	DependsOnIndex DependencyKind = "index"
+)
+
+// Dependency is an edge of the dependency graph, the operation To must be applied after From.
+type Dependency struct {
+	From int            `json:"from"`
+	To   int            `json:"to"`
+	Kind DependencyKind `json:"kind"`
+	// Path is the pointer of To causes the dependency.
+	Path string `json:"path"`
+}
+
+// Explanation is the dependency graph of a patch.
+type Explanation struct {
+	Operations   []Operation  `json:"operations"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Explain returns the dependency graph of ops without a document.
+// Overlapping pointers, which equal to or are an ancestor of each other, depend on the order,
+// except that tests never depend on tests.
+// Tokens look like an array index are treated as an array index.
+func Explain(ops []Operation) *Explanation {
+	e := &Explanation{Operations: ops, Dependencies: []Dependency{}}
+	for j := range ops {
+		for i := 0; i < j; i++ {
+			if d, ok := dependency(ops[i], ops[j]); ok {
+				d.From, d.To = i, j
+				e.Dependencies = append(e.Dependencies, d)
+			}
+		}
+	}
+	return e
+}
+
+// dependency returns the dependency of y on the earlier operation x.
+func dependency(x, y Operation) (Dependency, bool) {
+	if x.OP == nil || y.OP == nil {
+		return Dependency{}, false
+	}
+	for _, r := range readPaths(y) {
+		if overlapsAny(r, writePaths(x)) {
+			return Dependency{Kind: DependsOnRead, Path: r}, true
+		}
+	}
+	for _, w := range writePaths(y) {
+		if overlapsAny(w, writePaths(x)) || overlapsAny(w, readPaths(x)) {
+			return Dependency{Kind: DependsOnWrite, Path: w}, true
+		}
+	}
+	for _, e := range arrayEdits(x) {
+		for _, ptr := range touchedPaths(y) {
+			if e.affects(ptr) {
+				return Dependency{Kind: DependsOnIndex, Path: ptr}, true
+			}
+		}
+	}
+	return Dependency{}, false
+}
+
+func readPaths(op Operation) []string {
+	switch {
+	case isTestOP(*op.OP) && op.Path != nil:
+		return []string{*op.Path}
+	case (*op.OP == opCopy || *op.OP == opMove) && op.From != nil:
+		return []string{*op.From}
+	default:
+		return nil
+	}
+}
+
+func writePaths(op Operation) []string {
+	if isTestOP(*op.OP) {
+		return nil
+	}
+	switch *op.OP {
+	case opMove:
+		return touchedPaths(op)
+	case OpRename:
+		// rename writes the new member besides removing path.
+		if to, ok := renameTarget(op); ok {
+			return []string{*op.Path, to}
+		}
+		return touchedPaths(op)
+	default:
+		if op.Path == nil {
+			return nil
+		}
+		return []string{*op.Path}
+	}
+}
+
+func overlapsAny(ptr string, paths []string) bool {
+	for _, p := range paths {
+		if isPathPrefix(p, ptr) || isPathPrefix(ptr, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// arrayEdits returns the array inserts and removes of op.
+func arrayEdits(op Operation) []arrayEdit {
+	if op.Path == nil {
+		return nil
+	}
+	switch *op.OP {
+	case opAdd, opCopy:
+		return []arrayEdit{{*op.Path, 1}}
+	case opRemove:
+		return []arrayEdit{{*op.Path, -1}}
+	case opMove:
+		if op.From != nil {
+			return []arrayEdit{{*op.From, -1}, {*op.Path, 1}}
+		}
+	}
+	return nil
+}
+
+// affects returns true if the array element referred by ptr may be changed by the edit.
+// An append affects every element of the array, since the length is unknown.
+func (e arrayEdit) affects(ptr string) bool {
+	at := NewJSONPointer(e.path).Path()
+	if len(at) == 0 {
+		return false
+	}
+	parent := len(at) - 1
+	tokens := NewJSONPointer(ptr).Path()
+	if len(tokens) <= parent || !isIndexToken(at[parent]) || !isIndexToken(tokens[parent]) {
+		return false
+	}
+	if joinPointer(tokens[:parent]) != joinPointer(at[:parent]) {
+		return false
+	}
+	if at[parent] == "-" || tokens[parent] == "-" {
+		return true
+	}
+	return e.shift(ptr) != ptr
+}
+
+func isIndexToken(token string) bool {
+	return token == "-" || indexRE.MatchString(token)
+}
+
+// Levels returns the indices of operations grouped by their depth in the graph.
+// Operations of the same level do not depend on each other.
+func (e *Explanation) Levels() [][]int {
+	level := make([]int, len(e.Operations))
+	for _, d := range e.Dependencies {
+		// dependencies are sorted by To, and From is always before To.
+		if level[d.From]+1 > level[d.To] {
+			level[d.To] = level[d.From] + 1
+		}
+	}
+	var levels [][]int
+	for i, l := range level {
+		for len(levels) <= l {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], i)
+	}
+	return levels
+}
+
+// DOT returns the graph in the graphviz DOT language.
+func (e *Explanation) DOT() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("digraph patch {\n")
+	for i, op := range e.Operations {
+		fmt.Fprintf(&buf, "  n%d [label=%s];\n", i, strconv.Quote(explainLabel(i, op)))
+	}
+	for _, d := range e.Dependencies {
+		fmt.Fprintf(&buf, "  n%d -> n%d [label=%s];\n", d.From, d.To, strconv.Quote(string(d.Kind)+" "+d.Path))
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func explainLabel(i int, op Operation) string {
+	label := fmt.Sprintf("#%d %s %s", i, ptrString(op.OP), ptrString(op.Path))
+	if op.From != nil {
+		label += " from " + *op.From
+	}
+	return label
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	ops := mustOperations(t, `[
+		{"op":"add","path":"/a","value":{}},
+		{"op":"add","path":"/b","value":1},
+		{"op":"add","path":"/a/x","value":1},
+		{"op":"test","path":"/b","value":1},
+		{"op":"remove","path":"/arr/1"},
+		{"op":"replace","path":"/arr/3","value":1},
+		{"op":"replace","path":"/arr/0","value":1},
+		{"op":"copy","from":"/a","path":"/c"}
+	]`)
+	e := Explain(ops)
+	expect := []Dependency{
+		{From: 0, To: 2, Kind: DependsOnWrite, Path: "/a/x"},
+		{From: 1, To: 3, Kind: DependsOnRead, Path: "/b"},
+		{From: 4, To: 5, Kind: DependsOnIndex, Path: "/arr/3"},
+		{From: 0, To: 7, Kind: DependsOnRead, Path: "/a"},
+		{From: 2, To: 7, Kind: DependsOnRead, Path: "/a"},
+	}
+	if !reflect.DeepEqual(e.Dependencies, expect) {
+		t.Fatal("expected", jsonstring(expect), "got", jsonstring(e.Dependencies))
+	}
+	levels := [][]int{{0, 1, 4, 6}, {2, 3, 5}, {7}}
+	if got := e.Levels(); !reflect.DeepEqual(got, levels) {
+		t.Fatal("expected", levels, "got", got)
+	}
+	dot := string(e.DOT())
+	for _, s := range []string{"digraph patch {\n", `n0 [label="#0 add /a"];`, `n7 [label="#7 copy /c from /a"];`, `n4 -> n5 [label="index /arr/3"];`} {
+		if !strings.Contains(dot, s) {
+			t.Fatal("expected", s, "in", dot)
+		}
+	}
+
+	appends := Explain(mustOperations(t, `[{"op":"add","path":"/arr/-","value":1},{"op":"test","path":"/arr/0","value":1},{"op":"test","path":"/other/0","value":1}]`))
+	if len(appends.Dependencies) != 1 || appends.Dependencies[0].Kind != DependsOnIndex {
+		t.Fatal("expected an index dependency of append", appends.Dependencies)
+	}
+}
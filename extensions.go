@@ -0,0 +1,897 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operations of the extensions shipped with the package.
+// They are not registered by default, register them by WithExtension.
+const (
+	OpInc        = "inc"
+	OpStrReplace = "str-replace"
+	OpAppend     = "append"
+	OpPrepend    = "prepend"
+	OpSplice     = "splice"
+	OpMerge      = "merge"
+	OpDefault    = "default"
+	OpRename     = "rename"
+	OpSort       = "sort"
+	OpUnique     = "unique"
+	OpFlatten    = "flatten"
+	OpUnflatten  = "unflatten"
+	OpConcat     = "concat"
+)
+
+// visitOperand returns the node at the path of op and its setter.
+func visitOperand(p *Patch, o *any, op Operation) (any, Setter, error) {
+	v, set, err := p.VisitPath(o, p.pointer(*op.Path).Path()...)
+	if err != nil {
+		return nil, nil, errPathNotExists(*op.Path, err)
+	}
+	return v, set, nil
+}
+
+// visitArray returns the array at the path of op and its setter.
+func visitArray(p *Patch, o *any, op Operation) ([]any, Setter, error) {
+	v, set, err := visitOperand(p, o, op)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, ok := v.([]any)
+	if !ok {
+		return nil, nil, errBadType(*op.OP, v)
+	}
+	return a, set, nil
+}
+
+// writeElements returns the values written to the array at path from the index start.
+func (p *Patch) writeElements(path string, start int, values []any) ([]any, error) {
+	r := make([]any, len(values))
+	for i, v := range values {
+		var err error
+		if r[i], err = p.writeValue(path+"/"+strconv.Itoa(start+i), deepCopy(v)); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// checkValues returns an error if the value of op is not an array.
+func checkValues(op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(*op.OP, "value")
+	}
+	if _, ok := (*op.Value).([]any); !ok {
+		return fmt.Errorf("value of %s must be an array of values", *op.OP)
+	}
+	return nil
+}
+
+// IncExtension is the "inc" operation adds the number value to the number at path,
+// e.g. {"op":"inc","path":"/counter","value":-1} decrements the counter.
+// The number is read and written by one operation, so it does not race like test and replace
+// when patches are applied by compare-and-swap, see ApplyToKey.
+type IncExtension struct{}
+
+// OP implements Extension.
+func (IncExtension) OP() string {
+	return OpInc
+}
+
+// Apply implements Extension.
+func (IncExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, set, err := visitOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	n, ok := toFloat(v)
+	if !ok {
+		return errBadType(OpInc, v)
+	}
+	delta, _ := toFloat(*op.Value)
+	value, err := p.writeValue(*op.Path, n+delta)
+	if err != nil {
+		return err
+	}
+	set(value)
+	return nil
+}
+
+// Check implements Extension.
+func (IncExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpInc, "value")
+	}
+	if _, ok := toFloat(*op.Value); !ok {
+		return errors.New("value of inc must be a number")
+	}
+	return nil
+}
+
+// StrReplaceExtension is the "str-replace" operation replaces all the substrings of the string at path,
+// e.g. {"op":"str-replace","path":"/msg","value":{"pattern":"foo","repl":"bar"}}.
+// If the regexp member of value is true, pattern is a regular expression and
+// repl can refer the submatches like $1, see regexp.Regexp.ReplaceAllString.
+type StrReplaceExtension struct{}
+
+// OP implements Extension.
+func (StrReplaceExtension) OP() string {
+	return OpStrReplace
+}
+
+// Apply implements Extension.
+func (StrReplaceExtension) Apply(p *Patch, o *any, op Operation) error {
+	replace, err := strReplacer(*op.Value)
+	if err != nil {
+		return err
+	}
+	v, set, err := visitOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return errBadType(OpStrReplace, v)
+	}
+	value, err := p.writeValue(*op.Path, replace(s))
+	if err != nil {
+		return err
+	}
+	set(value)
+	return nil
+}
+
+// Check implements Extension.
+func (StrReplaceExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpStrReplace, "value")
+	}
+	_, err := strReplacer(*op.Value)
+	return err
+}
+
+// strReplacer returns the replace function of the value of a str-replace operation.
+func strReplacer(value any) (func(s string) string, error) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("value of str-replace must be an object")
+	}
+	pattern, ok := m["pattern"].(string)
+	if !ok || pattern == "" {
+		return nil, errors.New("pattern of str-replace must be a non-empty string")
+	}
+	repl, ok := m["repl"].(string)
+	if !ok {
+		return nil, errors.New("repl of str-replace must be a string")
+	}
+	if isRegexp, _ := m["regexp"].(bool); !isRegexp {
+		return func(s string) string { return strings.ReplaceAll(s, pattern, repl) }, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("bad pattern of str-replace: %w", err)
+	}
+	return func(s string) string { return re.ReplaceAllString(s, repl) }, nil
+}
+
+// AppendExtension is the "append" operation appends the values to the end of the array at path,
+// e.g. {"op":"append","path":"/tags","value":["a","b"]}.
+// The value is always an array of values, so appending an array is {"value":[[1,2]]}.
+// It is an error if the node at path is not an array.
+type AppendExtension struct{}
+
+// OP implements Extension.
+func (AppendExtension) OP() string {
+	return OpAppend
+}
+
+// Apply implements Extension.
+func (AppendExtension) Apply(p *Patch, o *any, op Operation) error {
+	a, set, err := visitArray(p, o, op)
+	if err != nil {
+		return err
+	}
+	values, err := p.writeElements(*op.Path, len(a), (*op.Value).([]any))
+	if err != nil {
+		return err
+	}
+	set(append(a, values...))
+	return nil
+}
+
+// Check implements Extension.
+func (AppendExtension) Check(_ *Patch, op Operation) error {
+	return checkValues(op)
+}
+
+// PrependExtension is the "prepend" operation inserts the values at the head of the array at path in order,
+// e.g. {"op":"prepend","path":"/recent","value":["c"]}.
+// The value is an array of values as the value of AppendExtension.
+// It is an error if the node at path is not an array.
+type PrependExtension struct{}
+
+// OP implements Extension.
+func (PrependExtension) OP() string {
+	return OpPrepend
+}
+
+// Apply implements Extension.
+func (PrependExtension) Apply(p *Patch, o *any, op Operation) error {
+	a, set, err := visitArray(p, o, op)
+	if err != nil {
+		return err
+	}
+	values, err := p.writeElements(*op.Path, 0, (*op.Value).([]any))
+	if err != nil {
+		return err
+	}
+	set(append(values, a...))
+	return nil
+}
+
+// Check implements Extension.
+func (PrependExtension) Check(_ *Patch, op Operation) error {
+	return checkValues(op)
+}
+
+// SpliceExtension is the "splice" operation replaces a range of the array at path like Array.splice of javascript,
+// e.g. {"op":"splice","path":"/items","value":{"start":1,"deleteCount":2,"values":["x"]}}
+// removes 2 elements from the index 1 and inserts "x" there.
+// A negative start counts from the end, start and deleteCount are clamped to the array,
+// deleteCount removes all the elements from start if it is omitted, and values is optional.
+type SpliceExtension struct{}
+
+// OP implements Extension.
+func (SpliceExtension) OP() string {
+	return OpSplice
+}
+
+// Apply implements Extension.
+func (SpliceExtension) Apply(p *Patch, o *any, op Operation) error {
+	a, set, err := visitArray(p, o, op)
+	if err != nil {
+		return err
+	}
+	args, err := parseSplice(*op.Value)
+	if err != nil {
+		return err
+	}
+	start := args.start
+	if start < 0 {
+		start += len(a)
+	}
+	start = clampInt(start, 0, len(a))
+	end := len(a)
+	if args.deleteCount != nil {
+		end = start + clampInt(*args.deleteCount, 0, len(a)-start)
+	}
+	values, err := p.writeElements(*op.Path, start, args.values)
+	if err != nil {
+		return err
+	}
+	r := make([]any, 0, len(a)-(end-start)+len(values))
+	r = append(r, a[:start]...)
+	r = append(r, values...)
+	set(append(r, a[end:]...))
+	return nil
+}
+
+// Check implements Extension.
+func (SpliceExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpSplice, "value")
+	}
+	_, err := parseSplice(*op.Value)
+	return err
+}
+
+type spliceArgs struct {
+	start       int
+	deleteCount *int
+	values      []any
+}
+
+func parseSplice(value any) (spliceArgs, error) {
+	var r spliceArgs
+	m, ok := value.(map[string]any)
+	if !ok {
+		return r, errors.New("value of splice must be an object")
+	}
+	start, ok := toInt(m["start"])
+	if !ok {
+		return r, errors.New("start of splice must be an integer")
+	}
+	r.start = start
+	if v, exists := m["deleteCount"]; exists {
+		n, ok := toInt(v)
+		if !ok {
+			return r, errors.New("deleteCount of splice must be an integer")
+		}
+		r.deleteCount = &n
+	}
+	if v, exists := m["values"]; exists {
+		if r.values, ok = v.([]any); !ok {
+			return r, errors.New("values of splice must be an array")
+		}
+	}
+	return r, nil
+}
+
+// toInt returns the integer of a json number.
+func toInt(v any) (int, bool) {
+	f, ok := toFloat(v)
+	if !ok || f != float64(int(f)) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// MergeExtension is the "merge" operation applies the value as a json merge patch introduced in RFC7386
+// to the node at path, e.g. {"op":"merge","path":"/config","value":{"debug":null,"log":{"level":"info"}}}
+// removes debug and sets log.level of config, other members are untouched.
+// See MergePatch.
+type MergeExtension struct{}
+
+// OP implements Extension.
+func (MergeExtension) OP() string {
+	return OpMerge
+}
+
+// Apply implements Extension.
+func (MergeExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, set, err := visitOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	value, err := p.writeValue(*op.Path, MergePatch(v, *op.Value))
+	if err != nil {
+		return err
+	}
+	set(value)
+	return nil
+}
+
+// Check implements Extension.
+func (MergeExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpMerge, "value")
+	}
+	return nil
+}
+
+// DefaultExtension is the "default" operation adds the value like add only if path not exists,
+// an existing value is untouched, e.g. {"op":"default","path":"/config/port","value":80}.
+// The parent of path must exist, and "-" of an array always appends the value.
+type DefaultExtension struct{}
+
+// OP implements Extension.
+func (DefaultExtension) OP() string {
+	return OpDefault
+}
+
+// Apply implements Extension.
+func (DefaultExtension) Apply(p *Patch, o *any, op Operation) error {
+	path := p.pointer(*op.Path)
+	if path.IsTheWholeDocument() {
+		return nil
+	}
+	parent, set, err := p.VisitPath(o, path.ParentPath()...)
+	if err != nil {
+		return errPathNotExists(*op.Path, err)
+	}
+	if _, _, err := p.visitPathPart(parent, path.LastToken()); err == nil {
+		return nil
+	}
+	value, err := p.writeValue(*op.Path, deepCopy(*op.Value))
+	if err != nil {
+		return err
+	}
+	return p.AddValue(parent, set, path.LastToken(), value)
+}
+
+// Check implements Extension.
+func (DefaultExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpDefault, "value")
+	}
+	return nil
+}
+
+// RenameExtension is the "rename" operation renames the member at path to the string value
+// in the same object, e.g. {"op":"rename","path":"/user/name","value":"fullName"}.
+// It is an error if the parent is not an object or the new member exists already.
+type RenameExtension struct{}
+
+// OP implements Extension.
+func (RenameExtension) OP() string {
+	return OpRename
+}
+
+// Apply implements Extension.
+func (RenameExtension) Apply(p *Patch, o *any, op Operation) error {
+	path := p.pointer(*op.Path)
+	parent, _, err := p.VisitPath(o, path.ParentPath()...)
+	if err != nil {
+		return errPathNotExists(*op.Path, err)
+	}
+	m, ok := parent.(map[string]any)
+	if !ok {
+		return errBadType(OpRename, parent)
+	}
+	from, to := path.LastToken(), (*op.Value).(string)
+	v, ok := m[from]
+	if !ok {
+		return errPathNotExists(*op.Path, ErrNotExists)
+	}
+	if from == to {
+		return nil
+	}
+	if _, ok := m[to]; ok {
+		return fmt.Errorf("can not rename %s to the existing member %s", *op.Path, to)
+	}
+	v, err = p.writeValue(NewJSONPointerFromTokens(append(path.ParentPath(), to)...).String(), v)
+	if err != nil {
+		return err
+	}
+	delete(m, from)
+	m[to] = v
+	return nil
+}
+
+// renameTarget returns the pointer of the new member of a rename operation.
+func renameTarget(op Operation) (string, bool) {
+	if op.Path == nil || op.Value == nil {
+		return "", false
+	}
+	to, ok := (*op.Value).(string)
+	if !ok {
+		return "", false
+	}
+	return NewJSONPointer(*op.Path).Parent().AppendToken(to).String(), true
+}
+
+// Check implements Extension.
+func (RenameExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpRename, "value")
+	}
+	if _, ok := (*op.Value).(string); !ok {
+		return errors.New("value of rename must be a string")
+	}
+	if *op.Path == "" {
+		return errors.New("can not rename the whole document")
+	}
+	return nil
+}
+
+// SortExtension is the "sort" operation sorts the array at path stably,
+// e.g. {"op":"sort","path":"/users","value":{"by":"/name","order":"desc"}}.
+// The value is optional, by is a json pointer relative to the elements and the elements are compared
+// as a whole if it is empty, order is "asc" (the default) or "desc".
+// Numbers and strings are compared by their values, values of different types are ordered by
+// missing, null, boolean, number, string, array and object, and arrays and objects are compared
+// by their canonical json.
+type SortExtension struct{}
+
+// OP implements Extension.
+func (SortExtension) OP() string {
+	return OpSort
+}
+
+// Apply implements Extension.
+func (SortExtension) Apply(p *Patch, o *any, op Operation) error {
+	a, set, err := visitArray(p, o, op)
+	if err != nil {
+		return err
+	}
+	by, desc, err := parseSort(op)
+	if err != nil {
+		return err
+	}
+	keys := make([]any, len(a))
+	for i, e := range a {
+		if keys[i], err = by.Resolve(e); err != nil {
+			keys[i] = missingKey{}
+		}
+	}
+	r := make([]any, len(a))
+	index := make([]int, len(a))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		c := compareJSON(keys[index[i]], keys[index[j]])
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	for i, k := range index {
+		r[i] = a[k]
+	}
+	set(r)
+	return nil
+}
+
+// Check implements Extension.
+func (SortExtension) Check(_ *Patch, op Operation) error {
+	_, _, err := parseSort(op)
+	return err
+}
+
+func parseSort(op Operation) (by JSONPointer, desc bool, err error) {
+	if op.Value == nil {
+		return by, false, nil
+	}
+	m, ok := (*op.Value).(map[string]any)
+	if !ok {
+		return by, false, errors.New("value of sort must be an object")
+	}
+	if v, ok := m["by"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return by, false, errors.New("by of sort must be a string")
+		}
+		by = NewJSONPointer(s)
+		if err := by.Check(); err != nil {
+			return by, false, err
+		}
+	}
+	switch m["order"] {
+	case nil, "asc":
+	case "desc":
+		desc = true
+	default:
+		return by, false, errors.New(`order of sort must be "asc" or "desc"`)
+	}
+	return by, desc, nil
+}
+
+// missingKey is the sort key of an element without the member to sort by.
+type missingKey struct{}
+
+// compareJSON compares two json values, see SortExtension for the order.
+func compareJSON(a, b any) int {
+	ra, rb := jsonRank(a), jsonRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	case []any, map[string]any:
+		ca, _ := canonicalJSON(a)
+		cb, _ := canonicalJSON(b)
+		return strings.Compare(string(ca), string(cb))
+	}
+	if x, ok := toFloat(a); ok {
+		y, _ := toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func jsonRank(v any) int {
+	switch v.(type) {
+	case missingKey:
+		return 0
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case string:
+		return 4
+	case []any:
+		return 5
+	case map[string]any:
+		return 6
+	default:
+		return 3
+	}
+}
+
+// UniqueExtension is the "unique" operation removes the duplicate elements of the array at path,
+// the first one of the duplicates is kept, e.g. {"op":"unique","path":"/tags"}.
+// Elements are compared by deep equality, or by the member at the json pointer by of the value
+// relative to the elements, e.g. {"value":{"by":"/id"}}. Elements without the member are all kept.
+type UniqueExtension struct{}
+
+// OP implements Extension.
+func (UniqueExtension) OP() string {
+	return OpUnique
+}
+
+// Apply implements Extension.
+func (UniqueExtension) Apply(p *Patch, o *any, op Operation) error {
+	a, set, err := visitArray(p, o, op)
+	if err != nil {
+		return err
+	}
+	by, err := parseUnique(op)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(a))
+	r := make([]any, 0, len(a))
+	for _, e := range a {
+		key, err := by.Resolve(e)
+		if err != nil {
+			r = append(r, e)
+			continue
+		}
+		b, err := canonicalJSON(key)
+		if err != nil {
+			return err
+		}
+		if !seen[string(b)] {
+			seen[string(b)] = true
+			r = append(r, e)
+		}
+	}
+	set(r)
+	return nil
+}
+
+// Check implements Extension.
+func (UniqueExtension) Check(_ *Patch, op Operation) error {
+	_, err := parseUnique(op)
+	return err
+}
+
+func parseUnique(op Operation) (by JSONPointer, err error) {
+	if op.Value == nil {
+		return by, nil
+	}
+	m, ok := (*op.Value).(map[string]any)
+	if !ok {
+		return by, errors.New("value of unique must be an object")
+	}
+	if v, ok := m["by"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return by, errors.New("by of unique must be a string")
+		}
+		by = NewJSONPointer(s)
+	}
+	return by, by.Check()
+}
+
+// FlattenExtension is the "flatten" operation replaces the object at path by an object
+// maps the pointers of its leaves to their values, e.g. {"a":{"b":1}} becomes {"/a/b":1}.
+// Only objects are flattened, arrays and empty objects are leaves, so UnflattenExtension restores it.
+// The value is optional, if its separator member is set the tokens are joined by the separator
+// instead, e.g. {"op":"flatten","path":"/env","value":{"separator":"."}} makes {"a.b":1}.
+type FlattenExtension struct{}
+
+// OP implements Extension.
+func (FlattenExtension) OP() string {
+	return OpFlatten
+}
+
+// Apply implements Extension.
+func (FlattenExtension) Apply(p *Patch, o *any, op Operation) error {
+	return applyFlatten(p, o, op, func(m map[string]any, sep string) (any, error) {
+		r := map[string]any{}
+		flattenObject(m, nil, sep, r)
+		return r, nil
+	})
+}
+
+// Check implements Extension.
+func (FlattenExtension) Check(_ *Patch, op Operation) error {
+	_, err := flattenSeparator(op)
+	return err
+}
+
+// UnflattenExtension is the "unflatten" operation is the inverse of FlattenExtension,
+// e.g. {"/a/b":1} becomes {"a":{"b":1}}, the value is the same as the value of FlattenExtension.
+// It is an error if a key is a prefix of another key, like "/a" and "/a/b".
+type UnflattenExtension struct{}
+
+// OP implements Extension.
+func (UnflattenExtension) OP() string {
+	return OpUnflatten
+}
+
+// Apply implements Extension.
+func (UnflattenExtension) Apply(p *Patch, o *any, op Operation) error {
+	return applyFlatten(p, o, op, unflattenObject)
+}
+
+// Check implements Extension.
+func (UnflattenExtension) Check(_ *Patch, op Operation) error {
+	_, err := flattenSeparator(op)
+	return err
+}
+
+func applyFlatten(p *Patch, o *any, op Operation, fn func(m map[string]any, sep string) (any, error)) error {
+	v, set, err := visitOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return errBadType(*op.OP, v)
+	}
+	sep, err := flattenSeparator(op)
+	if err != nil {
+		return err
+	}
+	r, err := fn(m, sep)
+	if err != nil {
+		return err
+	}
+	if r, err = p.writeValue(*op.Path, r); err != nil {
+		return err
+	}
+	set(r)
+	return nil
+}
+
+func flattenSeparator(op Operation) (string, error) {
+	if op.Value == nil {
+		return "", nil
+	}
+	m, ok := (*op.Value).(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("value of %s must be an object", *op.OP)
+	}
+	v, ok := m["separator"]
+	if !ok {
+		return "", nil
+	}
+	sep, ok := v.(string)
+	if !ok || sep == "" {
+		return "", fmt.Errorf("separator of %s must be a non-empty string", *op.OP)
+	}
+	return sep, nil
+}
+
+func flattenKey(tokens []string, sep string) string {
+	if sep == "" {
+		return joinPointer(tokens)
+	}
+	return strings.Join(tokens, sep)
+}
+
+func flattenObject(m map[string]any, prefix []string, sep string, r map[string]any) {
+	for k, v := range m {
+		tokens := append(prefix[:len(prefix):len(prefix)], k)
+		if child, ok := v.(map[string]any); ok && len(child) > 0 {
+			flattenObject(child, tokens, sep, r)
+			continue
+		}
+		r[flattenKey(tokens, sep)] = v
+	}
+}
+
+func unflattenObject(m map[string]any, sep string) (any, error) {
+	var (
+		r = map[string]any{}
+		// created are the objects created for the tokens, the other objects are values.
+		created = map[string]bool{}
+	)
+	for _, k := range sortedKeys(m) {
+		var tokens []string
+		if sep == "" {
+			if err := NewJSONPointer(k).Check(); err != nil || k == "" {
+				return nil, fmt.Errorf("flattened key %q must be a json pointer", k)
+			}
+			tokens = NewJSONPointer(k).Path()
+		} else {
+			tokens = strings.Split(k, sep)
+		}
+		node := r
+		for i, token := range tokens[:len(tokens)-1] {
+			prefix := joinPointer(tokens[:i+1])
+			child, exists := node[token]
+			switch {
+			case !exists:
+				next := map[string]any{}
+				node[token] = next
+				created[prefix] = true
+				node = next
+			case created[prefix]:
+				node = child.(map[string]any)
+			default:
+				return nil, fmt.Errorf("flattened key %q conflicts with other keys", k)
+			}
+		}
+		last := tokens[len(tokens)-1]
+		if _, exists := node[last]; exists {
+			return nil, fmt.Errorf("flattened key %q conflicts with other keys", k)
+		}
+		node[last] = m[k]
+	}
+	return r, nil
+}
+
+// ConcatExtension is the "concat" operation concatenates the value onto the string or array at path,
+// e.g. {"op":"concat","path":"/log","value":" done"} or {"op":"concat","path":"/tags","value":["a"]}.
+// The value must be the same type of the node.
+type ConcatExtension struct{}
+
+// OP implements Extension.
+func (ConcatExtension) OP() string {
+	return OpConcat
+}
+
+// Apply implements Extension.
+func (ConcatExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, set, err := visitOperand(p, o, op)
+	if err != nil {
+		return err
+	}
+	var r any
+	switch x := v.(type) {
+	case string:
+		s, ok := (*op.Value).(string)
+		if !ok {
+			return errors.New("value of concat must be a string to concatenate a string")
+		}
+		if r, err = p.writeValue(*op.Path, x+s); err != nil {
+			return err
+		}
+	case []any:
+		a, ok := (*op.Value).([]any)
+		if !ok {
+			return errors.New("value of concat must be an array to concatenate an array")
+		}
+		values, err := p.writeElements(*op.Path, len(x), a)
+		if err != nil {
+			return err
+		}
+		r = append(x, values...)
+	default:
+		return errBadType(OpConcat, v)
+	}
+	set(r)
+	return nil
+}
+
+// Check implements Extension.
+func (ConcatExtension) Check(_ *Patch, op Operation) error {
+	if op.Value == nil {
+		return errMissingMember(OpConcat, "value")
+	}
+	switch (*op.Value).(type) {
+	case string, []any:
+		return nil
+	default:
+		return errors.New("value of concat must be a string or an array")
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+// extensionCase is a case of an extension, an empty expect means an error.
+type extensionCase struct {
+	doc    string
+	ops    string
+	expect string
+}
+
+func testExtensionCases(t *testing.T, ext Extension, cases []extensionCase) {
+	t.Helper()
+	p := New(WithExtension(ext))
+	for _, c := range cases {
+		b, err := p.Apply([]byte(c.doc), mustOperations(t, c.ops))
+		if c.expect == "" {
+			if err == nil {
+				t.Fatal(c.ops, "expected error, got", string(b))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if ok, err := Equal(b, []byte(c.expect)); err != nil || !ok {
+			t.Fatal(c.ops, "unexpected document", string(b))
+		}
+	}
+}
+
+func TestIncExtension(t *testing.T) {
+	testExtensionCases(t, IncExtension{}, []extensionCase{
+		{`{"counter":1}`, `[{"op":"inc","path":"/counter","value":5}]`, `{"counter":6}`},
+		{`{"counter":1}`, `[{"op":"inc","path":"/counter","value":-1.5}]`, `{"counter":-0.5}`},
+		{`[1,2]`, `[{"op":"inc","path":"/1","value":1},{"op":"inc","path":"/1","value":1}]`, `[1,4]`},
+		{`3`, `[{"op":"inc","path":"","value":1}]`, `4`},
+		{`{"counter":"1"}`, `[{"op":"inc","path":"/counter","value":1}]`, ``},
+		{`{}`, `[{"op":"inc","path":"/counter","value":1}]`, ``},
+		{`{"counter":1}`, `[{"op":"inc","path":"/counter","value":"1"}]`, ``},
+		{`{"counter":1}`, `[{"op":"inc","path":"/counter"}]`, ``},
+	})
+}
+
+func TestStrReplaceExtension(t *testing.T) {
+	testExtensionCases(t, StrReplaceExtension{}, []extensionCase{
+		{`{"msg":"foo foo"}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":"foo","repl":"bar"}}]`, `{"msg":"bar bar"}`},
+		{`{"msg":"a.b"}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":".","repl":"/"}}]`, `{"msg":"a/b"}`},
+		{`{"msg":"a1b22"}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":"([0-9]+)","repl":"<$1>","regexp":true}}]`,
+			`{"msg":"a<1>b<22>"}`},
+		{`["x"]`, `[{"op":"str-replace","path":"/0","value":{"pattern":"x","repl":""}}]`, `[""]`},
+		{`{"msg":1}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":"1","repl":"2"}}]`, ``},
+		{`{"msg":"a"}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":"(","repl":"","regexp":true}}]`, ``},
+		{`{"msg":"a"}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":"","repl":"b"}}]`, ``},
+		{`{"msg":"a"}`, `[{"op":"str-replace","path":"/msg","value":"a"}]`, ``},
+		{`{}`, `[{"op":"str-replace","path":"/msg","value":{"pattern":"a","repl":"b"}}]`, ``},
+	})
+}
+
+func TestAppendExtension(t *testing.T) {
+	testExtensionCases(t, AppendExtension{}, []extensionCase{
+		{`{"tags":["a"]}`, `[{"op":"append","path":"/tags","value":["b","c"]}]`, `{"tags":["a","b","c"]}`},
+		{`{"tags":[]}`, `[{"op":"append","path":"/tags","value":[[1,2]]}]`, `{"tags":[[1,2]]}`},
+		{`[1]`, `[{"op":"append","path":"","value":[]}]`, `[1]`},
+		{`{"tags":{}}`, `[{"op":"append","path":"/tags","value":["a"]}]`, ``},
+		{`{"tags":[]}`, `[{"op":"append","path":"/tags","value":"a"}]`, ``},
+		{`{}`, `[{"op":"append","path":"/tags","value":["a"]}]`, ``},
+	})
+}
+
+func TestPrependExtension(t *testing.T) {
+	testExtensionCases(t, PrependExtension{}, []extensionCase{
+		{`{"recent":["a"]}`, `[{"op":"prepend","path":"/recent","value":["b","c"]}]`, `{"recent":["b","c","a"]}`},
+		{`{"recent":["a"]}`, `[{"op":"prepend","path":"/recent","value":["b"]},{"op":"prepend","path":"/recent","value":["c"]}]`,
+			`{"recent":["c","b","a"]}`},
+		{`[]`, `[{"op":"prepend","path":"","value":[{"a":1}]}]`, `[{"a":1}]`},
+		{`{"recent":"a"}`, `[{"op":"prepend","path":"/recent","value":["a"]}]`, ``},
+		{`{"recent":[]}`, `[{"op":"prepend","path":"/recent"}]`, ``},
+	})
+}
+
+func TestSpliceExtension(t *testing.T) {
+	doc := `{"items":[0,1,2,3,4]}`
+	testExtensionCases(t, SpliceExtension{}, []extensionCase{
+		{doc, `[{"op":"splice","path":"/items","value":{"start":1,"deleteCount":2,"values":["x"]}}]`, `{"items":[0,"x",3,4]}`},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":1,"deleteCount":0,"values":["x","y"]}}]`,
+			`{"items":[0,"x","y",1,2,3,4]}`},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":-2}}]`, `{"items":[0,1,2]}`},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":3,"deleteCount":10}}]`, `{"items":[0,1,2]}`},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":10,"values":[5]}}]`, `{"items":[0,1,2,3,4,5]}`},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":-10,"deleteCount":1}}]`, `{"items":[1,2,3,4]}`},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":1.5}}]`, ``},
+		{doc, `[{"op":"splice","path":"/items","value":{"start":0,"values":1}}]`, ``},
+		{doc, `[{"op":"splice","path":"/items","value":{"deleteCount":1}}]`, ``},
+		{`{"items":{}}`, `[{"op":"splice","path":"/items","value":{"start":0}}]`, ``},
+	})
+}
+
+func TestMergeExtension(t *testing.T) {
+	doc := `{"config":{"debug":true,"log":{"level":"debug","file":"a"}},"name":"x"}`
+	testExtensionCases(t, MergeExtension{}, []extensionCase{
+		{doc, `[{"op":"merge","path":"/config","value":{"debug":null,"log":{"level":"info"},"port":80}}]`,
+			`{"config":{"log":{"level":"info","file":"a"},"port":80},"name":"x"}`},
+		{doc, `[{"op":"merge","path":"/config/log","value":"off"}]`, `{"config":{"debug":true,"log":"off"},"name":"x"}`},
+		{doc, `[{"op":"merge","path":"/name","value":{"first":"y"}}]`,
+			`{"config":{"debug":true,"log":{"level":"debug","file":"a"}},"name":{"first":"y"}}`},
+		{doc, `[{"op":"merge","path":"","value":{"config":null}}]`, `{"name":"x"}`},
+		{doc, `[{"op":"merge","path":"/missing","value":{}}]`, ``},
+		{doc, `[{"op":"merge","path":"/config"}]`, ``},
+	})
+}
+
+func TestDefaultExtension(t *testing.T) {
+	doc := `{"config":{"port":8080,"debug":null},"list":[1]}`
+	testExtensionCases(t, DefaultExtension{}, []extensionCase{
+		{doc, `[{"op":"default","path":"/config/port","value":80},{"op":"default","path":"/config/host","value":"a"}]`,
+			`{"config":{"port":8080,"debug":null,"host":"a"},"list":[1]}`},
+		{doc, `[{"op":"default","path":"/config/debug","value":true}]`, doc},
+		{doc, `[{"op":"default","path":"/list/0","value":2},{"op":"default","path":"/list/1","value":3}]`,
+			`{"config":{"port":8080,"debug":null},"list":[1,3]}`},
+		{doc, `[{"op":"default","path":"/list/-","value":2}]`, `{"config":{"port":8080,"debug":null},"list":[1,2]}`},
+		{doc, `[{"op":"default","path":"","value":2}]`, doc},
+		{doc, `[{"op":"default","path":"/missing/port","value":80}]`, ``},
+		{doc, `[{"op":"default","path":"/list/5","value":80}]`, ``},
+	})
+}
+
+func TestRenameExtension(t *testing.T) {
+	doc := `{"user":{"name":"a","age":1},"list":[1]}`
+	testExtensionCases(t, RenameExtension{}, []extensionCase{
+		{doc, `[{"op":"rename","path":"/user/name","value":"fullName"}]`, `{"user":{"fullName":"a","age":1},"list":[1]}`},
+		{doc, `[{"op":"rename","path":"/user","value":"a/b"}]`, `{"a/b":{"name":"a","age":1},"list":[1]}`},
+		{doc, `[{"op":"rename","path":"/user/name","value":"name"}]`, doc},
+		{doc, `[{"op":"rename","path":"/user/name","value":"age"}]`, ``},
+		{doc, `[{"op":"rename","path":"/user/missing","value":"x"}]`, ``},
+		{doc, `[{"op":"rename","path":"/list/0","value":"x"}]`, ``},
+		{doc, `[{"op":"rename","path":"/user/name","value":1}]`, ``},
+		{doc, `[{"op":"rename","path":"","value":"x"}]`, ``},
+	})
+}
+
+func TestRenameExtensionWrites(t *testing.T) {
+	o := NewOwnership()
+	for _, c := range [][2]string{{"alice", "/status/alice"}, {"bob", "/status/bob"}} {
+		if err := o.Claim(c[0], c[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	doc := []byte(`{"status":{"alice":1}}`)
+	ops := mustOperations(t, `[{"op":"rename","path":"/status/alice","value":"bob"}]`)
+	p := New(WithExtension(RenameExtension{}), WithOwnership(o, "alice"))
+	if _, err := p.Apply(doc, ops); !errors.Is(err, ErrNotOwner) {
+		t.Fatal("expected not owner, got", err)
+	}
+	p = New(WithExtension(RenameExtension{}), WithPolicy(&Policy{Deny: []string{"/status/bob"}}))
+	if _, err := p.Apply(doc, ops); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatal("expected policy violation, got", err)
+	}
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"rename","path":"/status/alice","value":"carol"}]`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSortExtension(t *testing.T) {
+	users := `{"users":[{"name":"b","age":2},{"name":"a","age":3},{"age":1},{"name":"c","age":2}]}`
+	testExtensionCases(t, SortExtension{}, []extensionCase{
+		{`[3,1,2]`, `[{"op":"sort","path":""}]`, `[1,2,3]`},
+		{`[3,1,2]`, `[{"op":"sort","path":"","value":{"order":"desc"}}]`, `[3,2,1]`},
+		{`["b",null,2,true,"a",[1],{"a":1},false,10]`, `[{"op":"sort","path":""}]`, `[null,false,true,2,10,"a","b",[1],{"a":1}]`},
+		{users, `[{"op":"sort","path":"/users","value":{"by":"/name"}}]`,
+			`{"users":[{"age":1},{"name":"a","age":3},{"name":"b","age":2},{"name":"c","age":2}]}`},
+		{users, `[{"op":"sort","path":"/users","value":{"by":"/age","order":"desc"}}]`,
+			`{"users":[{"name":"a","age":3},{"name":"b","age":2},{"name":"c","age":2},{"age":1}]}`},
+		{users, `[{"op":"sort","path":"/users","value":{"by":"name"}}]`, ``},
+		{users, `[{"op":"sort","path":"/users","value":{"order":"up"}}]`, ``},
+		{users, `[{"op":"sort","path":"/users/0"}]`, ``},
+	})
+}
+
+func TestUniqueExtension(t *testing.T) {
+	testExtensionCases(t, UniqueExtension{}, []extensionCase{
+		{`{"tags":["a","b","a",1,1.0,{"x":[1]},{"x":[1]},null,null]}`, `[{"op":"unique","path":"/tags"}]`,
+			`{"tags":["a","b",1,{"x":[1]},null]}`},
+		{`[{"id":1,"v":"a"},{"id":2},{"id":1,"v":"b"},{"v":"c"},{"v":"d"}]`, `[{"op":"unique","path":"","value":{"by":"/id"}}]`,
+			`[{"id":1,"v":"a"},{"id":2},{"v":"c"},{"v":"d"}]`},
+		{`[]`, `[{"op":"unique","path":""}]`, `[]`},
+		{`[1]`, `[{"op":"unique","path":"","value":{"by":"id"}}]`, ``},
+		{`[1]`, `[{"op":"unique","path":"","value":"id"}]`, ``},
+		{`{"tags":"a"}`, `[{"op":"unique","path":"/tags"}]`, ``},
+	})
+}
+
+func TestFlattenExtension(t *testing.T) {
+	doc := `{"env":{"a":{"b":1,"c":{"d":[1,{"e":2}]},"f":{}},"g/h":null}}`
+	flat := `{"env":{"/a/b":1,"/a/c/d":[1,{"e":2}],"/a/f":{},"/g~1h":null}}`
+	dotted := `{"env":{"a.b":1,"a.c.d":[1,{"e":2}],"a.f":{},"g/h":null}}`
+	testExtensionCases(t, FlattenExtension{}, []extensionCase{
+		{doc, `[{"op":"flatten","path":"/env"}]`, flat},
+		{doc, `[{"op":"flatten","path":"/env","value":{"separator":"."}}]`, dotted},
+		{`{"a":{}}`, `[{"op":"flatten","path":""}]`, `{"/a":{}}`},
+		{`{"a":[]}`, `[{"op":"flatten","path":"/a"}]`, ``},
+		{doc, `[{"op":"flatten","path":"/env","value":{"separator":""}}]`, ``},
+	})
+	testExtensionCases(t, UnflattenExtension{}, []extensionCase{
+		{flat, `[{"op":"unflatten","path":"/env"}]`, doc},
+		{dotted, `[{"op":"unflatten","path":"/env","value":{"separator":"."}}]`, doc},
+		{`{"/a":1,"/a/b":2}`, `[{"op":"unflatten","path":""}]`, ``},
+		{`{"/a/b":1,"/a":{}}`, `[{"op":"unflatten","path":""}]`, ``},
+		{`{"a":1}`, `[{"op":"unflatten","path":""}]`, ``},
+	})
+}
+
+func TestConcatExtension(t *testing.T) {
+	doc := `{"log":"start","tags":["a"]}`
+	testExtensionCases(t, ConcatExtension{}, []extensionCase{
+		{doc, `[{"op":"concat","path":"/log","value":" done"}]`, `{"log":"start done","tags":["a"]}`},
+		{doc, `[{"op":"concat","path":"/tags","value":["b",["c"]]}]`, `{"log":"start","tags":["a","b",["c"]]}`},
+		{doc, `[{"op":"concat","path":"/tags/0","value":"b"}]`, `{"log":"start","tags":["ab"]}`},
+		{doc, `[{"op":"concat","path":"/log","value":["a"]}]`, ``},
+		{doc, `[{"op":"concat","path":"/tags","value":"b"}]`, ``},
+		{doc, `[{"op":"concat","path":"","value":"b"}]`, ``},
+		{doc, `[{"op":"concat","path":"/log","value":1}]`, ``},
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrFanoutExceeded is returned if an operation matches more locations than the MaxFanout option.
+var ErrFanoutExceeded = errors.New("fanout exceeded")
+
+// FanoutError is returned if an operation matches more locations than the MaxFanout option.
+type FanoutError struct {
+	Path    string
+	Limit   int
+	Matches int
+}
+
+// Error implements error.
+func (e *FanoutError) Error() string {
+	return fmt.Sprintf("fanout exceeded: %s matches %d locations, limit %d", e.Path, e.Matches, e.Limit)
+}
+
+// Is returns true if target is ErrFanoutExceeded.
+func (e *FanoutError) Is(target error) bool {
+	return target == ErrFanoutExceeded
+}
+
+// WithMaxFanout set the MaxFanout option.
+// The default value is 0, which means no limit.
+// A single operation whose path matches multiple locations, e.g. a path with wildcards,
+// fails with a *FanoutError if it matches more than n locations.
+func WithMaxFanout(n int) Option {
+	return func(o *Patch) {
+		o.MaxFanout = n
+	}
+}
+
+// CheckFanout returns a *FanoutError if matches exceeds the MaxFanout option.
+// Extensions touching multiple locations should call it before writing any of them.
+func (p *Patch) CheckFanout(path string, matches int) error {
+	if p.MaxFanout > 0 && matches > p.MaxFanout {
+		return &FanoutError{Path: path, Limit: p.MaxFanout, Matches: matches}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+// clearExtension sets all members of the object at path to null.
+type clearExtension struct{}
+
+func (clearExtension) OP() string { return "clear" }
+
+func (clearExtension) Apply(p *Patch, o *any, op Operation) error {
+	v, _, err := p.VisitPath(o, NewJSONPointer(*op.Path).Path()...)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return errBadType("clear", v)
+	}
+	if err := p.CheckFanout(*op.Path, len(m)); err != nil {
+		return err
+	}
+	for k := range m {
+		m[k] = nil
+	}
+	return nil
+}
+
+func (clearExtension) Check(*Patch, Operation) error { return nil }
+
+func TestMaxFanout(t *testing.T) {
+	doc := []byte(`{"a":{"b":1,"c":2,"d":3}}`)
+	ops := mustOperations(t, `[{"op":"clear","path":"/a"}]`)
+	b, err := New(WithExtension(clearExtension{}), WithMaxFanout(3)).Apply(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"a":{"b":null,"c":null,"d":null}}`+"\n" {
+		t.Fatal("unexpected document", string(b))
+	}
+	_, err = New(WithExtension(clearExtension{}), WithMaxFanout(2)).Apply(doc, ops)
+	var fe *FanoutError
+	if !errors.Is(err, ErrFanoutExceeded) || !errors.As(err, &fe) || fe.Matches != 3 || fe.Limit != 2 || fe.Path != "/a" {
+		t.Fatal("expected fanout error, got", err)
+	}
+}
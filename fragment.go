@@ -0,0 +1,84 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"net/url"
+	"strings"
+)
+
+// WithURIFragment set the URIFragment option.
+// The default value is false.
+// If URIFragment is true, path and from of operations may be in the URI fragment form
+// of RFC6901 section 6, e.g. "#/foo/a%20b", as used by "$ref" of JSON Schema.
+func WithURIFragment(on bool) Option {
+	return func(o *Patch) {
+		o.URIFragment = on
+	}
+}
+
+// ParseURIFragment parses the URI fragment form of a JSONPointer, e.g. "#/foo/a%20b".
+func ParseURIFragment(s string) (JSONPointer, error) {
+	if !strings.HasPrefix(s, "#") {
+		return JSONPointer{}, newError(CodeBadPointer, map[string]any{"pointer": s}, nil)
+	}
+	ptr, err := url.PathUnescape(s[1:])
+	if err != nil {
+		return JSONPointer{}, newError(CodeBadPointer, map[string]any{"pointer": s}, err)
+	}
+	p := NewJSONPointer(ptr)
+	return p, p.Check()
+}
+
+// URIFragment returns the URI fragment form of the JSONPointer.
+func (p JSONPointer) URIFragment() string {
+	var b strings.Builder
+	b.WriteByte('#')
+	for _, token := range p.Path() {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(EscapeToken(token)))
+	}
+	return b.String()
+}
+
+// fromURIFragments returns ops whose pointers in the URI fragment form are converted
+// if the URIFragment option is true.
+func (p *Patch) fromURIFragments(ops []Operation) ([]Operation, error) {
+	if !p.URIFragment {
+		return ops, nil
+	}
+	var r []Operation
+	for i, op := range ops {
+		converted, err := fromURIFragment(op)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil && (converted.Path != op.Path || converted.From != op.From) {
+			r = append(make([]Operation, 0, len(ops)), ops[:i]...)
+		}
+		if r != nil {
+			r = append(r, converted)
+		}
+	}
+	if r == nil {
+		return ops, nil
+	}
+	return r, nil
+}
+
+func fromURIFragment(op Operation) (Operation, error) {
+	for _, ptr := range []**string{&op.Path, &op.From} {
+		if *ptr == nil || !strings.HasPrefix(**ptr, "#") {
+			continue
+		}
+		p, err := ParseURIFragment(**ptr)
+		if err != nil {
+			return op, err
+		}
+		s := p.String()
+		*ptr = &s
+	}
+	return op, nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import "testing"
+
+func TestURIFragment(t *testing.T) {
+	cases := []struct {
+		fragment string
+		pointer  string
+	}{
+		{"#", ""},
+		{"#/foo", "/foo"},
+		{"#/foo/0", "/foo/0"},
+		{"#/a~1b", "/a~1b"},
+		{"#/c%25d", "/c%d"},
+		{"#/k%22l", `/k"l`},
+		{"#/%20", "/ "},
+		{"#/m~0n", "/m~0n"},
+	}
+	for _, c := range cases {
+		p, err := ParseURIFragment(c.fragment)
+		if err != nil {
+			t.Fatal(c.fragment, err)
+		}
+		if p.String() != c.pointer {
+			t.Fatal(c.fragment, "expected", c.pointer, "got", p.String())
+		}
+		if got := p.URIFragment(); got != c.fragment {
+			t.Fatal(c.pointer, "expected", c.fragment, "got", got)
+		}
+	}
+	for _, s := range []string{"/foo", "#foo", "#/%zz"} {
+		if _, err := ParseURIFragment(s); err == nil {
+			t.Fatal(s, "expected error")
+		}
+	}
+
+	doc := []byte(`{"definitions":{"a b":1}}`)
+	ops := mustOperations(t, `[{"op":"copy","from":"#/definitions/a%20b","path":"#/c"}]`)
+	if _, err := New().Apply(doc, ops); err == nil {
+		t.Fatal("expected error without the option")
+	}
+	for _, p := range []*Patch{New(WithURIFragment(true)), New(WithURIFragment(true), WithRawEngine(true))} {
+		got, err := p.Apply(doc, ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := Equal(got, []byte(`{"c":1,"definitions":{"a b":1}}`)); err != nil || !ok {
+			t.Fatal("bad output", string(got), err)
+		}
+	}
+	if *ops[0].Path != "#/c" {
+		t.Fatal("operations must not be changed")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import "encoding/json"
+
+// GuardTests returns the test operations of the current values at paths of the json document b.
+// Prepend them to a patch, so the patch fails if any of the values is changed
+// after the patch was created.
+func GuardTests(b []byte, paths ...string) ([]Operation, error) {
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return GuardTestsAny(doc, paths...)
+}
+
+// GuardTestsAny is like GuardTests but the document is a value decoded by encoding/json.
+// It returns ErrNotExists if any of paths not exists.
+func GuardTestsAny(doc any, paths ...string) ([]Operation, error) {
+	ops := make([]Operation, 0, len(paths))
+	for _, path := range paths {
+		v, err := NewJSONPointer(path).Resolve(doc)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, newOperation(opTest, path, deepCopy(v), nil))
+	}
+	return ops, nil
+}
+
+// Guard returns ops prepended with the GuardTests of paths.
+func Guard(b []byte, ops []Operation, paths ...string) ([]Operation, error) {
+	guards, err := GuardTests(b, paths...)
+	if err != nil {
+		return nil, err
+	}
+	return append(guards, ops...), nil
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	doc := []byte(`{"version":3,"spec":{"a":[1,2]}}`)
+	ops, err := Guard(doc, mustOperations(t, `[{"op":"replace","path":"/spec/a/0","value":0}]`), "/version", "/spec/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[{"op":"test","path":"/version","value":3},{"op":"test","path":"/spec/a","value":[1,2]},{"op":"replace","path":"/spec/a/0","value":0}]`
+	if got := strings.TrimSpace(jsonstring(ops)); got != expect {
+		t.Fatal("expected", expect, "got", got)
+	}
+	got, err := New().Apply(doc, ops)
+	if err != nil || strings.TrimSpace(string(got)) != `{"spec":{"a":[0,2]},"version":3}` {
+		t.Fatal("bad apply", string(got), err)
+	}
+	if _, err := New().Apply([]byte(`{"version":4,"spec":{"a":[1,2]}}`), ops); err == nil {
+		t.Fatal("expected error of changed document")
+	}
+	if _, err := GuardTests(doc, "/missing"); !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected not exists, got", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package httppatch provides helpers to exchange json patches over HTTP.
+package httppatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+const (
+	// MediaTypeJSONPatch is the media type of json patch introduced in RFC6902.
+	MediaTypeJSONPatch = "application/json-patch+json"
+	// MediaTypeMergePatch is the media type of json merge patch introduced in RFC7386.
+	MediaTypeMergePatch = "application/merge-patch+json"
+)
+
+const defaultMaxAttempts = 3
+
+// StatusError is returned if the server responds with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// Client sends json patches with HTTP PATCH requests.
+type Client struct {
+	// HTTPClient is the client to send requests, http.DefaultClient is used if it is nil.
+	HTTPClient *http.Client
+	// MaxAttempts is the max number of PATCH requests to send, 3 is used if it is 0.
+	MaxAttempts int
+	// Backoff returns the delay before the attempt, attempt starts from 1.
+	// An exponential backoff starting from 100ms is used if it is nil.
+	Backoff func(attempt int) time.Duration
+	// Rebase rebuilds the patch against the latest document after a conflict.
+	// The patch is retried unchanged if it is nil.
+	Rebase func(doc []byte, ops []jsonpatch.Operation) ([]jsonpatch.Operation, error)
+}
+
+// Patch sends ops to url with a PATCH request and returns the successful response.
+// If etag is not empty, it is sent as If-Match precondition.
+// If the server responds 409 Conflict or 412 Precondition Failed, the document is fetched
+// again to refresh the ETag, the patch is rebased and retried with backoff.
+// The caller should close the body of the returned response.
+func (c *Client) Patch(ctx context.Context, url, etag string, ops []jsonpatch.Operation) (*http.Response, error) {
+	attempts := c.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			if err := sleep(ctx, c.backoff(attempt-1)); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.send(ctx, url, etag, ops)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		lastErr = statusError(resp)
+		if resp.StatusCode != http.StatusConflict && resp.StatusCode != http.StatusPreconditionFailed {
+			return nil, lastErr
+		}
+		if attempt == attempts {
+			break
+		}
+		if etag, ops, err = c.refresh(ctx, url, ops); err != nil {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) send(ctx context.Context, url, etag string, ops []jsonpatch.Operation) (*http.Response, error) {
+	b, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", MediaTypeJSONPatch)
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	return c.client().Do(req)
+}
+
+// refresh fetches the latest document and rebases ops.
+func (c *Client) refresh(ctx context.Context, url string, ops []jsonpatch.Operation) (string, []jsonpatch.Operation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, statusError(resp)
+	}
+	defer resp.Body.Close()
+	doc, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	if c.Rebase != nil {
+		if ops, err = c.Rebase(doc, ops); err != nil {
+			return "", nil, err
+		}
+	}
+	return resp.Header.Get("ETag"), ops, nil
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.Backoff != nil {
+		return c.Backoff(attempt)
+	}
+	return 100 * time.Millisecond << (attempt - 1)
+}
+
+func statusError(resp *http.Response) error {
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &StatusError{StatusCode: resp.StatusCode, Body: b}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
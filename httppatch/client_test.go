@@ -0,0 +1,93 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package httppatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// server is a document server using a version number as ETag.
+type server struct {
+	mu      sync.Mutex
+	doc     any
+	version int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	etag := strconv.Itoa(s.version)
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("ETag", etag)
+		_ = json.NewEncoder(w).Encode(s.doc)
+	case http.MethodPatch:
+		if r.Header.Get("Content-Type") != MediaTypeJSONPatch {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && m != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var ops []jsonpatch.Operation
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &ops); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := jsonpatch.New().ApplyAny(&s.doc, ops); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		s.version++
+		w.Header().Set("ETag", strconv.Itoa(s.version))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestClientPatch(t *testing.T) {
+	s := &server{doc: map[string]any{"n": 1.0}, version: 5}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var ops []jsonpatch.Operation
+	if err := json.Unmarshal([]byte(`[{"op":"replace","path":"/n","value":2}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	rebased := 0
+	c := &Client{
+		Backoff: func(int) time.Duration { return 0 },
+		Rebase: func(_ []byte, ops []jsonpatch.Operation) ([]jsonpatch.Operation, error) {
+			rebased++
+			return ops, nil
+		},
+	}
+	resp, err := c.Patch(context.Background(), ts.URL, "1", ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if rebased != 1 || resp.Header.Get("ETag") != "6" {
+		t.Fatal("bad retry", rebased, resp.Header.Get("ETag"))
+	}
+
+	c.MaxAttempts = 1
+	_, err = c.Patch(context.Background(), ts.URL, "1", ops)
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusPreconditionFailed {
+		t.Fatal("expected precondition failed, got", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package httppatch
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// WritePatch writes ops as the response body with the json patch media type.
+func WritePatch(w http.ResponseWriter, ops []jsonpatch.Operation) error {
+	return writeJSON(w, MediaTypeJSONPatch, ops)
+}
+
+// WriteMergePatch writes patch as the response body with the json merge patch media type.
+func WriteMergePatch(w http.ResponseWriter, patch any) error {
+	return writeJSON(w, MediaTypeMergePatch, patch)
+}
+
+func writeJSON(w http.ResponseWriter, mediaType string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(b)
+	return err
+}
+
+// Negotiate picks MediaTypeJSONPatch or MediaTypeMergePatch by the Accept header of r.
+// The json patch is preferred if both are equally acceptable or there is no Accept header.
+// It returns an empty string if neither is acceptable.
+func Negotiate(r *http.Request) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return MediaTypeJSONPatch
+	}
+	patch := acceptQuality(accept, MediaTypeJSONPatch)
+	merge := acceptQuality(accept, MediaTypeMergePatch)
+	switch {
+	case patch <= 0 && merge <= 0:
+		return ""
+	case merge > patch:
+		return MediaTypeMergePatch
+	default:
+		return MediaTypeJSONPatch
+	}
+}
+
+// acceptQuality returns the quality of mediaType in the Accept header values,
+// the most specific matched media range wins.
+func acceptQuality(accept []string, mediaType string) float64 {
+	var (
+		quality     float64
+		specificity = -1
+	)
+	for _, value := range accept {
+		for _, r := range strings.Split(value, ",") {
+			params := strings.Split(r, ";")
+			rng := strings.ToLower(strings.TrimSpace(params[0]))
+			s := matchMediaRange(rng, mediaType)
+			if s <= specificity {
+				continue
+			}
+			specificity = s
+			quality = 1
+			for _, p := range params[1:] {
+				k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+				if ok && strings.TrimSpace(k) == "q" {
+					if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+						quality = q
+					}
+				}
+			}
+		}
+	}
+	return quality
+}
+
+// matchMediaRange returns the specificity of the media range matches mediaType, or -1 if not matched.
+func matchMediaRange(rng, mediaType string) int {
+	switch {
+	case rng == mediaType:
+		return 2
+	case rng == "*/*":
+		return 0
+	case strings.HasSuffix(rng, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rng, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package httppatch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func TestWritePatch(t *testing.T) {
+	var ops []jsonpatch.Operation
+	if err := json.Unmarshal([]byte(`[{"op":"remove","path":"/a"}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if err := WritePatch(w, ops); err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Content-Type") != MediaTypeJSONPatch || w.Body.String() != `[{"op":"remove","path":"/a"}]` {
+		t.Fatal("bad response", w.Header(), w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	if err := WriteMergePatch(w, map[string]any{"a": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Content-Type") != MediaTypeMergePatch || w.Body.String() != `{"a":null}` {
+		t.Fatal("bad response", w.Header(), w.Body.String())
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept []string
+		expect string
+	}{
+		{nil, MediaTypeJSONPatch},
+		{[]string{"*/*"}, MediaTypeJSONPatch},
+		{[]string{MediaTypeMergePatch}, MediaTypeMergePatch},
+		{[]string{MediaTypeJSONPatch + ";q=0.5, " + MediaTypeMergePatch}, MediaTypeMergePatch},
+		{[]string{"application/*;q=0.2", MediaTypeJSONPatch + ";q=0"}, MediaTypeMergePatch},
+		{[]string{"text/html"}, ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, a := range c.accept {
+			r.Header.Add("Accept", a)
+		}
+		if got := Negotiate(r); got != c.expect {
+			t.Fatal(c.accept, "expected", c.expect, "got", got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"context"
+	"sync"
+)
+
+// Ledger records the patches applied to documents.
+type Ledger interface {
+	// Has returns true if the patch key is recorded for the document.
+	Has(ctx context.Context, document, key string) (bool, error)
+	// Add records the patch key for the document.
+	Add(ctx context.Context, document, key string) error
+}
+
+// Key returns the idempotency key of the bundle.
+// It is the IdempotencyKey if it's not empty, otherwise the canonical hash of the operations,
+// so semantically identical patches have the same key.
+func (b *PatchBundle) Key() (string, error) {
+	if b.IdempotencyKey != "" {
+		return b.IdempotencyKey, nil
+	}
+	h, err := canonicalHash(b.Operations)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + h, nil
+}
+
+// Idempotent applies every patch bundle at most once per document.
+// Writes to the same document must be serialized by the caller.
+type Idempotent struct {
+	// Patch applies the operations. New() is used if it's nil.
+	Patch  *Patch
+	Ledger Ledger
+}
+
+// Apply applies the bundle to doc, the json document identified by document,
+// unless the key of the bundle is recorded for the document.
+// It returns doc as is and false if the bundle is a duplicate.
+// The key is recorded only if the bundle is applied, and the operations are routed once it's recorded.
+func (i *Idempotent) Apply(ctx context.Context, document string, doc []byte, b *PatchBundle) ([]byte, bool, error) {
+	key, err := b.Key()
+	if err != nil {
+		return nil, false, err
+	}
+	seen, err := i.Ledger.Has(ctx, document, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if seen {
+		return doc, false, nil
+	}
+	p := i.Patch
+	if p == nil {
+		p = New()
+	}
+	out, err := p.applyJSON(doc, b.Operations)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := i.Ledger.Add(ctx, document, key); err != nil {
+		return nil, false, err
+	}
+	p.route(b.Operations)
+	return out, true, nil
+}
+
+// MemoryLedger is a Ledger in memory.
+type MemoryLedger struct {
+	mu   sync.Mutex
+	keys map[string]map[string]struct{}
+}
+
+var _ Ledger = (*MemoryLedger)(nil)
+
+// NewMemoryLedger creates an empty MemoryLedger.
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{keys: map[string]map[string]struct{}{}}
+}
+
+// Has implements Ledger.
+func (l *MemoryLedger) Has(_ context.Context, document, key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.keys[document][key]
+	return ok, nil
+}
+
+// Add implements Ledger.
+func (l *MemoryLedger) Add(_ context.Context, document, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.keys[document]
+	if !ok {
+		m = map[string]struct{}{}
+		l.keys[document] = m
+	}
+	m[key] = struct{}{}
+	return nil
+}
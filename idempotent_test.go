@@ -0,0 +1,49 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIdempotent(t *testing.T) {
+	ctx := context.Background()
+	i := &Idempotent{Ledger: NewMemoryLedger()}
+	b := &PatchBundle{ID: "1", IdempotencyKey: "k1", Operations: mustOperations(t, `[{"op":"add","path":"/n/-","value":1}]`)}
+	doc := []byte(`{"n":[]}`)
+	doc, applied, err := i.Apply(ctx, "d1", doc, b)
+	if err != nil || !applied {
+		t.Fatal("expected applied", applied, err)
+	}
+	doc, applied, err = i.Apply(ctx, "d1", doc, b)
+	if err != nil || applied {
+		t.Fatal("expected duplicate", applied, err)
+	}
+	if string(doc) != `{"n":[1]}`+"\n" {
+		t.Fatal("unexpected document", string(doc))
+	}
+	if _, applied, _ := i.Apply(ctx, "d2", []byte(`{"n":[]}`), b); !applied {
+		t.Fatal("expected applied to another document")
+	}
+
+	// semantically identical patches without keys.
+	b1 := &PatchBundle{Operations: mustOperations(t, `[{"op":"add","path":"/m","value":{"a":1,"b":2}}]`)}
+	b2 := &PatchBundle{Operations: mustOperations(t, `[{"path":"/m","value":{"b":2.0,"a":1},"op":"add"}]`)}
+	if _, applied, err := i.Apply(ctx, "d1", doc, b1); err != nil || !applied {
+		t.Fatal("expected applied", applied, err)
+	}
+	if _, applied, err := i.Apply(ctx, "d1", doc, b2); err != nil || applied {
+		t.Fatal("expected duplicate", applied, err)
+	}
+
+	// failed patches are not recorded.
+	bad := &PatchBundle{IdempotencyKey: "bad", Operations: mustOperations(t, `[{"op":"test","path":"/n","value":1}]`)}
+	if _, _, err := i.Apply(ctx, "d1", doc, bad); err == nil {
+		t.Fatal("expected error")
+	}
+	if seen, _ := i.Ledger.Has(ctx, "d1", "bad"); seen {
+		t.Fatal("failed patch is recorded")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrNotInvertible is returned by Invert if an operation is not a standard RFC6902 operation.
+var ErrNotInvertible = errors.New("operation not invertible")
+
+// Invert returns the operations undo ops on the json document b.
+// Removed and replaced values are captured from b, array indices are resolved to the actual ones,
+// so applying the result to the patched document gives back b.
+// Skipped operations and tests have no inverse.
+func (p *Patch) Invert(b []byte, ops []Operation) ([]Operation, error) {
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return p.InvertAny(doc, ops)
+}
+
+// InvertAny is the same as Invert but accepts a decoded document, which is not modified.
+func (p *Patch) InvertAny(doc any, ops []Operation) ([]Operation, error) {
+	if err := p.Check(ops); err != nil {
+		return nil, err
+	}
+	doc = deepCopy(doc)
+	var groups [][]Operation
+	for i, op := range ops {
+		inv, err := p.invert(&doc, op)
+		if err != nil && !(!p.StrictPathExists && errors.Is(err, ErrNotExists)) {
+			return nil, fmt.Errorf("invert operation %d: %w", i, err)
+		}
+		groups = append(groups, inv)
+	}
+	var r []Operation
+	for i := len(groups) - 1; i >= 0; i-- {
+		r = append(r, groups[i]...)
+	}
+	return r, nil
+}
+
+// invert applies op to doc and returns its inverse.
+func (p *Patch) invert(doc *any, op Operation) ([]Operation, error) {
+	path := *op.Path
+	if isTestOP(*op.OP) {
+		return nil, p.extensions[*op.OP].Apply(p, doc, op)
+	}
+	switch *op.OP {
+	case opRemove:
+		old, err := p.valueAt(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		resolved := p.resolveIndex(doc, path, 0)
+		if err := p.extensions[opRemove].Apply(p, doc, op); err != nil {
+			return nil, err
+		}
+		return []Operation{newOperation(opAdd, resolved, old, nil)}, nil
+	case opAdd, opReplace, opCopy:
+		return p.invertWrite(doc, op)
+	case opMove:
+		return p.invertMove(doc, op)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotInvertible, *op.OP)
+	}
+}
+
+// invertWrite inverts add, replace and copy.
+func (p *Patch) invertWrite(doc *any, op Operation) ([]Operation, error) {
+	path := *op.Path
+	old, err := p.valueAt(doc, path)
+	existed := err == nil && !(*op.OP != opReplace && p.parentIsArray(doc, path))
+	if err := p.extensions[*op.OP].Apply(p, doc, deepCopyOperation(op)); err != nil {
+		return nil, err
+	}
+	if existed {
+		return []Operation{newOperation(opReplace, path, old, nil)}, nil
+	}
+	return []Operation{newOperation(opRemove, p.resolveIndex(doc, path, 1), nil, nil)}, nil
+}
+
+func (p *Patch) invertMove(doc *any, op Operation) ([]Operation, error) {
+	path, from := *op.Path, *op.From
+	if path == from {
+		return nil, p.extensions[opMove].Apply(p, doc, op)
+	}
+	old, err := p.valueAt(doc, path)
+	existed := err == nil && !p.parentIsArray(doc, path)
+	resolvedFrom := p.resolveIndex(doc, from, 0)
+	if err := p.extensions[opMove].Apply(p, doc, op); err != nil {
+		return nil, err
+	}
+	resolved := p.resolveIndex(doc, path, 1)
+	inv := []Operation{newOperation(opMove, resolvedFrom, nil, &resolved)}
+	if existed {
+		inv = append(inv, newOperation(opAdd, path, old, nil))
+	}
+	return inv, nil
+}
+
+func (p *Patch) valueAt(doc *any, path string) (any, error) {
+	v, _, err := p.VisitPath(doc, NewJSONPointer(path).Path()...)
+	if err != nil {
+		return nil, err
+	}
+	return deepCopy(v), nil
+}
+
+// parentIsArray returns true if the parent of path is an array.
+func (p *Patch) parentIsArray(doc *any, path string) bool {
+	parts := NewJSONPointer(path)
+	if parts.IsTheWholeDocument() {
+		return false
+	}
+	c, _, err := p.VisitPath(doc, parts.ParentPath()...)
+	if err != nil {
+		return false
+	}
+	_, ok := c.([]any)
+	return ok
+}
+
+// resolveIndex replaces the last token of path by the actual index if its parent is an array.
+// extra is the number of elements inserted by the applied operation.
+func (p *Patch) resolveIndex(doc *any, path string, extra int) string {
+	parts := NewJSONPointer(path)
+	if parts.IsTheWholeDocument() {
+		return path
+	}
+	c, _, err := p.VisitPath(doc, parts.ParentPath()...)
+	if err != nil {
+		return path
+	}
+	a, ok := c.([]any)
+	if !ok {
+		return path
+	}
+	i, err := p.ParseArrayIndex(len(a)-extra, parts.LastToken())
+	if err != nil {
+		return path
+	}
+	return joinPointer(parts.ParentPath()) + "/" + strconv.Itoa(i)
+}
+
+func deepCopyOperation(op Operation) Operation {
+	return copyOperations([]Operation{op})[0]
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInvert(t *testing.T) {
+	doc := []byte(`{"a":[1,2,3],"b":{"c":"d"},"e":1}`)
+	ops := mustOperations(t, `[
+		{"op":"add","path":"/a/-","value":4},
+		{"op":"remove","path":"/a/0"},
+		{"op":"replace","path":"/b/c","value":"x"},
+		{"op":"move","from":"/e","path":"/b/c"},
+		{"op":"copy","from":"/a","path":"/f"},
+		{"op":"test","path":"/f/0","value":2}
+	]`)
+	p := New()
+	inv, err := p.Invert(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mustOperations(t, `[
+		{"op":"remove","path":"/f"},
+		{"op":"move","from":"/b/c","path":"/e"},
+		{"op":"add","path":"/b/c","value":"x"},
+		{"op":"replace","path":"/b/c","value":"d"},
+		{"op":"add","path":"/a/0","value":1},
+		{"op":"remove","path":"/a/3"}
+	]`)
+	if !reflect.DeepEqual(inv, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(inv))
+	}
+	_, err = New().Invert(doc, mustOperations(t, `[{"op":"inc","path":"/e","value":1}]`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	_, err = New(WithExtension(clearExtension{})).Invert(doc, mustOperations(t, `[{"op":"clear","path":"/b"}]`))
+	if !errors.Is(err, ErrNotInvertible) {
+		t.Fatal("expected ErrNotInvertible, got", err)
+	}
+}
+
+func TestInvertRandom(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a":[1,2,{"b":"c"}],"d/e":{"f":null},"g":"h"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithStrictPathExists(true))
+	for seed := int64(0); seed < 100; seed++ {
+		ops := GenerateRandomPatch(doc, 10, seed)
+		inv, err := p.InvertAny(doc, ops)
+		if err != nil {
+			t.Fatal("seed", seed, err)
+		}
+		o := deepCopy(doc)
+		if err := p.applyAny(&o, copyOperations(ops)); err != nil {
+			t.Fatal("seed", seed, err)
+		}
+		if err := p.applyAny(&o, inv); err != nil {
+			t.Fatal("seed", seed, err)
+		}
+		if !reflect.DeepEqual(o, doc) {
+			t.Fatal("seed", seed, "expected", jsonstring(doc), "got", jsonstring(o))
+		}
+	}
+}
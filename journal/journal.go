@@ -0,0 +1,352 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package journal stores a json document as a log of patches with periodic snapshots.
+//
+// Any version of the document is reconstructed by loading the nearest snapshot
+// and replaying the patches after it. The journal is storage agnostic,
+// entries and snapshots are persisted by a Store.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+var (
+	// ErrVersionExists is returned by Store.Append if the version of the entry already exists.
+	// It wraps jsonpatch.ErrVersionConflict, so it's classified as jsonpatch.ClassConflict.
+	ErrVersionExists = fmt.Errorf("version already exists: %w", jsonpatch.ErrVersionConflict)
+	// ErrNoSnapshot is returned if there is no snapshot to reconstruct a version from.
+	ErrNoSnapshot = errors.New("no snapshot")
+	// ErrBadVersion is returned if a version is not in the journal.
+	ErrBadVersion = errors.New("bad version")
+)
+
+// Entry is a patch in the journal.
+// The patch applies to the document of the previous entry,
+// whose version is less than Version-1 if the entries between them are squashed by Journal.Compact.
+type Entry struct {
+	// Version is the version of the document after the patch is applied.
+	Version uint64                `json:"version"`
+	Time    time.Time             `json:"time"`
+	Patch   []jsonpatch.Operation `json:"patch"`
+}
+
+// Snapshot is a materialized document at a version.
+type Snapshot struct {
+	Version  uint64          `json:"version"`
+	Time     time.Time       `json:"time"`
+	Document json.RawMessage `json:"document"`
+}
+
+// Store persists entries and snapshots of a journal.
+type Store interface {
+	// Head returns the version of the last entry, or the version of the latest snapshot
+	// if there is no entry after it.
+	Head(ctx context.Context) (uint64, error)
+	// Append appends an entry, it must return ErrVersionExists if the version of the entry
+	// already exists.
+	Append(ctx context.Context, e Entry) error
+	// Entries returns the entries whose version is in (from, to] in version order.
+	Entries(ctx context.Context, from, to uint64) ([]Entry, error)
+	// SaveSnapshot saves a snapshot.
+	SaveSnapshot(ctx context.Context, s Snapshot) error
+	// LatestSnapshot returns the latest snapshot whose version is not greater than version.
+	// It returns ErrNoSnapshot if there is no such snapshot.
+	LatestSnapshot(ctx context.Context, version uint64) (Snapshot, error)
+	// Truncate deletes the entries whose version is not greater than version.
+	Truncate(ctx context.Context, version uint64) error
+	// Squash replaces the entries whose version is in (from, e.Version] with e.
+	Squash(ctx context.Context, from uint64, e Entry) error
+}
+
+// Journal is a patch log of a document.
+// It is safe for concurrent use.
+type Journal struct {
+	// SnapshotInterval is the number of entries between two snapshots.
+	// No snapshot is taken automatically if it is 0.
+	SnapshotInterval uint64
+
+	store Store
+	patch *jsonpatch.Patch
+	now   func() time.Time
+
+	mu      sync.Mutex
+	head    uint64
+	headDoc any
+	cached  bool
+	// times indexes the times of entries in version order, up to version indexed.
+	times   []versionTime
+	indexed uint64
+}
+
+type versionTime struct {
+	version uint64
+	time    time.Time
+}
+
+// New creates a journal on store.
+// p is used to apply patches, jsonpatch.New() is used if p is nil.
+func New(store Store, p *jsonpatch.Patch) *Journal {
+	if p == nil {
+		p = jsonpatch.New()
+	}
+	return &Journal{store: store, patch: p, now: time.Now}
+}
+
+// Init saves doc as the snapshot of version 0.
+func (j *Journal) Init(ctx context.Context, doc any) error {
+	return j.saveSnapshot(ctx, 0, j.now(), doc)
+}
+
+// Append appends a patch and returns the new version.
+// The patch is applied to the latest document before it is appended.
+func (j *Journal) Append(ctx context.Context, ops []jsonpatch.Operation) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	head, err := j.store.Head(ctx)
+	if err != nil {
+		return 0, err
+	}
+	doc, err := j.materializeHead(ctx, head)
+	if err != nil {
+		return 0, err
+	}
+	// the caller may modify ops later, store a copy.
+	stored, err := copyOperations(ops)
+	if err != nil {
+		return 0, err
+	}
+	doc, _, err = j.patch.Simulate(doc, ops)
+	if err != nil {
+		return 0, err
+	}
+	version := head + 1
+	now := j.now()
+	if err := j.store.Append(ctx, Entry{Version: version, Time: now, Patch: stored}); err != nil {
+		j.cached = false
+		return 0, err
+	}
+	j.head, j.headDoc, j.cached = version, doc, true
+	if j.indexed == head && len(j.times) > 0 {
+		j.times = append(j.times, versionTime{version: version, time: now})
+		j.indexed = version
+	}
+	if j.SnapshotInterval > 0 && version%j.SnapshotInterval == 0 {
+		if err := j.saveSnapshot(ctx, version, now, doc); err != nil {
+			return version, err
+		}
+	}
+	return version, nil
+}
+
+// copyOperations returns a deep copy of ops.
+func copyOperations(ops []jsonpatch.Operation) ([]jsonpatch.Operation, error) {
+	b, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	var r []jsonpatch.Operation
+	err = json.Unmarshal(b, &r)
+	return r, err
+}
+
+func (j *Journal) materializeHead(ctx context.Context, head uint64) (any, error) {
+	if j.cached && j.head == head {
+		return j.headDoc, nil
+	}
+	return j.Materialize(ctx, head)
+}
+
+// Materialize reconstructs the document at version by replaying patches after the nearest snapshot.
+func (j *Journal) Materialize(ctx context.Context, version uint64) (any, error) {
+	s, err := j.store.LatestSnapshot(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	return j.replay(ctx, s, version)
+}
+
+func (j *Journal) replay(ctx context.Context, s Snapshot, version uint64) (any, error) {
+	var doc any
+	if err := json.Unmarshal(s.Document, &doc); err != nil {
+		return nil, err
+	}
+	if version == s.Version {
+		return doc, nil
+	}
+	entries, err := j.store.Entries(ctx, s.Version, version)
+	if err != nil {
+		return nil, err
+	}
+	next := s.Version + 1
+	for _, e := range entries {
+		if e.Version < next {
+			return nil, fmt.Errorf("%w: duplicate entry of version %d", ErrBadVersion, e.Version)
+		}
+		// Simulate accepts any document Append accepted and never notifies the router of the patch.
+		if doc, _, err = j.patch.Simulate(doc, e.Patch); err != nil {
+			return nil, fmt.Errorf("replay version %d: %w", e.Version, err)
+		}
+		next = e.Version + 1
+	}
+	if next != version+1 {
+		return nil, fmt.Errorf("%w: %d", ErrBadVersion, version)
+	}
+	return doc, nil
+}
+
+// Snapshot takes a snapshot of version, whose time is the time of the entry of version.
+func (j *Journal) Snapshot(ctx context.Context, version uint64) error {
+	doc, err := j.Materialize(ctx, version)
+	if err != nil {
+		return err
+	}
+	t, err := j.timeOf(ctx, version)
+	if err != nil {
+		return err
+	}
+	return j.saveSnapshot(ctx, version, t, doc)
+}
+
+// timeOf returns the time of the entry of version,
+// or the time of the snapshot of version if the entry is truncated.
+func (j *Journal) timeOf(ctx context.Context, version uint64) (time.Time, error) {
+	if version > 0 {
+		entries, err := j.store.Entries(ctx, version-1, version)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if len(entries) == 1 {
+			return entries[0].Time, nil
+		}
+	}
+	s, err := j.store.LatestSnapshot(ctx, version)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if s.Version != version {
+		return time.Time{}, fmt.Errorf("%w: missing entry of version %d", ErrBadVersion, version)
+	}
+	return s.Time, nil
+}
+
+// Compact squashes the entries after the nearest snapshot of version up to version into one entry,
+// which is the diff between the snapshot and the document at version,
+// and deletes the entries before the snapshot.
+// Only the snapshot, version and the later versions can be reconstructed after compaction.
+func (j *Journal) Compact(ctx context.Context, version uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s, err := j.store.LatestSnapshot(ctx, version)
+	if err != nil {
+		return err
+	}
+	var squashed Entry
+	if s.Version != version {
+		if squashed, err = j.squash(ctx, s, version); err != nil {
+			return err
+		}
+	}
+	// the entries are changed, the time index is loaded again.
+	j.times, j.indexed = nil, 0
+	if err := j.store.Truncate(ctx, s.Version); err != nil {
+		return err
+	}
+	if s.Version == version {
+		return nil
+	}
+	return j.store.Squash(ctx, s.Version, squashed)
+}
+
+// squash returns an entry of version whose patch transforms the document of s into the document of version.
+func (j *Journal) squash(ctx context.Context, s Snapshot, version uint64) (Entry, error) {
+	base, err := j.replay(ctx, s, s.Version)
+	if err != nil {
+		return Entry{}, err
+	}
+	doc, err := j.replay(ctx, s, version)
+	if err != nil {
+		return Entry{}, err
+	}
+	t, err := j.timeOf(ctx, version)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Version: version, Time: t, Patch: jsonpatch.CreatePatchAny(base, doc)}, nil
+}
+
+func (j *Journal) saveSnapshot(ctx context.Context, version uint64, t time.Time, doc any) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return j.store.SaveSnapshot(ctx, Snapshot{Version: version, Time: t, Document: b})
+}
+
+// VersionAt returns the version of the document at t, which is the version of the last entry appended not after t.
+// If t is before the first entry, which may be squashed by Compact,
+// it returns the version of the snapshot the entry applies to.
+// The times of entries are indexed in memory, the index is loaded from the store on the first call
+// and extended by later calls.
+func (j *Journal) VersionAt(ctx context.Context, t time.Time) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	head, err := j.store.Head(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if head > j.indexed {
+		entries, err := j.store.Entries(ctx, j.indexed, head)
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range entries {
+			j.times = append(j.times, versionTime{version: e.Version, time: e.Time})
+		}
+		j.indexed = head
+	}
+	i := sort.Search(len(j.times), func(i int) bool {
+		return j.times[i].time.After(t)
+	})
+	if i > 0 {
+		return j.times[i-1].version, nil
+	}
+	// t is before the first entry, the version is the snapshot the first entry applies to,
+	// which may be older than the version before it if the entries are squashed.
+	version := head
+	if len(j.times) > 0 {
+		version = j.times[0].version - 1
+	}
+	s, err := j.store.LatestSnapshot(ctx, version)
+	if err != nil {
+		return 0, err
+	}
+	if s.Time.After(t) {
+		return 0, fmt.Errorf("%w: no version at %s", ErrBadVersion, t.Format(time.RFC3339))
+	}
+	return s.Version, nil
+}
+
+// AsOf reconstructs the document at t and returns it with its version.
+// Only the entries after the nearest snapshot of the version are replayed.
+func (j *Journal) AsOf(ctx context.Context, t time.Time) (any, uint64, error) {
+	version, err := j.VersionAt(ctx, t)
+	if err != nil {
+		return nil, 0, err
+	}
+	doc, err := j.Materialize(ctx, version)
+	if err != nil {
+		return nil, 0, err
+	}
+	return doc, version, nil
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func operations(t *testing.T, s string) []jsonpatch.Operation {
+	t.Helper()
+	var ops []jsonpatch.Operation
+	if err := json.Unmarshal([]byte(s), &ops); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	j := New(store, nil)
+	j.SnapshotInterval = 2
+	if err := j.Init(ctx, map[string]any{"n": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		v, err := j.Append(ctx, operations(t, `[{"op":"replace","path":"/n","value":`+strconv.Itoa(i)+`}]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != uint64(i) {
+			t.Fatal("expected version", i, "got", v)
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		doc, err := j.Materialize(ctx, uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(doc, map[string]any{"n": float64(i)}) {
+			t.Fatal("bad document of version", i, doc)
+		}
+	}
+	if _, err := j.Materialize(ctx, 6); !errors.Is(err, ErrBadVersion) {
+		t.Fatal("expected bad version, got", err)
+	}
+	if err := j.Compact(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Materialize(ctx, 1); err == nil {
+		t.Fatal("expected error after compaction")
+	}
+	doc, err := j.Materialize(ctx, 5)
+	if err != nil || !reflect.DeepEqual(doc, map[string]any{"n": 5.0}) {
+		t.Fatal("bad document after compaction", doc, err)
+	}
+}
+
+func TestJournalRejectsBadPatch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	j := New(store, nil)
+	if err := j.Init(ctx, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Append(ctx, operations(t, `[{"op":"remove","path":"/x"}]`)); err == nil {
+		t.Fatal("expected error")
+	}
+	head, _ := store.Head(ctx)
+	if head != 0 {
+		t.Fatal("expected head 0, got", head)
+	}
+}
+
+func TestJournalAsOf(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	j := New(store, nil)
+	j.now = func() time.Time { return now }
+	j.SnapshotInterval = 3
+	if err := j.Init(ctx, map[string]any{"n": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		now = start.Add(time.Duration(i) * time.Hour)
+		if _, err := j.Append(ctx, operations(t, `[{"op":"replace","path":"/n","value":`+strconv.Itoa(i)+`}]`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		at      time.Time
+		version uint64
+	}{
+		{start, 0},
+		{start.Add(90 * time.Minute), 1},
+		{start.Add(3 * time.Hour), 3},
+		{start.Add(48 * time.Hour), 4},
+	}
+	for _, c := range cases {
+		doc, version, err := j.AsOf(ctx, c.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != c.version || !reflect.DeepEqual(doc, map[string]any{"n": float64(c.version)}) {
+			t.Fatal("bad document at", c.at, version, doc)
+		}
+	}
+	if _, _, err := j.AsOf(ctx, start.Add(-time.Hour)); !errors.Is(err, ErrBadVersion) {
+		t.Fatal("expected bad version, got", err)
+	}
+
+	// a new journal loads the index from the store.
+	j2 := New(store, nil)
+	if v, err := j2.VersionAt(ctx, start.Add(150*time.Minute)); err != nil || v != 2 {
+		t.Fatal("bad version", v, err)
+	}
+	now = start.Add(5 * time.Hour)
+	if _, err := j.Append(ctx, operations(t, `[{"op":"replace","path":"/n","value":5}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := j2.VersionAt(ctx, now); err != nil || v != 5 {
+		t.Fatal("bad version", v, err)
+	}
+}
+
+func TestJournalSnapshotTime(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	j := New(NewMemoryStore(), nil)
+	j.now = func() time.Time { return now }
+	if err := j.Init(ctx, map[string]any{"n": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		now = start.Add(time.Duration(i) * time.Hour)
+		if _, err := j.Append(ctx, operations(t, `[{"op":"replace","path":"/n","value":`+strconv.Itoa(i)+`}]`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// snapshots and squashed entries of old versions keep the time of their versions.
+	now = start.Add(10 * time.Hour)
+	if err := j.Snapshot(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Compact(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		at      time.Time
+		version uint64
+	}{
+		{start.Add(150 * time.Minute), 2},
+		{start.Add(3 * time.Hour), 3},
+		{start.Add(48 * time.Hour), 4},
+	}
+	for _, c := range cases {
+		if v, err := j.VersionAt(ctx, c.at); err != nil || v != c.version {
+			t.Fatal("bad version at", c.at, v, err)
+		}
+	}
+	if _, err := j.VersionAt(ctx, start.Add(time.Hour)); !errors.Is(err, ErrBadVersion) {
+		t.Fatal("expected bad version, got", err)
+	}
+}
+
+func TestJournalKeepsPatch(t *testing.T) {
+	ctx := context.Background()
+	j := New(NewMemoryStore(), nil)
+	if err := j.Init(ctx, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	ops := operations(t, `[{"op":"add","path":"/a","value":{"x":1}},{"op":"remove","path":"/a/x"}]`)
+	if _, err := j.Append(ctx, ops); err != nil {
+		t.Fatal(err)
+	}
+	// modifying the patch after it is appended does not change the journal.
+	*ops[0].Value = "changed"
+	doc, err := j.Materialize(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, map[string]any{"a": map[string]any{}}) {
+		t.Fatal("bad document", doc)
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	ctx := context.Background()
+	var routed int
+	router := jsonpatch.NewRouter()
+	router.Handle("/**", func([]jsonpatch.Operation) { routed++ })
+	j := New(NewMemoryStore(), jsonpatch.New(jsonpatch.WithRouter(router)))
+	if err := j.Init(ctx, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Append(ctx, operations(t, `[{"op":"replace","path":"","value":2}]`)); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := j.Materialize(ctx, 1)
+	if err != nil || doc != 2.0 {
+		t.Fatal("bad document", doc, err)
+	}
+	if routed != 0 {
+		t.Fatal("replay notifies the router")
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	j := New(store, nil)
+	if err := j.Init(ctx, map[string]any{"n": 0.0, "a": []any{}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := j.Append(ctx, operations(t, `[{"op":"replace","path":"/n","value":`+strconv.Itoa(i)+`},{"op":"add","path":"/a/-","value":`+strconv.Itoa(i)+`}]`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Compact(ctx, 9); !errors.Is(err, ErrBadVersion) {
+		t.Fatal("expected bad version, got", err)
+	}
+	if err := j.Compact(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.Entries(ctx, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Version != 4 || entries[1].Version != 5 {
+		t.Fatal("entries are not squashed", entries)
+	}
+	for _, v := range []uint64{0, 4, 5} {
+		doc, err := j.Materialize(ctx, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := []any{}
+		for i := 1; i <= int(v); i++ {
+			a = append(a, float64(i))
+		}
+		if !reflect.DeepEqual(doc, map[string]any{"n": float64(v), "a": a}) {
+			t.Fatal("bad document of version", v, doc)
+		}
+	}
+	if _, err := j.Materialize(ctx, 2); !errors.Is(err, ErrBadVersion) {
+		t.Fatal("expected bad version, got", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package journal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps everything in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   []Entry
+	snapshots []Snapshot
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Head implements Store.
+func (m *MemoryStore) Head(_ context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var head uint64
+	if n := len(m.snapshots); n > 0 {
+		head = m.snapshots[n-1].Version
+	}
+	if n := len(m.entries); n > 0 && m.entries[n-1].Version > head {
+		head = m.entries[n-1].Version
+	}
+	return head, nil
+}
+
+// Append implements Store.
+func (m *MemoryStore) Append(_ context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n := len(m.entries); n > 0 && m.entries[n-1].Version >= e.Version {
+		return fmt.Errorf("%w: %d", ErrVersionExists, e.Version)
+	}
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+// Entries implements Store.
+func (m *MemoryStore) Entries(_ context.Context, from, to uint64) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var r []Entry
+	for _, e := range m.entries {
+		if e.Version > from && e.Version <= to {
+			r = append(r, e)
+		}
+	}
+	return r, nil
+}
+
+// SaveSnapshot implements Store.
+func (m *MemoryStore) SaveSnapshot(_ context.Context, s Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := sort.Search(len(m.snapshots), func(i int) bool {
+		return m.snapshots[i].Version >= s.Version
+	})
+	if i < len(m.snapshots) && m.snapshots[i].Version == s.Version {
+		m.snapshots[i] = s
+		return nil
+	}
+	m.snapshots = append(m.snapshots, Snapshot{})
+	copy(m.snapshots[i+1:], m.snapshots[i:])
+	m.snapshots[i] = s
+	return nil
+}
+
+// LatestSnapshot implements Store.
+func (m *MemoryStore) LatestSnapshot(_ context.Context, version uint64) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := sort.Search(len(m.snapshots), func(i int) bool {
+		return m.snapshots[i].Version > version
+	})
+	if i == 0 {
+		return Snapshot{}, ErrNoSnapshot
+	}
+	return m.snapshots[i-1], nil
+}
+
+// Truncate implements Store.
+func (m *MemoryStore) Truncate(_ context.Context, version uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := sort.Search(len(m.entries), func(i int) bool {
+		return m.entries[i].Version > version
+	})
+	m.entries = append([]Entry(nil), m.entries[i:]...)
+	return nil
+}
+
+// Squash implements Store.
+func (m *MemoryStore) Squash(_ context.Context, from uint64, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]Entry, 0, len(m.entries))
+	for _, v := range m.entries {
+		switch {
+		case v.Version <= from || v.Version > e.Version:
+			entries = append(entries, v)
+		case v.Version == e.Version:
+			entries = append(entries, e)
+		}
+	}
+	m.entries = entries
+	return nil
+}
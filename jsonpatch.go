@@ -10,10 +10,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"reflect"
+	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
 const (
@@ -60,20 +62,24 @@ type Operation struct {
 	// Unlike json Unmarshal, if the value is null, it will not be set to nil, but a pointer to nil.
 	Value *any    `json:"value,omitempty"`
 	From  *string `json:"from,omitempty"`
+	// Comment and Label are non-standard annotations of the operation, see WithAnnotations.
+	// They are ignored by the operations and removed by StripAnnotations.
+	Comment *string `json:"comment,omitempty"`
+	Label   *string `json:"label,omitempty"`
 }
 
-func (o Operation) check() error {
+func (o Operation) check(checkPath func(string) error) error {
 	if o.OP == nil {
-		return errors.New("must contains an op member")
+		return errMissingMember("", "op")
 	}
 	if o.Path == nil {
-		return errors.New("must contains a path member")
+		return errMissingMember("", "path")
 	}
-	if err := NewJSONPointer(*o.Path).Check(); err != nil {
+	if err := checkPath(*o.Path); err != nil {
 		return err
 	}
 	if o.From != nil {
-		if err := NewJSONPointer(*o.From).Check(); err != nil {
+		if err := checkPath(*o.From); err != nil {
 			return err
 		}
 	}
@@ -90,6 +96,8 @@ func (o *Operation) UnmarshalJSON(data []byte) error {
 	setValueFromMap(&o.Path, m, "path")
 	setAnyFromMap(&o.Value, m, "value")
 	setValueFromMap(&o.From, m, "from")
+	setValueFromMap(&o.Comment, m, "comment")
+	setValueFromMap(&o.Label, m, "label")
 	return nil
 }
 
@@ -123,6 +131,31 @@ func NewJSONPointer(p string) JSONPointer {
 	return JSONPointer{origin: p}
 }
 
+// NewJSONPointerFromTokens create a new JSONPointer from unescaped tokens,
+// "~" and "/" of the tokens are escaped.
+func NewJSONPointerFromTokens(tokens ...string) JSONPointer {
+	return JSONPointer{origin: joinPointer(tokens)}
+}
+
+// AppendToken returns a new JSONPointer with the unescaped token appended.
+func (p JSONPointer) AppendToken(token string) JSONPointer {
+	return p.Join(token)
+}
+
+// Join returns a new JSONPointer with the unescaped tokens appended.
+func (p JSONPointer) Join(tokens ...string) JSONPointer {
+	return JSONPointer{origin: p.origin + joinPointer(tokens)}
+}
+
+// Parent returns the JSONPointer of the parent, or the whole document if p is the whole document.
+func (p JSONPointer) Parent() JSONPointer {
+	i := strings.LastIndexByte(p.origin, '/')
+	if i < 0 {
+		return JSONPointer{}
+	}
+	return JSONPointer{origin: p.origin[:i]}
+}
+
 // IsTheWholeDocument return true if the JSONPointer points to the whole document.
 func (p JSONPointer) IsTheWholeDocument() bool {
 	return p.origin == ""
@@ -132,18 +165,43 @@ func (p JSONPointer) IsTheWholeDocument() bool {
 func (p JSONPointer) Path() []string {
 	v := strings.Split(p.origin, "/")
 	for i, d := range v {
-		v[i] = unescapePath(d)
+		v[i] = UnescapeToken(d)
 	}
 	return v[1:]
 }
 
+// String returns the JSONPointer as a string.
+func (p JSONPointer) String() string {
+	return p.origin
+}
+
 // Check check the JSONPointer, and return error if the JSONPointer is invalid.
 func (p JSONPointer) Check() error {
 	if p.origin == "" {
 		return nil
 	}
 	if p.origin[0] != '/' {
-		return errors.New("json pointer must start with /")
+		return newError(CodeBadPointer, map[string]any{"pointer": p.origin}, nil)
+	}
+	return nil
+}
+
+// CheckStrict checks the JSONPointer like Check, and also rejects "~" not followed by "0" or "1".
+// The error is an *Error of CodeBadEscape with the index of the offending token.
+func (p JSONPointer) CheckStrict() error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	if p.origin == "" {
+		return nil
+	}
+	for i, token := range strings.Split(p.origin[1:], "/") {
+		for j := strings.IndexByte(token, '~'); j >= 0; j = strings.IndexByte(token, '~') {
+			if j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1') {
+				return newError(CodeBadEscape, map[string]any{"pointer": p.origin, "index": i, "token": token}, nil)
+			}
+			token = token[j+2:]
+		}
 	}
 	return nil
 }
@@ -184,21 +242,142 @@ func (p JSONPointer) LastToken() string {
 	return v[len(v)-1]
 }
 
+// Resolve returns the value at the JSONPointer of doc, which is a value decoded by encoding/json.
+// It returns an error wraps ErrNotExists if the value not exists.
+// The returned value is shared with doc.
+func (p JSONPointer) Resolve(doc any) (any, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	v, _, err := New().VisitPath(&doc, p.Path()...)
+	if err != nil {
+		if !errors.Is(err, ErrNotExists) {
+			// a scalar can not contain the value.
+			err = fmt.Errorf("%w: %v", ErrNotExists, err)
+		}
+		return nil, errPathNotExists(p.origin, err)
+	}
+	return v, nil
+}
+
+// ResolveBytes returns the value at the JSONPointer of the json document b.
+func (p JSONPointer) ResolveBytes(b []byte) (any, error) {
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return p.Resolve(doc)
+}
+
+// Set sets the value at the JSONPointer of doc.
+// An object member is added or replaced, an array element is replaced
+// and the index equals to the length of the array, or "-", appends the value.
+// The parent of the value must exist.
+func (p JSONPointer) Set(doc *any, value any) error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	if p.IsTheWholeDocument() {
+		*doc = value
+		return nil
+	}
+	patch := New()
+	parent, set, err := patch.VisitPath(doc, p.ParentPath()...)
+	if err != nil {
+		return errPathNotExists(p.origin, err)
+	}
+	if v, ok := parent.([]any); ok {
+		i, err := patch.ParseArrayIndex(len(v), p.LastToken())
+		if err != nil {
+			return err
+		}
+		if i < len(v) {
+			return patch.ReplaceValue(parent, set, p.LastToken(), value)
+		}
+	}
+	return patch.AddValue(parent, set, p.LastToken(), value)
+}
+
+// Delete removes the value at the JSONPointer of doc.
+// It returns an error wraps ErrNotExists if the value not exists.
+// The whole document can not be deleted.
+func (p JSONPointer) Delete(doc *any) error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	if p.IsTheWholeDocument() {
+		return errors.New("cannot delete the whole document")
+	}
+	patch := New()
+	parent, set, err := patch.VisitPath(doc, p.ParentPath()...)
+	if err != nil {
+		return errPathNotExists(p.origin, err)
+	}
+	if err := patch.RemoveValue(parent, set, p.LastToken()); err != nil {
+		return errPathNotExists(p.origin, err)
+	}
+	return nil
+}
+
 // Patch is a jsonpatch introduced in RFC6902.
 type Patch struct {
 	// StrictPathExists is a flag that indicates whether to throw an error if the path does not exist.
 	StrictPathExists bool
 	// SupportNegativeArrayIndex is a flag that indicates whether to support negative array index.
 	SupportNegativeArrayIndex bool
+	// RawEngine is a flag that indicates whether to apply patches on raw json bytes.
+	RawEngine bool
+	// OriginalArrayIndex is a flag that indicates whether to support "#N" tokens referring to original array elements.
+	OriginalArrayIndex bool
+	// MaxFanout is the max number of locations a single operation may touch, 0 means no limit.
+	MaxFanout int
+	// MaxDocumentSize is the max size in bytes of the output of Apply, 0 means no limit.
+	MaxDocumentSize int
+	// Annotations is a flag that indicates whether to surface the comment and label of operations.
+	Annotations bool
+	// URIFragment is a flag that indicates whether to accept pointers in the URI fragment form.
+	URIFragment bool
+	// RFC6902Strict is a flag that indicates whether to reject everything out of RFC6902, see WithRFC6902Strict.
+	RFC6902Strict bool
+	// Wildcards is a flag that indicates whether a "*" token of a path matches every member or element.
+	Wildcards bool
+	// RecursiveWildcards is a flag that indicates whether a "**" token of a path matches any depth.
+	RecursiveWildcards bool
+	// ElementSelectors is a flag that indicates whether a "[key=value]" token of a path selects array elements by member.
+	ElementSelectors bool
+	// JSONPathPaths is a flag that indicates whether path and from starting with "$" are JSONPath expressions.
+	JSONPathPaths bool
+	// SymbolicArrayIndex is a flag that indicates whether "first" and "last" are array indices.
+	SymbolicArrayIndex bool
+	// IntermediateAppend is a flag that indicates whether "-" in the middle of the path of add appends a new element.
+	IntermediateAppend bool
 
 	// Standard json marshaling options.
 	JSONPrefix     string
 	JSONIndent     string
 	JSONEscapeHTML bool
 
-	extensions map[string]Extension
+	extensions    map[string]Extension
+	redactions    []redaction
+	cache         Cache
+	pointers      *pointerCache
+	retrier       *Retrier
+	errorRenderer ErrorRenderer
+	router        *Router
+	types         []typeRule
+	coerce        bool
+	policy        *Policy
+	transformers  []ValueTransformer
+	embedded      [][]string
+	ownership     *Ownership
+	writer        string
+	// id identifies the Patch in cache keys.
+	id uint64
 }
 
+// patchIDs is the last id of Patches created by New.
+var patchIDs uint64
+
 // Option is a jsonpatch option.
 type Option func(o *Patch)
 
@@ -235,6 +414,17 @@ func WithSupportNegativeArrayIndex(on bool) Option {
 	}
 }
 
+// WithSymbolicArrayIndex set the SymbolicArrayIndex option.
+// The default value is false.
+// If SymbolicArrayIndex is true, "first" is the index 0 and "last" is the index of the last element
+// wherever an array index is accepted, e.g. replace "/items/last".
+// Adding at "last" inserts before the last element, use "-" to append.
+func WithSymbolicArrayIndex(on bool) Option {
+	return func(o *Patch) {
+		o.SymbolicArrayIndex = on
+	}
+}
+
 // WithExtension  add a new extension.
 func WithExtension(ext Extension) Option {
 	return func(o *Patch) {
@@ -246,7 +436,9 @@ func WithExtension(ext Extension) Option {
 // It exactly matches the RFC6902 spec if no option is set.
 func New(options ...Option) *Patch {
 	p := &Patch{
+		id:               atomic.AddUint64(&patchIDs, 1),
 		StrictPathExists: true,
+		pointers:         newPointerCache(defaultPointerCacheSize),
 		extensions: map[string]Extension{
 			opAdd:     addExtension{},
 			opRemove:  removeExtension{},
@@ -264,9 +456,19 @@ func New(options ...Option) *Patch {
 
 var ()
 
+// Symbolic array indices, see WithSymbolicArrayIndex.
+const (
+	indexFirst = "first"
+	indexLast  = "last"
+)
+
 var indexRE = regexp.MustCompile(`^(0|([1-9][0-9]*))$`)
 var negativeIndexRE = regexp.MustCompile(`^-?(0|([1-9][0-9]*))$`)
 
+func (p *Patch) isSymbolicIndex(s string) bool {
+	return p.SymbolicArrayIndex && (s == indexFirst || s == indexLast)
+}
+
 // ParseArrayIndex parse the array index.
 func (p *Patch) ParseArrayIndex(size int, s string) (i int, err error) {
 	if s == "-" {
@@ -275,9 +477,18 @@ func (p *Patch) ParseArrayIndex(size int, s string) (i int, err error) {
 		}
 		return size, nil
 	}
+	if p.isSymbolicIndex(s) {
+		if s == indexFirst {
+			return 0, nil
+		}
+		if size == 0 {
+			return 0, newError(CodeIndexOutOfRange, map[string]any{"index": s, "size": size}, nil)
+		}
+		return size - 1, nil
+	}
 	if p.SupportNegativeArrayIndex {
 		if !negativeIndexRE.MatchString(s) {
-			return 0, fmt.Errorf("bad array index: %s", s)
+			return 0, errBadArrayIndex(s)
 		}
 		i, err = strconv.Atoi(s)
 		if err != nil {
@@ -288,7 +499,7 @@ func (p *Patch) ParseArrayIndex(size int, s string) (i int, err error) {
 		}
 	} else {
 		if !indexRE.MatchString(s) {
-			return 0, fmt.Errorf("bad array index: %s", s)
+			return 0, errBadArrayIndex(s)
 		}
 		i, err = strconv.Atoi(s)
 		if err != nil {
@@ -299,7 +510,7 @@ func (p *Patch) ParseArrayIndex(size int, s string) (i int, err error) {
 		}
 	}
 	if i < 0 || i > size {
-		return 0, fmt.Errorf("array index out of range: size=%d, %s", size, s)
+		return 0, newError(CodeIndexOutOfRange, map[string]any{"index": s, "size": size}, nil)
 	}
 	return i, nil
 }
@@ -347,7 +558,7 @@ func (p *Patch) visitPathPart(o any, part string) (any, Setter, error) {
 		}
 		return v[i], func(n any) { v[i] = n }, nil
 	default:
-		return nil, nil, fmt.Errorf("cannot visit type: %T", o)
+		return nil, nil, errBadType("visit", o)
 	}
 }
 
@@ -366,7 +577,7 @@ func (p *Patch) AddValue(o any, set Setter, key string, value any) (err error) {
 		set(v)
 		return nil
 	default:
-		return fmt.Errorf("bad type for add: %T", o)
+		return errBadType("add", o)
 	}
 }
 
@@ -395,7 +606,7 @@ func (p *Patch) ReplaceValue(o any, _ Setter, key string, value any) (err error)
 		v[i] = value
 		return nil
 	default:
-		return fmt.Errorf("bad type for replace: %T", o)
+		return errBadType("replace", o)
 	}
 }
 
@@ -430,7 +641,7 @@ func (p *Patch) RemoveValue(o any, set Setter, key string) (err error) {
 		set(v)
 		return nil
 	default:
-		return fmt.Errorf("bad type for remove: %T", o)
+		return errBadType("remove", o)
 	}
 }
 
@@ -453,6 +664,10 @@ func (p *Patch) MoveValue(o any, set Setter, from, to string) (err error) {
 		return nil
 	case []any:
 		fi, err := p.ParseArrayIndex(len(v), from)
+		if err == nil && fi == len(v) {
+			// "-" or the length refers to no element.
+			err = ErrNotExists
+		}
 		if err != nil {
 			if p.StrictPathExists {
 				return ErrNotExists
@@ -460,6 +675,15 @@ func (p *Patch) MoveValue(o any, set Setter, from, to string) (err error) {
 			return nil
 		}
 		ti, err := p.ParseArrayIndex(len(v), to)
+		if err == nil && ti == len(v) {
+			if to == "-" {
+				// append after the element is removed.
+				ti = len(v) - 1
+			} else {
+				// the index is out of range once the element is removed.
+				err = ErrNotExists
+			}
+		}
 		if err != nil {
 			if p.StrictPathExists {
 				return ErrNotExists
@@ -476,29 +700,102 @@ func (p *Patch) MoveValue(o any, set Setter, from, to string) (err error) {
 		set(v)
 		return nil
 	default:
-		return fmt.Errorf("bad type for move: %T", o)
+		return errBadType("move", o)
 	}
 }
 
 // Check check the operations.
 func (p *Patch) Check(ops []Operation) error {
+	ops, err := p.fromURIFragments(ops)
+	if err != nil {
+		return p.localize(err)
+	}
 	for _, op := range ops {
-		if err := op.check(); err != nil {
+		if err := p.checkOne(op); err != nil {
 			return err
 		}
-		e := p.extensions[*op.OP]
-		if e == nil {
-			return fmt.Errorf("unknown operation: %s", *op.OP)
+	}
+	return p.checkAccess(ops)
+}
+
+// checkAccess checks ops by the ownership and the policy of p.
+func (p *Patch) checkAccess(ops []Operation) error {
+	if p.ownership != nil {
+		if err := p.ownership.Check(p.writer, ops); err != nil {
+			return err
 		}
-		if err := e.Check(p, op); err != nil {
-			return fmt.Errorf("%w: %+v", err, op)
+	}
+	if p.policy != nil {
+		return p.policy.Check(ops)
+	}
+	return nil
+}
+
+// checkOne checks an operation without the policy.
+func (p *Patch) checkOne(op Operation) error {
+	if err := op.check(p.checkPath); err != nil {
+		return p.localize(err)
+	}
+	if p.RFC6902Strict {
+		if err := checkRFC6902(op); err != nil {
+			return p.localize(err)
 		}
 	}
+	if op.From != nil && !p.isJSONPath(*op.From) && p.hasWildcard(*op.From) {
+		return p.localize(newError(CodeInvalidOperation, map[string]any{"operation": p.Describe(op)}, errPatternFrom))
+	}
+	e := p.extensions[*op.OP]
+	if e == nil {
+		return p.localize(newError(CodeUnknownOperation, map[string]any{"op": *op.OP}, nil))
+	}
+	if err := e.Check(p, op); err != nil {
+		return p.localize(newError(CodeInvalidOperation, map[string]any{"operation": p.Describe(op)}, err))
+	}
 	return nil
 }
 
 // Apply apply the operations.
 func (p *Patch) Apply(b []byte, ops []Operation) ([]byte, error) {
+	out, err := p.applyJSON(b, ops)
+	if err != nil {
+		return nil, err
+	}
+	p.route(ops)
+	return out, nil
+}
+
+// applyJSON apply the operations as Apply does, without routing them.
+func (p *Patch) applyJSON(b []byte, ops []Operation) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+	if p.cache != nil {
+		out, err = p.applyCached(b, ops)
+	} else {
+		out, err = p.applyBytes(b, ops)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkOutputSize(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (p *Patch) applyBytes(b []byte, ops []Operation) ([]byte, error) {
+	if p.canApplyRaw(ops) {
+		out, err := p.applyRaw(b, ops)
+		if err != nil {
+			return nil, err
+		}
+		// frame the output as the encoder of the standard engine does.
+		if p.EncodeOptions().TrailingNewline {
+			out = append(out, '\n')
+		}
+		return out, nil
+	}
 	var o any
 	if err := json.Unmarshal(b, &o); err != nil {
 		return nil, err
@@ -507,56 +804,176 @@ func (p *Patch) Apply(b []byte, ops []Operation) ([]byte, error) {
 		return nil, err
 	}
 	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	enc.SetEscapeHTML(p.JSONEscapeHTML)
-	enc.SetIndent(p.JSONPrefix, p.JSONIndent)
-	if err := enc.Encode(o); err != nil {
+	if err := p.EncodeOptions().encode(&buf, o); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
+// ApplyTo apply the operations and writes the output to w,
+// so the caller can reuse its buffers instead of receiving a new allocated slice.
+// Nothing is written to w if the operations fail.
+func (p *Patch) ApplyTo(w io.Writer, b []byte, ops []Operation) error {
+	if p.cache != nil || p.MaxDocumentSize > 0 || p.canApplyRaw(ops) {
+		out, err := p.Apply(b, ops)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+	var o any
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	if err := p.applyAny(&o, ops); err != nil {
+		return err
+	}
+	if err := p.EncodeOptions().encode(w, o); err != nil {
+		return err
+	}
+	p.route(ops)
+	return nil
+}
+
 // ApplyAny apply the operations.
+// o is modified in place, unless RFC6902Strict is true, which replaces o only if all operations succeed.
 func (p *Patch) ApplyAny(o *any, ops []Operation) error {
+	if err := p.applyRoot(o, ops); err != nil {
+		return err
+	}
+	p.route(ops)
+	return nil
+}
+
+// applyRoot checks the type of the root before applying the operations as ApplyAny does, without routing them.
+func (p *Patch) applyRoot(o *any, ops []Operation) error {
 	if o == nil {
-		return fmt.Errorf("bad type for apply: %T", o)
+		return errBadType("apply", o)
 	}
 	switch (*o).(type) {
 	case map[string]interface{}:
 	case []interface{}:
 	default:
-		return fmt.Errorf("bad type for apply: %T", o)
+		return errBadType("apply", o)
+	}
+	if p.RFC6902Strict {
+		// apply to a copy, so o is untouched if the patch fails.
+		c := deepCopy(*o)
+		if err := p.applyAny(&c, ops); err != nil {
+			return err
+		}
+		*o = c
+		return nil
 	}
 	return p.applyAny(o, ops)
 }
 
 func (p *Patch) applyAny(o *any, ops []Operation) error {
+	return p.apply(o, ops, nil)
+}
+
+// apply apply the operations and record the result of every operation to r if r is not nil.
+func (p *Patch) apply(o *any, ops []Operation, r *Result) error {
+	ops, err := p.fromURIFragments(ops)
+	if err != nil {
+		return p.localize(err)
+	}
 	if err := p.Check(ops); err != nil {
 		return err
 	}
-	for _, op := range ops {
+	var tracker *indexTracker
+	if p.OriginalArrayIndex {
+		tracker = newIndexTracker()
+	}
+	for i, op := range ops {
 		ext := p.extensions[*op.OP]
-		if err := ext.Apply(p, o, op); err != nil {
+		err := p.applyOne(o, ext, op, tracker)
+		if err != nil && !(!p.StrictPathExists && errors.Is(err, ErrNotExists)) {
+			return p.operationError(ext, op, err)
+		}
+		r.record(i, op, err != nil, p.Annotations)
+	}
+	return nil
+}
 
-			if !p.StrictPathExists && errors.Is(err, ErrNotExists) {
-				continue
-			}
-			v, ok := ext.(Descriptor)
-			var desc string
-			if ok {
-				desc = v.Description(p, op)
-			} else {
-				desc = fmt.Sprintf("%s %s", *op.OP, *op.Path)
-			}
-			if errors.Is(err, ErrStop) {
-				return fmt.Errorf("operation stopped: %s ext=%T, err=%w", desc, ext, err)
+func (p *Patch) applyOne(o *any, ext Extension, op Operation, tracker *indexTracker) error {
+	resolved, err := p.resolveFrom(*o, op)
+	if err != nil {
+		return err
+	}
+	if !p.hasPattern(*op.Path) {
+		if resolved.From != op.From {
+			if err := p.checkNestedAccess(resolved); err != nil {
+				return err
 			}
-			return fmt.Errorf("operation failed: %s ext=%T, err=%w", desc, ext, err)
+		}
+		return p.applyTarget(o, ext, resolved, tracker)
+	}
+	paths, err := p.ExpandPath(*o, *op.Path)
+	if err != nil {
+		return err
+	}
+	if err := p.CheckFanout(*op.Path, len(paths)); err != nil {
+		return err
+	}
+	// the matches are checked before any of them is applied,
+	// since the pattern is not checked as concrete paths by Check.
+	targets := make([]Operation, len(paths))
+	for i := range paths {
+		targets[i] = resolved
+		targets[i].Path = &paths[i]
+		if err := p.checkNestedAccess(targets[i]); err != nil {
+			return err
+		}
+	}
+	// the last match first, so removing array elements does not shift the other matches.
+	for i := len(targets) - 1; i >= 0; i-- {
+		// a match without the last member is not a target.
+		if err := p.applyTarget(o, ext, targets[i], tracker); err != nil && !errors.Is(err, ErrNotExists) {
+			return err
 		}
 	}
 	return nil
 }
 
+func (p *Patch) applyTarget(o *any, ext Extension, op Operation, tracker *indexTracker) error {
+	decoded, err := p.decodeEmbedded(o, op)
+	if err == nil {
+		err = p.applyTracked(o, ext, op, tracker)
+	}
+	if eerr := p.encodeEmbedded(o, decoded); err == nil {
+		err = eerr
+	}
+	return err
+}
+
+func (p *Patch) applyTracked(o *any, ext Extension, op Operation, tracker *indexTracker) error {
+	if tracker == nil {
+		return ext.Apply(p, o, op)
+	}
+	op, err := tracker.translate(p, o, op)
+	if err != nil {
+		return err
+	}
+	before := tracker.before(p, o, op)
+	if err := ext.Apply(p, o, op); err != nil {
+		return err
+	}
+	tracker.after(p, o, op, before)
+	return nil
+}
+
+func (p *Patch) operationError(ext Extension, op Operation, err error) error {
+	desc := p.annotate(p.description(ext, op), op)
+	code := CodeOperationFailed
+	if errors.Is(err, ErrStop) {
+		code = CodeOperationStopped
+	}
+	params := map[string]any{"operation": desc, "extension": fmt.Sprintf("%T", ext)}
+	return p.localize(newError(code, params, err))
+}
+
 type addExtension struct{}
 
 func (addExtension) OP() string {
@@ -566,23 +983,26 @@ func (addExtension) OP() string {
 func (addExtension) Apply(p *Patch, o *any, op Operation) error {
 	var (
 		path  = *op.Path
-		value = *op.Value
-		parts = NewJSONPointer(path)
+		parts = p.pointer(path)
 	)
+	value, err := p.writeValue(path, *op.Value)
+	if err != nil {
+		return err
+	}
 	if parts.IsTheWholeDocument() {
 		*o = value
 		return nil
 	}
-	parent, set, err := p.VisitPath(o, parts.ParentPath()...)
+	parent, set, err := p.visitAddParent(o, parts)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", path, err)
+		return errPathNotExists(path, err)
 	}
 	return p.AddValue(parent, set, parts.LastToken(), value)
 }
 
 func (addExtension) Check(_ *Patch, op Operation) error {
 	if op.Value == nil {
-		return errors.New("operation add must contains a value member")
+		return errMissingMember(opAdd, "value")
 	}
 	return nil
 }
@@ -596,11 +1016,11 @@ func (removeExtension) OP() string {
 func (removeExtension) Apply(p *Patch, o *any, op Operation) error {
 	var (
 		path  = *op.Path
-		parts = NewJSONPointer(path)
+		parts = p.pointer(path)
 	)
 	parent, set, err := p.VisitPath(o, parts.ParentPath()...)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", path, err)
+		return errPathNotExists(path, err)
 	}
 	return p.RemoveValue(parent, set, parts.LastToken())
 }
@@ -618,23 +1038,26 @@ func (replaceExtension) OP() string {
 func (replaceExtension) Apply(p *Patch, o *any, op Operation) error {
 	var (
 		path  = *op.Path
-		value = *op.Value
-		parts = NewJSONPointer(path)
+		parts = p.pointer(path)
 	)
+	value, err := p.writeValue(path, *op.Value)
+	if err != nil {
+		return err
+	}
 	if parts.IsTheWholeDocument() {
 		*o = value
 		return nil
 	}
 	parent, set, err := p.VisitPath(o, parts.ParentPath()...)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", path, err)
+		return errPathNotExists(path, err)
 	}
 	return p.ReplaceValue(parent, set, parts.LastToken(), value)
 }
 
 func (replaceExtension) Check(_ *Patch, op Operation) error {
 	if op.Value == nil {
-		return errors.New("operation replace must contains a value member")
+		return errMissingMember(opReplace, "value")
 	}
 	return nil
 }
@@ -649,21 +1072,25 @@ func (moveExtension) Apply(p *Patch, o *any, op Operation) error {
 	var (
 		path      = *op.Path
 		from      = *op.From
-		parts     = NewJSONPointer(path)
-		fromParts = NewJSONPointer(from)
+		parts     = p.pointer(path)
+		fromParts = p.pointer(from)
 	)
 	fromParent, fromSet, err := p.VisitPath(o, fromParts.ParentPath()...)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", from, err)
+		return errPathNotExists(from, err)
 	}
 	value, _, err := p.visitPathPart(fromParent, fromParts.LastToken())
 	if err != nil {
 		if p.StrictPathExists {
-			return fmt.Errorf("path not exists: %s, err=%w", from, err)
+			return errPathNotExists(from, err)
 		}
 		return nil
 	}
-	if parts.SameParent(fromParts) {
+	if value, err = p.writeValue(path, value); err != nil {
+		return err
+	}
+	// the moved value may be changed by the write hooks, so it can not be moved in place.
+	if parts.SameParent(fromParts) && !p.hooksWrites() {
 		return p.MoveValue(fromParent, fromSet, fromParts.LastToken(), parts.LastToken())
 	}
 	if err := p.RemoveValue(fromParent, fromSet, fromParts.LastToken()); err != nil {
@@ -671,7 +1098,7 @@ func (moveExtension) Apply(p *Patch, o *any, op Operation) error {
 	}
 	parent, set, err := p.VisitPath(o, parts.ParentPath()...)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", path, err)
+		return errPathNotExists(path, err)
 	}
 	err = p.AddValue(parent, set, parts.LastToken(), value)
 	return err
@@ -679,7 +1106,7 @@ func (moveExtension) Apply(p *Patch, o *any, op Operation) error {
 
 func (moveExtension) Check(_ *Patch, op Operation) error {
 	if op.From == nil {
-		return errors.New("operation move must contains a from member")
+		return errMissingMember(opMove, "from")
 	}
 	return nil
 }
@@ -698,23 +1125,26 @@ func (copyExtension) Apply(p *Patch, o *any, op Operation) error {
 	var (
 		path      = *op.Path
 		from      = *op.From
-		parts     = NewJSONPointer(path)
-		fromParts = NewJSONPointer(from)
+		parts     = p.pointer(path)
+		fromParts = p.pointer(from)
 	)
 	parent, set, err := p.VisitPath(o, parts.ParentPath()...)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", path, err)
+		return errPathNotExists(path, err)
 	}
 	value, _, err := p.VisitPath(o, fromParts.Path()...)
 	if err != nil {
-		return fmt.Errorf("path not exists: %s, err=%w", from, err)
+		return errPathNotExists(from, err)
+	}
+	if value, err = p.writeValue(path, deepCopy(value)); err != nil {
+		return err
 	}
-	return p.AddValue(parent, set, parts.LastToken(), deepCopy(value))
+	return p.AddValue(parent, set, parts.LastToken(), value)
 }
 
 func (copyExtension) Check(_ *Patch, op Operation) error {
 	if op.From == nil {
-		return errors.New("operation copy must contains a from member")
+		return errMissingMember(opCopy, "from")
 	}
 	return nil
 }
@@ -733,16 +1163,16 @@ func (testExtension) Apply(p *Patch, o *any, op Operation) error {
 	var (
 		path   = *op.Path
 		expect = *op.Value
-		parts  = NewJSONPointer(path)
+		parts  = p.pointer(path)
 	)
 	value, _, err := p.VisitPath(o, parts.Path()...)
 	if err != nil {
 		if p.StrictPathExists {
-			return fmt.Errorf("path not exists: %s, err=%w", path, err)
+			return errPathNotExists(path, err)
 		}
 		return ErrStop
 	}
-	if reflect.DeepEqual(value, expect) {
+	if EqualAny(value, expect) {
 		return nil
 	}
 	return ErrStop
@@ -750,7 +1180,7 @@ func (testExtension) Apply(p *Patch, o *any, op Operation) error {
 
 func (testExtension) Check(_ *Patch, op Operation) error {
 	if op.Value == nil {
-		return errors.New("operation test must contains a value member")
+		return errMissingMember(opTest, "value")
 	}
 	return nil
 }
@@ -767,8 +1197,58 @@ transformation).
 */
 var unescapeReplace = strings.NewReplacer("~1", "/", "~0", "~")
 
-func unescapePath(path string) string {
-	return unescapeReplace.Replace(path)
+// UnescapeToken returns the reference token of RFC6901 with "~1" and "~0" decoded.
+func UnescapeToken(token string) string {
+	return unescapeReplace.Replace(token)
+}
+
+var escapeReplace = strings.NewReplacer("~", "~0", "/", "~1")
+
+// EscapeToken returns the reference token of RFC6901 with "~" and "/" encoded,
+// so it can be joined into a JSONPointer.
+func EscapeToken(token string) string {
+	return escapeReplace.Replace(token)
+}
+
+// Walk calls fn with every node of doc and its pointer, doc is a value decoded by encoding/json.
+// A node is visited before its children, object members are visited in key order.
+// Walk stops and returns the error if fn returns an error.
+func Walk(doc any, fn func(ptr JSONPointer, value any) error) error {
+	return walk(doc, "", func(ptr string, v any) error {
+		return fn(NewJSONPointer(ptr), v)
+	})
+}
+
+// walk calls fn for o and every node below it in depth-first order.
+// ptr is the json pointer of o, object members are visited in key order.
+func walk(o any, ptr string, fn func(ptr string, v any) error) error {
+	if err := fn(ptr, o); err != nil {
+		return err
+	}
+	switch v := o.(type) {
+	case []any:
+		for i, e := range v {
+			if err := walk(e, ptr+"/"+strconv.Itoa(i), fn); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			if err := walk(v[k], ptr+"/"+EscapeToken(k), fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func deepCopy(o any) any {
@@ -790,6 +1270,19 @@ func deepCopy(o any) any {
 	}
 }
 
+// copyOperations returns a copy of ops whose values are deeply copied.
+func copyOperations(ops []Operation) []Operation {
+	r := make([]Operation, len(ops))
+	for i, op := range ops {
+		r[i] = op
+		if op.Value != nil {
+			v := deepCopy(*op.Value)
+			r[i].Value = &v
+		}
+	}
+	return r
+}
+
 // sliceRemove removes the element at index i
 func sliceRemove(s []any, i int) []any {
 	return append(s[:i], s[i+1:]...)
@@ -4,7 +4,9 @@
 package jsonpatch
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
 	"reflect"
 	"strings"
@@ -144,6 +146,10 @@ func (tc *testCase) New(t *testing.T) *Patch {
 			opts = append(opts, WithStrictPathExists(false))
 		case "SupportNegativeArrayIndex":
 			opts = append(opts, WithSupportNegativeArrayIndex(true))
+		case "SymbolicArrayIndex":
+			opts = append(opts, WithSymbolicArrayIndex(true))
+		case "IntermediateAppend":
+			opts = append(opts, WithIntermediateAppend(true))
 		default:
 			t.Fatal("unknown option", v)
 		}
@@ -192,6 +198,227 @@ func testFile(t *testing.T, filename string) {
 	}
 }
 
+func TestApplyTo(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+	ops := mustOperations(t, `[{"op":"add","path":"/b","value":[1,2]}]`)
+	for _, p := range []*Patch{New(), New(WithRawEngine(true)), New(WithCache(NewLRUCache(1)))} {
+		var buf bytes.Buffer
+		buf.WriteString("prefix ")
+		if err := p.ApplyTo(&buf, doc, ops); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := p.Apply(doc, ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "prefix "+string(expected) {
+			t.Fatal("expected", string(expected), "got", buf.String())
+		}
+		buf.Reset()
+		err = p.ApplyTo(&buf, doc, mustOperations(t, `[{"op":"test","path":"/a","value":2}]`))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if buf.Len() != 0 {
+			t.Fatal("expected nothing written, got", buf.String())
+		}
+	}
+}
+
+func TestMoveToArrayEnd(t *testing.T) {
+	cases := []struct {
+		doc    string
+		ops    string
+		expect string
+	}{
+		{`{"a":[1,2,3]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[2,3,1]}`},
+		{`{"a":[1,2,3]}`, `[{"op":"move","from":"/a/2","path":"/a/-"}]`, `{"a":[1,2,3]}`},
+		{`{"a":[1]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[1]}`},
+	}
+	for _, c := range cases {
+		out, err := New().Apply([]byte(c.doc), mustOperations(t, c.ops))
+		if err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if ok, err := Equal(out, []byte(c.expect)); err != nil || !ok {
+			t.Fatal(c.ops, "expected", c.expect, "got", string(out), err)
+		}
+	}
+	// a numeric index equal to the length is out of range once the element is removed.
+	for _, ops := range []string{
+		`[{"op":"move","from":"/a/0","path":"/a/3"}]`,
+		`[{"op":"move","from":"/a/3","path":"/a/0"}]`,
+		`[{"op":"move","from":"/a/-","path":"/a/0"}]`,
+	} {
+		for _, p := range []*Patch{New(), New(WithRFC6902Strict()), New(WithRawEngine(true))} {
+			if out, err := p.Apply([]byte(`{"a":[1,2,3]}`), mustOperations(t, ops)); err == nil {
+				t.Fatal(ops, "expected error, got", string(out))
+			}
+		}
+	}
+}
+
+func TestJSONPointerResolve(t *testing.T) {
+	doc := []byte(`{"a":{"b/c":[1,{"d":null}]},"":2}`)
+	cases := []struct {
+		pointer string
+		expect  any
+	}{
+		{"", map[string]any{"a": map[string]any{"b/c": []any{1.0, map[string]any{"d": nil}}}, "": 2.0}},
+		{"/a/b~1c/0", 1.0},
+		{"/a/b~1c/1/d", nil},
+		{"/", 2.0},
+	}
+	for _, c := range cases {
+		v, err := NewJSONPointer(c.pointer).ResolveBytes(doc)
+		if err != nil {
+			t.Fatal(c.pointer, err)
+		}
+		if !reflect.DeepEqual(v, c.expect) {
+			t.Fatal(c.pointer, "expected", c.expect, "got", v)
+		}
+	}
+	for _, pointer := range []string{"/x", "/a/b~1c/2", "/a/b~1c/-", "/a/b~1c/0/x"} {
+		if _, err := NewJSONPointer(pointer).ResolveBytes(doc); !errors.Is(err, ErrNotExists) {
+			t.Fatal(pointer, "expected not exists, got", err)
+		}
+	}
+	if _, err := NewJSONPointer("a").ResolveBytes(doc); err == nil {
+		t.Fatal("expected error of bad pointer")
+	}
+}
+
+func TestJSONPointerSetDelete(t *testing.T) {
+	var doc any = map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{}}
+	sets := []struct {
+		pointer string
+		value   any
+	}{
+		{"/b/c~1d", 1.0},
+		{"/b/c~1d", 2.0},
+		{"/a/0", 0.0},
+		{"/a/-", 3.0},
+		{"/a/3", 4.0},
+	}
+	for _, s := range sets {
+		if err := NewJSONPointer(s.pointer).Set(&doc, s.value); err != nil {
+			t.Fatal(s.pointer, err)
+		}
+	}
+	expect := map[string]any{"a": []any{0.0, 2.0, 3.0, 4.0}, "b": map[string]any{"c/d": 2.0}}
+	if !reflect.DeepEqual(doc, expect) {
+		t.Fatal("expected", expect, "got", doc)
+	}
+	for _, pointer := range []string{"/a/0", "/b/c~1d"} {
+		if err := NewJSONPointer(pointer).Delete(&doc); err != nil {
+			t.Fatal(pointer, err)
+		}
+	}
+	expect = map[string]any{"a": []any{2.0, 3.0, 4.0}, "b": map[string]any{}}
+	if !reflect.DeepEqual(doc, expect) {
+		t.Fatal("expected", expect, "got", doc)
+	}
+	for _, pointer := range []string{"/x", "/a/3", "/x/y"} {
+		if err := NewJSONPointer(pointer).Delete(&doc); !errors.Is(err, ErrNotExists) {
+			t.Fatal(pointer, "expected not exists, got", err)
+		}
+	}
+	for _, pointer := range []string{"/x/y", "/a/5", "a"} {
+		if err := NewJSONPointer(pointer).Set(&doc, 1.0); err == nil {
+			t.Fatal(pointer, "expected error")
+		}
+	}
+	if err := NewJSONPointer("").Delete(&doc); err == nil {
+		t.Fatal("expected error of deleting the whole document")
+	}
+	if err := NewJSONPointer("").Set(&doc, "x"); err != nil || doc != "x" {
+		t.Fatal("bad set of the whole document", doc, err)
+	}
+}
+
+func TestNewJSONPointerFromTokens(t *testing.T) {
+	p := NewJSONPointerFromTokens("a/b", "c~d")
+	if p.String() != "/a~1b/c~0d" {
+		t.Fatal("bad pointer", p.String())
+	}
+	p = p.AppendToken("~1").AppendToken("")
+	if p.String() != "/a~1b/c~0d/~01/" {
+		t.Fatal("bad pointer", p.String())
+	}
+	if expect := []string{"a/b", "c~d", "~1", ""}; !reflect.DeepEqual(p.Path(), expect) {
+		t.Fatal("expected", expect, "got", p.Path())
+	}
+	if p := NewJSONPointerFromTokens(); !p.IsTheWholeDocument() {
+		t.Fatal("expected the whole document", p.String())
+	}
+}
+
+func TestJSONPointerNavigation(t *testing.T) {
+	p := NewJSONPointer("/a~1b/0")
+	if got := p.Parent().String(); got != "/a~1b" {
+		t.Fatal("bad parent", got)
+	}
+	if got := p.Parent().Parent(); !got.IsTheWholeDocument() || !got.Parent().IsTheWholeDocument() {
+		t.Fatal("bad parent of the top level", got)
+	}
+	sibling := p.Parent().Join("1", "c/d")
+	if got := sibling.String(); got != "/a~1b/1/c~1d" {
+		t.Fatal("bad sibling", got)
+	}
+	if got := NewJSONPointer(sibling.String()); !reflect.DeepEqual(got.Path(), sibling.Path()) {
+		t.Fatal("bad round trip", got)
+	}
+	if got := NewJSONPointer("").Join().String(); got != "" {
+		t.Fatal("bad join", got)
+	}
+}
+
+func TestJSONPointerCheckStrict(t *testing.T) {
+	for _, s := range []string{"", "/", "/a~0b/~1", "/~01/x"} {
+		if err := NewJSONPointer(s).CheckStrict(); err != nil {
+			t.Fatal(s, err)
+		}
+	}
+	cases := []struct {
+		pointer string
+		index   int
+	}{
+		{"/a~2", 0},
+		{"/a/b~", 1},
+		{"/a/~0/c~x~1", 2},
+	}
+	for _, c := range cases {
+		err := NewJSONPointer(c.pointer).CheckStrict()
+		var e *Error
+		if !errors.As(err, &e) || e.Code != CodeBadEscape || e.Params["index"] != c.index {
+			t.Fatal(c.pointer, "expected bad escape at", c.index, "got", err)
+		}
+	}
+	if err := NewJSONPointer("a").CheckStrict(); err == nil {
+		t.Fatal("expected error of bad pointer")
+	}
+}
+
+func TestEscapeToken(t *testing.T) {
+	cases := []struct {
+		token, escaped string
+	}{
+		{"a", "a"},
+		{"a/b", "a~1b"},
+		{"m~n", "m~0n"},
+		{"~1", "~01"},
+		{"/~", "~1~0"},
+	}
+	for _, c := range cases {
+		if got := EscapeToken(c.token); got != c.escaped {
+			t.Fatal(c.token, "expected", c.escaped, "got", got)
+		}
+		if got := UnescapeToken(c.escaped); got != c.token {
+			t.Fatal(c.escaped, "expected", c.token, "got", got)
+		}
+	}
+}
+
 func TestSpec(t *testing.T) {
 	testFile(t, "json-patch-tests/spec_tests.json")
 }
@@ -203,3 +430,36 @@ func TestTestsJSON(t *testing.T) {
 func TestExtend(t *testing.T) {
 	testFile(t, "tests.json")
 }
+
+func TestWalk(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"b":[1,{"c/d":2}],"a":null}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	err := Walk(doc, func(ptr JSONPointer, value any) error {
+		paths = append(paths, ptr.String())
+		if v, err := ptr.Resolve(doc); err != nil || !EqualAny(v, value) {
+			t.Fatal("unexpected value", ptr, value, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{"", "/a", "/b", "/b/0", "/b/1", "/b/1/c~1d"}) {
+		t.Fatal("unexpected paths", paths)
+	}
+	stop := errors.New("stop")
+	paths = nil
+	err = Walk(doc, func(ptr JSONPointer, _ any) error {
+		paths = append(paths, ptr.String())
+		if ptr.String() == "/b" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(paths) != 3 {
+		t.Fatal("walk must stop at the error", err, paths)
+	}
+}
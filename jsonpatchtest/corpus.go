@@ -0,0 +1,170 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatchtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// Case is a test case in the json-patch-tests format.
+// See https://github.com/json-patch/json-patch-tests.
+type Case struct {
+	Comment  string                `json:"comment"`
+	Doc      any                   `json:"doc"`
+	Patch    []jsonpatch.Operation `json:"patch"`
+	Expected any                   `json:"expected"`
+	Error    string                `json:"error"`
+	Disabled bool                  `json:"disabled"`
+	Options  []string              `json:"options"`
+
+	// HasExpected is true if the case contains an expected member.
+	HasExpected bool `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Case) UnmarshalJSON(data []byte) error {
+	type plain Case
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	_, c.HasExpected = members["expected"]
+	return nil
+}
+
+// LoadCorpus loads test cases from r.
+func LoadCorpus(r io.Reader) ([]Case, error) {
+	var cases []Case
+	if err := json.NewDecoder(r).Decode(&cases); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// LoadCorpusFile loads test cases from the named file.
+func LoadCorpusFile(name string) ([]Case, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadCorpus(f)
+}
+
+// Filter reports whether a test case should be kept.
+type Filter func(c Case) bool
+
+// FilterCases returns the cases that match all filters.
+func FilterCases(cases []Case, filters ...Filter) []Case {
+	var r []Case
+next:
+	for _, c := range cases {
+		for _, f := range filters {
+			if !f(c) {
+				continue next
+			}
+		}
+		r = append(r, c)
+	}
+	return r
+}
+
+// ByComment keeps the cases whose comment contains substr.
+func ByComment(substr string) Filter {
+	return func(c Case) bool {
+		return strings.Contains(c.Comment, substr)
+	}
+}
+
+// ByOptions keeps the cases that contains all the options.
+func ByOptions(names ...string) Filter {
+	return func(c Case) bool {
+		for _, n := range names {
+			if !contains(c.Options, n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Enabled keeps the cases that are not disabled.
+func Enabled() Filter {
+	return func(c Case) bool {
+		return !c.Disabled
+	}
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var options = map[string]jsonpatch.Option{
+	"NoStrictPathExists":        jsonpatch.WithStrictPathExists(false),
+	"SupportNegativeArrayIndex": jsonpatch.WithSupportNegativeArrayIndex(true),
+	"SymbolicArrayIndex":        jsonpatch.WithSymbolicArrayIndex(true),
+	"IntermediateAppend":        jsonpatch.WithIntermediateAppend(true),
+}
+
+// ParseOption returns the jsonpatch option of the name used in the options member of a case.
+func ParseOption(name string) (jsonpatch.Option, error) {
+	o, ok := options[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown option: %s", name)
+	}
+	return o, nil
+}
+
+// PatchOptions returns the jsonpatch options of the case.
+func (c Case) PatchOptions() ([]jsonpatch.Option, error) {
+	opts := make([]jsonpatch.Option, 0, len(c.Options))
+	for _, n := range c.Options {
+		o, err := ParseOption(n)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, o)
+	}
+	return opts, nil
+}
+
+// ErrUnexpectedSuccess is returned by Case.Run if the case expects an error but the patch succeeded.
+var ErrUnexpectedSuccess = errors.New("expected error but patch succeeded")
+
+// Run applies the case with p and returns nil if the case passes.
+// The document of the case is not modified.
+func (c Case) Run(p *jsonpatch.Patch) error {
+	got, _, err := p.Simulate(c.Doc, c.Patch)
+	if err != nil {
+		if c.Error != "" {
+			return nil
+		}
+		return err
+	}
+	if c.Error != "" {
+		return fmt.Errorf("%w: %s", ErrUnexpectedSuccess, c.Error)
+	}
+	if c.HasExpected && !reflect.DeepEqual(got, c.Expected) {
+		path, _ := FirstDiff(c.Expected, got)
+		return fmt.Errorf("document mismatch at %q: expected %s, got %s", path, jsonString(c.Expected), jsonString(got))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatchtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func TestCorpus(t *testing.T) {
+	cases, err := LoadCorpusFile("../tests.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no cases loaded")
+	}
+	for _, c := range FilterCases(cases, Enabled()) {
+		opts, err := c.PatchOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Run(jsonpatch.New(opts...)); err != nil {
+			t.Fatal(c.Comment, err)
+		}
+	}
+}
+
+func TestFilterCases(t *testing.T) {
+	cases, err := LoadCorpus(strings.NewReader(`[
+		{"comment":"add one","doc":{},"patch":[{"op":"add","path":"/a","value":1}],"expected":{"a":1}},
+		{"comment":"bad op","doc":{},"patch":[{"op":"bad","path":"/a"}],"error":"unknown op","options":["NoStrictPathExists"]},
+		{"comment":"disabled","doc":{},"patch":[],"disabled":true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(FilterCases(cases, Enabled())); n != 2 {
+		t.Fatal("expected 2 enabled cases, got", n)
+	}
+	if n := len(FilterCases(cases, ByComment("add"))); n != 1 {
+		t.Fatal("expected 1 case, got", n)
+	}
+	got := FilterCases(cases, ByOptions("NoStrictPathExists"))
+	if len(got) != 1 || got[0].Comment != "bad op" {
+		t.Fatal("bad filter result", got)
+	}
+	if !cases[0].HasExpected || cases[1].HasExpected {
+		t.Fatal("bad expected member detection")
+	}
+	if err := got[0].Run(jsonpatch.New()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package jsonpatchtest provides utilities for testing code that uses jsonpatch.
+package jsonpatchtest
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// AssertPatched applies patchJSON to docJSON and fails the test if the result is not expectedJSON.
+// The failure message shows the first divergent path of the documents.
+func AssertPatched(t testing.TB, docJSON, patchJSON, expectedJSON string, opts ...jsonpatch.Option) {
+	t.Helper()
+	var doc, expected any
+	if err := json.Unmarshal([]byte(docJSON), &doc); err != nil {
+		t.Fatalf("bad document: %v", err)
+	}
+	if err := json.Unmarshal([]byte(expectedJSON), &expected); err != nil {
+		t.Fatalf("bad expected document: %v", err)
+	}
+	var ops []jsonpatch.Operation
+	if err := json.Unmarshal([]byte(patchJSON), &ops); err != nil {
+		t.Fatalf("bad patch: %v", err)
+	}
+	if err := jsonpatch.New(opts...).ApplyAny(&doc, ops); err != nil {
+		t.Fatalf("apply patch failed: %v", err)
+	}
+	if path, ok := FirstDiff(expected, doc); ok {
+		e, _ := lookup(expected, path)
+		g, _ := lookup(doc, path)
+		t.Fatalf("document mismatch at %q: expected %s, got %s\nexpected: %s\ngot:      %s",
+			path, jsonString(e), jsonString(g), jsonString(expected), jsonString(doc))
+	}
+}
+
+// FirstDiff returns the json pointer of the first divergent node of a and b.
+// Object members are compared in key order.
+// It returns false if a and b are equal.
+func FirstDiff(a, b any) (string, bool) {
+	return firstDiff(a, b, "")
+}
+
+func firstDiff(a, b any, ptr string) (string, bool) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			return ptr, true
+		}
+		for _, k := range unionKeys(av, bv) {
+			ae, aok := av[k]
+			be, bok := bv[k]
+			if aok != bok {
+				return ptr + "/" + jsonpatch.EscapeToken(k), true
+			}
+			if p, ok := firstDiff(ae, be, ptr+"/"+jsonpatch.EscapeToken(k)); ok {
+				return p, true
+			}
+		}
+		return "", false
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			return ptr, true
+		}
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if p, ok := firstDiff(av[i], bv[i], ptr+"/"+strconv.Itoa(i)); ok {
+				return p, true
+			}
+		}
+		if len(av) != len(bv) {
+			n := len(av)
+			if len(bv) < n {
+				n = len(bv)
+			}
+			return ptr + "/" + strconv.Itoa(n), true
+		}
+		return "", false
+	default:
+		if reflect.DeepEqual(a, b) {
+			return "", false
+		}
+		return ptr, true
+	}
+}
+
+func unionKeys(a, b map[string]any) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func lookup(doc any, ptr string) (any, bool) {
+	v, _, err := jsonpatch.New().VisitPath(&doc, jsonpatch.NewJSONPointer(ptr).Path()...)
+	return v, err == nil
+}
+
+func jsonString(o any) string {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
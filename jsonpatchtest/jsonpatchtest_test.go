@@ -0,0 +1,45 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatchtest
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAssertPatched(t *testing.T) {
+	AssertPatched(t,
+		`{"a":[1,2],"b":{"c":"d"}}`,
+		`[{"op":"add","path":"/a/-","value":3},{"op":"remove","path":"/b/c"}]`,
+		`{"a":[1,2,3],"b":{}}`,
+	)
+}
+
+func TestFirstDiff(t *testing.T) {
+	cases := []struct {
+		a, b   string
+		expect string
+		diff   bool
+	}{
+		{a: `{"a":1}`, b: `{"a":1}`},
+		{a: `{"a":[1,2]}`, b: `{"a":[1,3]}`, expect: "/a/1", diff: true},
+		{a: `{"a":[1,2]}`, b: `{"a":[1]}`, expect: "/a/1", diff: true},
+		{a: `{"a/b":1}`, b: `{"a/b":2}`, expect: "/a~1b", diff: true},
+		{a: `{"a":1}`, b: `{"a":1,"b":2}`, expect: "/b", diff: true},
+		{a: `{"a":1}`, b: `[1]`, expect: "", diff: true},
+	}
+	for _, c := range cases {
+		var a, b any
+		if err := json.Unmarshal([]byte(c.a), &a); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(c.b), &b); err != nil {
+			t.Fatal(err)
+		}
+		got, diff := FirstDiff(a, b)
+		if got != c.expect || diff != c.diff {
+			t.Fatal("expected", c.expect, c.diff, "got", got, diff)
+		}
+	}
+}
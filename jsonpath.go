@@ -0,0 +1,478 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrBadJSONPath is returned if a path is not a valid JSONPath expression.
+var ErrBadJSONPath = errors.New("bad jsonpath")
+
+// WithJSONPathPaths set the JSONPathPaths option.
+// The default value is false.
+// If JSONPathPaths is true, path and from starting with "$" are JSONPath expressions,
+// e.g. "$.store.book[?(@.price<10)].title".
+// The expression is resolved against the document when the operation is applied,
+// an operation is applied to every matched location as the matches of "*", see WithWildcards,
+// and from must match exactly one location.
+//
+// The supported syntax is:
+//
+//	$                 the root
+//	.name ['name']    a member, the last member may not exist yet
+//	[0] [-1]          an array element, a negative index counts from the end
+//	.* [*]            every member or element
+//	[0,1] ['a','b']   a union of indices or names
+//	[1:3]             a slice of an array
+//	..                recursive descent, e.g. $..name
+//	[?(@.a.b)]        members or elements having a.b
+//	[?(@.price<10)]   members or elements compared with a literal by ==, !=, <, <=, > or >=
+func WithJSONPathPaths(on bool) Option {
+	return func(o *Patch) {
+		o.JSONPathPaths = on
+	}
+}
+
+func (p *Patch) isJSONPath(path string) bool {
+	return p.JSONPathPaths && strings.HasPrefix(path, "$")
+}
+
+// checkPath returns an error if path is not a valid json pointer or JSONPath expression.
+func (p *Patch) checkPath(path string) error {
+	if p.isJSONPath(path) {
+		_, err := compileJSONPath(path)
+		return err
+	}
+	return NewJSONPointer(path).Check()
+}
+
+// resolveFrom returns op with its JSONPath or selector from resolved to a json pointer.
+func (p *Patch) resolveFrom(doc any, op Operation) (Operation, error) {
+	if op.From == nil || !p.hasPattern(*op.From) {
+		return op, nil
+	}
+	paths, err := p.ExpandPath(doc, *op.From)
+	if err != nil {
+		return op, err
+	}
+	switch len(paths) {
+	case 0:
+		return op, errPathNotExists(*op.From, ErrNotExists)
+	case 1:
+		op.From = &paths[0]
+		return op, nil
+	default:
+		return op, newError(CodeInvalidOperation, map[string]any{"operation": p.Describe(op)}, errPatternFrom)
+	}
+}
+
+// jsonPath is a compiled JSONPath expression.
+type jsonPath []jsonPathStep
+
+type jsonPathStep struct {
+	recursive bool
+	selectors []jsonPathSelector
+}
+
+// jsonPathSelector calls fn with the selected children of node,
+// create reports whether a member not exists yet is selected.
+type jsonPathSelector interface {
+	selectFrom(node any, prefix string, create bool, fn func(child any, path string))
+}
+
+type jsonPathNode struct {
+	value any
+	path  string
+}
+
+func compileJSONPath(s string) (jsonPath, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("%w: must start with $: %s", ErrBadJSONPath, s)
+	}
+	var r jsonPath
+	for i := 1; i < len(s); {
+		step, next, err := parseJSONPathStep(s, i)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s at %d: %v", ErrBadJSONPath, s, i, err)
+		}
+		r = append(r, step)
+		i = next
+	}
+	return r, nil
+}
+
+func parseJSONPathStep(s string, i int) (jsonPathStep, int, error) {
+	var step jsonPathStep
+	switch {
+	case strings.HasPrefix(s[i:], ".."):
+		step.recursive = true
+		i += 2
+		if i < len(s) && s[i] == '[' {
+			return parseJSONPathBracket(s, i, step)
+		}
+	case s[i] == '.':
+		i++
+	case s[i] == '[':
+		return parseJSONPathBracket(s, i, step)
+	default:
+		return step, 0, fmt.Errorf("unexpected %q", s[i])
+	}
+	end := i
+	for end < len(s) && s[end] != '.' && s[end] != '[' {
+		end++
+	}
+	switch name := s[i:end]; name {
+	case "":
+		return step, 0, errors.New("empty name")
+	case "*":
+		step.selectors = []jsonPathSelector{jsonPathWildcard{}}
+	default:
+		step.selectors = []jsonPathSelector{jsonPathName(name)}
+	}
+	return step, end, nil
+}
+
+func parseJSONPathBracket(s string, i int, step jsonPathStep) (jsonPathStep, int, error) {
+	end := jsonPathBracketEnd(s, i)
+	if end < 0 {
+		return step, 0, errors.New("unclosed [")
+	}
+	inner := strings.TrimSpace(s[i+1 : end])
+	if strings.HasPrefix(inner, "?") {
+		f, err := parseJSONPathFilter(inner[1:])
+		if err != nil {
+			return step, 0, err
+		}
+		step.selectors = []jsonPathSelector{f}
+		return step, end + 1, nil
+	}
+	for _, part := range splitJSONPathUnion(inner) {
+		sel, err := parseJSONPathSelector(strings.TrimSpace(part))
+		if err != nil {
+			return step, 0, err
+		}
+		step.selectors = append(step.selectors, sel)
+	}
+	return step, end + 1, nil
+}
+
+// jsonPathBracketEnd returns the offset of the "]" closes the "[" at i, or -1.
+func jsonPathBracketEnd(s string, i int) int {
+	var (
+		quote byte
+		depth int
+	)
+	for j := i + 1; j < len(s); j++ {
+		c := s[j]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				j++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ']' && depth == 0:
+			return j
+		}
+	}
+	return -1
+}
+
+func splitJSONPathUnion(s string) []string {
+	var (
+		r     []string
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			r = append(r, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(r, s[start:])
+}
+
+func parseJSONPathSelector(s string) (jsonPathSelector, error) {
+	switch {
+	case s == "*":
+		return jsonPathWildcard{}, nil
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		name, err := unquoteJSONPath(s)
+		return jsonPathName(name), err
+	case strings.Contains(s, ":"):
+		return parseJSONPathSlice(s)
+	default:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("bad selector %q", s)
+		}
+		return jsonPathIndex(i), nil
+	}
+}
+
+func unquoteJSONPath(s string) (string, error) {
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+func parseJSONPathSlice(s string) (jsonPathSelector, error) {
+	startText, endText, _ := strings.Cut(s, ":")
+	var r jsonPathSlice
+	for _, v := range []struct {
+		text string
+		to   **int
+	}{{startText, &r.start}, {endText, &r.end}} {
+		text := strings.TrimSpace(v.text)
+		if text == "" {
+			continue
+		}
+		i, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("bad slice %q", s)
+		}
+		*v.to = &i
+	}
+	return r, nil
+}
+
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseJSONPathFilter(s string) (jsonPathSelector, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("filter must be enclosed by (): %s", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	var f jsonPathFilter
+	left := s
+	for _, op := range jsonPathOperators {
+		if l, r, ok := strings.Cut(s, op); ok {
+			left, f.op = strings.TrimSpace(l), op
+			v, err := parseJSONPathLiteral(strings.TrimSpace(r))
+			if err != nil {
+				return nil, err
+			}
+			f.value = v
+			break
+		}
+	}
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("filter must start with @: %s", s)
+	}
+	path, err := compileJSONPath("$" + left[1:])
+	if err != nil {
+		return nil, err
+	}
+	f.path = path
+	return f, nil
+}
+
+func parseJSONPathLiteral(s string) (any, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return unquoteJSONPath(s)
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("bad literal %q", s)
+	}
+	return v, nil
+}
+
+// eval returns the nodes matched by jp, create reports whether the last member may not exist yet.
+func (jp jsonPath) eval(doc any, create bool) []jsonPathNode {
+	nodes := []jsonPathNode{{value: doc}}
+	for i, step := range jp {
+		if step.recursive {
+			nodes = jsonPathDescendants(nodes)
+		}
+		last := create && i == len(jp)-1 && !step.recursive
+		var next []jsonPathNode
+		for _, n := range nodes {
+			for _, sel := range step.selectors {
+				sel.selectFrom(n.value, n.path, last, func(child any, path string) {
+					next = append(next, jsonPathNode{value: child, path: path})
+				})
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// pattern returns the json pointer tokens matching every location jp may select,
+// a step selecting more than a member is "*" and a recursive descent ends the tokens with "**".
+func (jp jsonPath) pattern() []string {
+	var r []string
+	for _, step := range jp {
+		if step.recursive {
+			return append(r, "**")
+		}
+		name, ok := step.selectors[0].(jsonPathName)
+		if ok && len(step.selectors) == 1 {
+			r = append(r, string(name))
+		} else {
+			r = append(r, "*")
+		}
+	}
+	return r
+}
+
+// jsonPathDescendants returns nodes and all their descendants in document order.
+func jsonPathDescendants(nodes []jsonPathNode) []jsonPathNode {
+	var r []jsonPathNode
+	for _, n := range nodes {
+		_ = walk(n.value, n.path, func(ptr string, v any) error {
+			r = append(r, jsonPathNode{value: v, path: ptr})
+			return nil
+		})
+	}
+	return r
+}
+
+type jsonPathName string
+
+func (s jsonPathName) selectFrom(node any, prefix string, create bool, fn func(child any, path string)) {
+	m, ok := node.(map[string]any)
+	if !ok {
+		return
+	}
+	if v, ok := m[string(s)]; ok || create {
+		fn(v, prefix+"/"+EscapeToken(string(s)))
+	}
+}
+
+type jsonPathIndex int
+
+func (s jsonPathIndex) selectFrom(node any, prefix string, _ bool, fn func(child any, path string)) {
+	a, ok := node.([]any)
+	if !ok {
+		return
+	}
+	i := int(s)
+	if i < 0 {
+		i += len(a)
+	}
+	if i >= 0 && i < len(a) {
+		fn(a[i], prefix+"/"+strconv.Itoa(i))
+	}
+}
+
+type jsonPathWildcard struct{}
+
+func (jsonPathWildcard) selectFrom(node any, prefix string, _ bool, fn func(child any, path string)) {
+	eachChild(node, prefix, fn)
+}
+
+type jsonPathSlice struct {
+	start, end *int
+}
+
+func (s jsonPathSlice) selectFrom(node any, prefix string, _ bool, fn func(child any, path string)) {
+	a, ok := node.([]any)
+	if !ok {
+		return
+	}
+	bound := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += len(a)
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > len(a) {
+			return len(a)
+		}
+		return i
+	}
+	for i := bound(s.start, 0); i < bound(s.end, len(a)); i++ {
+		fn(a[i], prefix+"/"+strconv.Itoa(i))
+	}
+}
+
+type jsonPathFilter struct {
+	path  jsonPath
+	op    string
+	value any
+}
+
+func (s jsonPathFilter) selectFrom(node any, prefix string, _ bool, fn func(child any, path string)) {
+	eachChild(node, prefix, func(child any, path string) {
+		for _, n := range s.path.eval(child, false) {
+			if s.op == "" || compareJSONPath(n.value, s.op, s.value) {
+				fn(child, path)
+				return
+			}
+		}
+	})
+}
+
+func compareJSONPath(a any, op string, b any) bool {
+	switch op {
+	case "==":
+		return EqualAny(a, b)
+	case "!=":
+		return !EqualAny(a, b)
+	}
+	var c int
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(x, y)
+	default:
+		return false
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
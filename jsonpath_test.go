@@ -0,0 +1,88 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const storeDocument = `{"store":{"book":[
+	{"title":"a","price":8.95,"isbn":"1"},
+	{"title":"b","price":12.99},
+	{"title":"c","price":8.99,"isbn":"2"}
+],"bicycle":{"color":"red","price":19.95}}}`
+
+func TestExpandJSONPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(storeDocument), &doc); err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithJSONPathPaths(true))
+	cases := []struct {
+		path   string
+		expect []string
+	}{
+		{"$", []string{""}},
+		{"$.store.bicycle.color", []string{"/store/bicycle/color"}},
+		{"$['store']['bicycle'].size", []string{"/store/bicycle/size"}},
+		{"$.store.book[0].title", []string{"/store/book/0/title"}},
+		{"$.store.book[-1]", []string{"/store/book/2"}},
+		{"$.store.book[0,2].title", []string{"/store/book/0/title", "/store/book/2/title"}},
+		{"$.store.book[1:].title", []string{"/store/book/1/title", "/store/book/2/title"}},
+		{"$.store.book[*].isbn", []string{"/store/book/0/isbn", "/store/book/1/isbn", "/store/book/2/isbn"}},
+		{"$.store.book[?(@.isbn)].title", []string{"/store/book/0/title", "/store/book/2/title"}},
+		{"$.store.book[?(@.price<10)].title", []string{"/store/book/0/title", "/store/book/2/title"}},
+		{"$.store.book[?(@.title=='b')]", []string{"/store/book/1"}},
+		{"$..price", []string{"/store/bicycle/price", "/store/book/0/price", "/store/book/1/price", "/store/book/2/price"}},
+		{"$.store.*.color", []string{"/store/bicycle/color"}},
+		{"$.nothing.title", nil},
+	}
+	for _, c := range cases {
+		paths, err := p.ExpandPath(doc, c.path)
+		if err != nil {
+			t.Fatal(c.path, err)
+		}
+		if len(paths) != len(c.expect) || (len(paths) > 0 && !reflect.DeepEqual(paths, c.expect)) {
+			t.Fatal(c.path, "unexpected paths", paths)
+		}
+	}
+	for _, path := range []string{"$.", "$[", "$[x]", "$[?(price<1)]", "$[?(@.a==x)]", "$a"} {
+		if _, err := p.ExpandPath(doc, path); !errors.Is(err, ErrBadJSONPath) {
+			t.Fatal(path, "expected bad jsonpath, got", err)
+		}
+	}
+}
+
+func TestJSONPathPaths(t *testing.T) {
+	p := New(WithJSONPathPaths(true))
+	b, err := p.Apply([]byte(storeDocument), mustOperations(t, `[
+		{"op":"replace","path":"$.store.book[?(@.price<10)].price","value":10},
+		{"op":"remove","path":"$..isbn"},
+		{"op":"copy","from":"$.store.bicycle.color","path":"/store/color"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"store":{"book":[{"title":"a","price":10},{"title":"b","price":12.99},{"title":"c","price":10}],
+		"bicycle":{"color":"red","price":19.95},"color":"red"}}`
+	if ok, err := Equal(b, []byte(expect)); err != nil || !ok {
+		t.Fatal("unexpected document", string(b))
+	}
+
+	_, err = p.Apply([]byte(storeDocument), mustOperations(t, `[{"op":"copy","from":"$..price","path":"/a"}]`))
+	if !errors.Is(err, errPatternFrom) {
+		t.Fatal("expected from error, got", err)
+	}
+	_, err = p.Apply([]byte(storeDocument), mustOperations(t, `[{"op":"remove","path":"$.store[?(@"}]`))
+	if !errors.Is(err, ErrBadJSONPath) {
+		t.Fatal("expected bad jsonpath, got", err)
+	}
+	_, err = New().Apply([]byte(storeDocument), mustOperations(t, `[{"op":"remove","path":"$.store"}]`))
+	if err == nil {
+		t.Fatal("jsonpath must be rejected without JSONPathPaths")
+	}
+}
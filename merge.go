@@ -0,0 +1,103 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// ApplyMergePatch applies the json merge patch introduced in RFC7386 to the document b.
+// Members of the patch with null values are removed from the document
+// and objects are merged recursively. The output is encoded like Apply.
+// If p has an ownership or a policy, the json patch transforms the document into the output
+// is checked by them.
+func (p *Patch) ApplyMergePatch(b, mergePatch []byte) ([]byte, error) {
+	var doc, patch any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(mergePatch, &patch); err != nil {
+		return nil, err
+	}
+	var original any
+	if p.ownership != nil || p.policy != nil {
+		original = deepCopy(doc)
+	}
+	doc = MergePatch(doc, patch)
+	if p.ownership != nil || p.policy != nil {
+		if err := p.checkAccess(CreatePatchAny(original, doc)); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if err := p.EncodeOptions().encode(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MergePatch applies the decoded json merge patch to target and returns the result.
+// target may be modified, patch is not.
+func MergePatch(target, patch any) any {
+	m, ok := patch.(map[string]any)
+	if !ok {
+		return deepCopy(patch)
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range m {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = MergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// CreateMergePatch returns the minimal json merge patch transforms the original json document into the modified one.
+// Null members of objects in modified can't be represented by merge patches, they are removed by the patch.
+func CreateMergePatch(original, modified []byte) ([]byte, error) {
+	var a, b any
+	if err := json.Unmarshal(original, &a); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(modified, &b); err != nil {
+		return nil, err
+	}
+	return canonicalJSON(CreateMergePatchAny(a, b))
+}
+
+// CreateMergePatchAny returns the minimal decoded json merge patch transforms original into modified.
+func CreateMergePatchAny(original, modified any) any {
+	a, ok := original.(map[string]any)
+	if !ok {
+		return deepCopy(modified)
+	}
+	b, ok := modified.(map[string]any)
+	if !ok {
+		return deepCopy(modified)
+	}
+	patch := map[string]any{}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, v := range b {
+		old, ok := a[k]
+		switch {
+		case !ok:
+			patch[k] = deepCopy(v)
+		case !reflect.DeepEqual(old, v):
+			patch[k] = CreateMergePatchAny(old, v)
+		}
+	}
+	return patch
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	// test cases of RFC7386 Appendix A.
+	cases := [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	p := New()
+	for _, c := range cases {
+		b, err := p.ApplyMergePatch([]byte(c[0]), []byte(c[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c[2]+"\n" {
+			t.Fatal(c[0], c[1], "expected", c[2], "got", string(b))
+		}
+	}
+	if _, err := p.ApplyMergePatch([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	cases := [][3]string{
+		{`{"a":"b","c":{"d":"e","f":"g"},"h":[1]}`, `{"a":"z","c":{"d":"e"},"h":[1],"i":{"j":1}}`, `{"a":"z","c":{"f":null},"i":{"j":1}}`},
+		{`{"a":1}`, `{"a":1}`, `{}`},
+		{`{"a":[1,2]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`[1]`, `{"a":1}`, `{"a":1}`},
+		{`{"a":1}`, `"x"`, `"x"`},
+		{`{"a":{"b":1}}`, `{"a":2}`, `{"a":2}`},
+	}
+	p := New()
+	for _, c := range cases {
+		patch, err := CreateMergePatch([]byte(c[0]), []byte(c[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(patch) != c[2] {
+			t.Fatal(c[0], c[1], "expected", c[2], "got", string(patch))
+		}
+		b, err := p.ApplyMergePatch([]byte(c[0]), patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := p.ApplyMergePatch([]byte(`null`), []byte(c[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != string(expected) {
+			t.Fatal(c[0], c[1], "expected", string(expected), "got", string(b))
+		}
+	}
+}
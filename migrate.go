@@ -0,0 +1,57 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"fmt"
+)
+
+// Migration moves the members under the From pointer prefix to the To pointer prefix.
+type Migration struct {
+	From string
+	To   string
+	// Transform converts the value of an operation whose path is under From.
+	// ptr is the migrated path of the operation.
+	// The value is kept as is if Transform is nil.
+	Transform func(ptr string, v any) (any, error)
+}
+
+// MigratePatch rewrites the paths of ops after the document layout is changed by migrations,
+// so patches recorded with the old layout can be replayed against the new one.
+// For each path the first matched migration is used.
+// Values of operations whose path is a parent of a migration prefix are not rewritten.
+// ops is not modified.
+func MigratePatch(ops []Operation, migrations ...Migration) ([]Operation, error) {
+	r := copyOperations(ops)
+	for i := range r {
+		op := &r[i]
+		if op.Path != nil {
+			path, m := migratePointer(*op.Path, migrations)
+			op.Path = &path
+			if m != nil && m.Transform != nil && op.Value != nil {
+				v, err := m.Transform(path, *op.Value)
+				if err != nil {
+					return nil, fmt.Errorf("migrate operation %d: %w", i, err)
+				}
+				op.Value = &v
+			}
+		}
+		if op.From != nil {
+			from, _ := migratePointer(*op.From, migrations)
+			op.From = &from
+		}
+	}
+	return r, nil
+}
+
+func migratePointer(ptr string, migrations []Migration) (string, *Migration) {
+	for i := range migrations {
+		m := &migrations[i]
+		if isPathPrefix(m.From, ptr) {
+			return m.To + ptr[len(m.From):], m
+		}
+	}
+	return ptr, nil
+}
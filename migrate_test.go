@@ -0,0 +1,52 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMigratePatch(t *testing.T) {
+	ops := mustOperations(t, `[
+		{"op":"add","path":"/user/name","value":"bob"},
+		{"op":"replace","path":"/age","value":"3"},
+		{"op":"move","from":"/user/name","path":"/owner"},
+		{"op":"remove","path":"/username"}
+	]`)
+	origin := copyOperations(ops)
+	migrated, err := MigratePatch(ops,
+		Migration{From: "/user", To: "/profile/user"},
+		Migration{From: "/age", To: "/profile/age", Transform: func(ptr string, v any) (any, error) {
+			if ptr != "/profile/age" {
+				t.Fatal("unexpected path", ptr)
+			}
+			return float64(len(v.(string))), nil
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mustOperations(t, `[
+		{"op":"add","path":"/profile/user/name","value":"bob"},
+		{"op":"replace","path":"/profile/age","value":1},
+		{"op":"move","from":"/profile/user/name","path":"/owner"},
+		{"op":"remove","path":"/username"}
+	]`)
+	if !reflect.DeepEqual(migrated, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(migrated))
+	}
+	if !reflect.DeepEqual(ops, origin) {
+		t.Fatal("operations are modified")
+	}
+
+	errBad := errors.New("bad")
+	_, err = MigratePatch(ops, Migration{From: "/user", To: "/u", Transform: func(string, any) (any, error) {
+		return nil, errBad
+	}})
+	if !errors.Is(err, errBad) {
+		t.Fatal("expected transform error, got", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownDocument is returned if a pointer refers to a document not exists.
+var ErrUnknownDocument = errors.New("unknown document")
+
+// ApplyDocuments apply the operations to the named json documents as one atomic patch.
+// Every path and from of ops must be qualified by the document name like "doc2#/settings/theme",
+// so copy and move operations can take values from the other documents.
+// The name is the text before the first "#" and must not contain "#".
+// None of the documents is changed if any operation fails.
+// The whole document can be replaced but not removed.
+// The router of p is not called since the paths are not pointers of a single document.
+func (p *Patch) ApplyDocuments(docs map[string][]byte, ops []Operation) (map[string][]byte, error) {
+	root := make(map[string]any, len(docs))
+	for name, b := range docs {
+		var o any
+		if err := json.Unmarshal(b, &o); err != nil {
+			return nil, fmt.Errorf("document %s: %w", name, err)
+		}
+		root[name] = o
+	}
+	qualified, err := qualifyOperations(root, ops)
+	if err != nil {
+		return nil, err
+	}
+	var o any = root
+	if err := p.applyAny(&o, qualified); err != nil {
+		return nil, err
+	}
+	out := make(map[string][]byte, len(docs))
+	for name := range docs {
+		v, ok := root[name]
+		if !ok {
+			return nil, fmt.Errorf("document %s is removed", name)
+		}
+		var buf bytes.Buffer
+		if err := p.EncodeOptions().encode(&buf, v); err != nil {
+			return nil, err
+		}
+		out[name] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// qualifyOperations returns copies of ops whose paths are the pointers of the documents in root.
+func qualifyOperations(root map[string]any, ops []Operation) ([]Operation, error) {
+	qualified := make([]Operation, len(ops))
+	for i, op := range ops {
+		if op.Path != nil {
+			path, err := qualifyPointer(root, *op.Path)
+			if err != nil {
+				return nil, err
+			}
+			op.Path = &path
+		}
+		if op.From != nil {
+			from, err := qualifyPointer(root, *op.From)
+			if err != nil {
+				return nil, err
+			}
+			op.From = &from
+		}
+		qualified[i] = op
+	}
+	return qualified, nil
+}
+
+// qualifyPointer converts "name#pointer" to the pointer of root.
+func qualifyPointer(root map[string]any, s string) (string, error) {
+	name, ptr, ok := strings.Cut(s, "#")
+	if !ok {
+		return "", fmt.Errorf("%w: pointer %s must be qualified by a document name", ErrUnknownDocument, s)
+	}
+	if _, ok := root[name]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownDocument, name)
+	}
+	if err := NewJSONPointer(ptr).Check(); err != nil {
+		return "", err
+	}
+	return "/" + EscapeToken(name) + ptr, nil
+}
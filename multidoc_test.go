@@ -0,0 +1,57 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyDocuments(t *testing.T) {
+	docs := map[string][]byte{
+		"user":  []byte(`{"settings":{"theme":"dark"},"tmp":{"a":1}}`),
+		"org/1": []byte(`{"settings":{}}`),
+	}
+	ops := mustOperations(t, `[
+		{"op":"copy","from":"user#/settings/theme","path":"org/1#/settings/theme"},
+		{"op":"move","from":"user#/tmp","path":"org/1#/tmp"},
+		{"op":"test","path":"org/1#/tmp/a","value":1}
+	]`)
+	p := New()
+	out, err := p.ApplyDocuments(docs, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out["user"])); got != `{"settings":{"theme":"dark"}}` {
+		t.Fatal("bad user", got)
+	}
+	if got := strings.TrimSpace(string(out["org/1"])); got != `{"settings":{"theme":"dark"},"tmp":{"a":1}}` {
+		t.Fatal("bad org", got)
+	}
+	if got := string(docs["user"]); got != `{"settings":{"theme":"dark"},"tmp":{"a":1}}` {
+		t.Fatal("documents must not be changed", got)
+	}
+
+	cases := []struct {
+		ops string
+		err error
+	}{
+		{`[{"op":"add","path":"/a","value":1}]`, ErrUnknownDocument},
+		{`[{"op":"add","path":"other#/a","value":1}]`, ErrUnknownDocument},
+		{`[{"op":"remove","path":"user#/missing"}]`, ErrNotExists},
+	}
+	for _, c := range cases {
+		if _, err := p.ApplyDocuments(docs, mustOperations(t, c.ops)); !errors.Is(err, c.err) {
+			t.Fatal(c.ops, "expected", c.err, "got", err)
+		}
+	}
+	if _, err := p.ApplyDocuments(docs, mustOperations(t, `[{"op":"remove","path":"user#"}]`)); err == nil {
+		t.Fatal("expected error of removed document")
+	}
+	out, err = p.ApplyDocuments(docs, mustOperations(t, `[{"op":"replace","path":"user#","value":[1]}]`))
+	if err != nil || strings.TrimSpace(string(out["user"])) != `[1]` {
+		t.Fatal("bad replace of the whole document", string(out["user"]), err)
+	}
+}
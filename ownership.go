@@ -0,0 +1,136 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrNotOwner is returned if a writer writes outside of its claimed subtrees.
+	ErrNotOwner = errors.New("not owner")
+	// ErrClaimConflict is returned if a subtree is claimed by another writer.
+	ErrClaimConflict = errors.New("claim conflict")
+)
+
+// OwnershipError is returned if a writer writes outside of its claimed subtrees.
+type OwnershipError struct {
+	Writer string
+	Path   string
+	// Owner is the writer claimed Path, it's empty if nobody claimed it.
+	Owner string
+}
+
+// Error implements error.
+func (e *OwnershipError) Error() string {
+	if e.Owner == "" {
+		return fmt.Sprintf("not owner: %s writes %s, which is not claimed", e.Writer, e.Path)
+	}
+	return fmt.Sprintf("not owner: %s writes %s, which is owned by %s", e.Writer, e.Path, e.Owner)
+}
+
+// Is returns true if target is ErrNotOwner.
+func (e *OwnershipError) Is(target error) bool {
+	return target == ErrNotOwner
+}
+
+// Ownership is the claims of writers over the subtrees of a document.
+// A subtree is claimed by at most one writer.
+// It's safe for concurrent use.
+type Ownership struct {
+	mu     sync.RWMutex
+	claims map[string]string
+}
+
+// NewOwnership creates an Ownership without claims.
+func NewOwnership() *Ownership {
+	return &Ownership{claims: map[string]string{}}
+}
+
+// Claim claims the subtree at prefix for writer.
+// It returns an error wraps ErrClaimConflict if prefix, its ancestors or descendants
+// are claimed by another writer.
+func (o *Ownership) Claim(writer, prefix string) error {
+	if err := NewJSONPointer(prefix).Check(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for claimed, owner := range o.claims {
+		if owner != writer && (isPathPrefix(claimed, prefix) || isPathPrefix(prefix, claimed)) {
+			return fmt.Errorf("%w: %s overlaps %s owned by %s", ErrClaimConflict, prefix, claimed, owner)
+		}
+	}
+	o.claims[prefix] = writer
+	return nil
+}
+
+// Release releases the claim of writer at prefix.
+func (o *Ownership) Release(writer, prefix string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.claims[prefix] == writer {
+		delete(o.claims, prefix)
+	}
+}
+
+// Claims returns the sorted prefixes claimed by writer.
+func (o *Ownership) Claims(writer string) []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var r []string
+	for prefix, owner := range o.claims {
+		if owner == writer {
+			r = append(r, prefix)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// Check returns an *OwnershipError if any operation writes outside of the subtrees claimed by writer.
+// Test operations, test extensions and the from of copy operations only read, so they are always allowed.
+// The nested operations of composite operations are checked when they are applied.
+func (o *Ownership) Check(writer string, ops []Operation) error {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for _, op := range ops {
+		if op.OP == nil || isCompositeOP(*op.OP) {
+			continue
+		}
+		for _, path := range writePaths(op) {
+			if owner := o.owner(path); owner != writer {
+				return &OwnershipError{Writer: writer, Path: path, Owner: owner}
+			}
+		}
+	}
+	return nil
+}
+
+// owner returns the writer claimed path or its nearest ancestor.
+func (o *Ownership) owner(path string) string {
+	var (
+		owner string
+		n     = -1
+	)
+	for prefix, w := range o.claims {
+		if isPathPrefix(prefix, path) && len(prefix) > n {
+			owner, n = w, len(prefix)
+		}
+	}
+	return owner
+}
+
+// WithOwnership set the ownership checked by Check for writer,
+// so writes outside of the subtrees claimed by writer fail with an *OwnershipError.
+func WithOwnership(o *Ownership, writer string) Option {
+	return func(p *Patch) {
+		p.ownership = o
+		p.writer = writer
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOwnership(t *testing.T) {
+	o := NewOwnership()
+	for _, c := range [][2]string{{"a", "/status/a"}, {"a", "/status/shared/a"}, {"b", "/status/b"}} {
+		if err := o.Claim(c[0], c[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range [][2]string{{"b", "/status"}, {"b", "/status/a/x"}, {"b", ""}} {
+		if err := o.Claim(c[0], c[1]); !errors.Is(err, ErrClaimConflict) {
+			t.Fatal(c, "expected claim conflict, got", err)
+		}
+	}
+	if got := o.Claims("a"); !reflect.DeepEqual(got, []string{"/status/a", "/status/shared/a"}) {
+		t.Fatal("bad claims", got)
+	}
+
+	p := New(WithOwnership(o, "a"))
+	doc := []byte(`{"status":{"a":{},"b":{},"shared":{"a":1}}}`)
+	if _, err := p.Apply(doc, mustOperations(t, `[
+		{"op":"test","path":"/status/b","value":{}},
+		{"op":"add","path":"/status/a/x","value":1},
+		{"op":"copy","from":"/status/b","path":"/status/a/b"},
+		{"op":"replace","path":"/status/shared/a","value":2}
+	]`)); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		ops   string
+		owner string
+	}{
+		{`[{"op":"add","path":"/status/b/x","value":1}]`, "b"},
+		{`[{"op":"move","from":"/status/b","path":"/status/a/b"}]`, "b"},
+		{`[{"op":"remove","path":"/status/shared"}]`, ""},
+	}
+	for _, c := range cases {
+		_, err := p.Apply(doc, mustOperations(t, c.ops))
+		var oe *OwnershipError
+		if !errors.As(err, &oe) || !errors.Is(err, ErrNotOwner) || oe.Owner != c.owner || oe.Writer != "a" {
+			t.Fatal(c.ops, "expected ownership error, got", err)
+		}
+	}
+
+	o.Release("b", "/status/b")
+	if err := o.Claim("a", "/status"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"remove","path":"/status/b"}]`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOwnershipEntryPoints(t *testing.T) {
+	o := NewOwnership()
+	if err := o.Claim("alice", "/a"); err != nil {
+		t.Fatal(err)
+	}
+	doc := []byte(`{"a":1,"b":2}`)
+	ops := `[{"op":"replace","path":"/a","value":3}]`
+	p := New(WithOwnership(o, "alice"), WithCache(NewLRUCache(10)))
+	if _, err := p.Apply(doc, mustOperations(t, ops)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ApplyMergePatch(doc, []byte(`{"a":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	o.Release("alice", "/a")
+	// a cached output is not returned after the claim is released.
+	if _, err := p.Apply(doc, mustOperations(t, ops)); !errors.Is(err, ErrNotOwner) {
+		t.Fatal("expected not owner, got", err)
+	}
+	if _, err := p.NewDecoder(strings.NewReader(ops)).Next(); !errors.Is(err, ErrNotOwner) {
+		t.Fatal("expected not owner, got", err)
+	}
+	if _, err := p.ApplyMergePatch(doc, []byte(`{"a":3}`)); !errors.Is(err, ErrNotOwner) {
+		t.Fatal("expected not owner, got", err)
+	}
+	// a merge patch changes nothing writes nothing.
+	if _, err := p.ApplyMergePatch(doc, []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	pol := New(WithPolicy(&Policy{Deny: []string{"/b"}}))
+	if _, err := pol.ApplyMergePatch(doc, []byte(`{"b":null}`)); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatal("expected policy violation, got", err)
+	}
+}
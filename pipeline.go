@@ -0,0 +1,111 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"context"
+	"fmt"
+)
+
+// Names of the pipeline stages.
+const (
+	StagePolicy    = "policy"
+	StageTransform = "transform"
+	StageApply     = "apply"
+	StageValidate  = "validate"
+	StageEmit      = "emit"
+)
+
+// StageError is the error returned by Pipeline.Run.
+type StageError struct {
+	// Stage is the name of the failed stage.
+	Stage string
+	// Index is the index of the failed function in the stage.
+	Index int
+	Err   error
+}
+
+// Error implements error.
+func (e *StageError) Error() string {
+	return fmt.Sprintf("pipeline stage %s[%d]: %v", e.Stage, e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// StageFunc filters or rewrites the operations before they are applied to doc.
+type StageFunc func(ctx context.Context, doc any, ops []Operation) ([]Operation, error)
+
+// PipelineEvent is emitted after a patch is applied and validated.
+type PipelineEvent struct {
+	Before     any
+	After      any
+	Operations []Operation
+	Result     *Result
+}
+
+// Pipeline runs a patch through the stages in order:
+// policy filters, transforms, apply, validators and emitters.
+type Pipeline struct {
+	// Patch applies the operations. New() is used if it's nil.
+	Patch *Patch
+	// Policies may reject or drop operations.
+	Policies []StageFunc
+	// Transforms rewrite operations.
+	Transforms []StageFunc
+	// Validators check the patched document.
+	Validators []func(ctx context.Context, doc any) error
+	// Emitters receive the event of a successful patch.
+	Emitters []func(ctx context.Context, e PipelineEvent) error
+}
+
+// Run runs ops through the pipeline and returns the patched document.
+// doc is never modified. Errors are returned as *StageError.
+// If an emitter fails, the patched document is returned with the error.
+func (pl *Pipeline) Run(ctx context.Context, doc any, ops []Operation) (any, error) {
+	var err error
+	if ops, err = runStages(ctx, StagePolicy, pl.Policies, doc, ops); err != nil {
+		return nil, err
+	}
+	if ops, err = runStages(ctx, StageTransform, pl.Transforms, doc, ops); err != nil {
+		return nil, err
+	}
+	p := pl.Patch
+	if p == nil {
+		p = New()
+	}
+	after, r, err := p.Simulate(doc, ops)
+	if err != nil {
+		return nil, &StageError{Stage: StageApply, Index: 0, Err: err}
+	}
+	for i, v := range pl.Validators {
+		if err := v(ctx, after); err != nil {
+			return nil, &StageError{Stage: StageValidate, Index: i, Err: err}
+		}
+	}
+	e := PipelineEvent{Before: doc, After: after, Operations: ops, Result: r}
+	for i, emit := range pl.Emitters {
+		if err := emit(ctx, e); err != nil {
+			return after, &StageError{Stage: StageEmit, Index: i, Err: err}
+		}
+	}
+	return after, nil
+}
+
+func runStages(ctx context.Context, stage string, fns []StageFunc, doc any, ops []Operation) ([]Operation, error) {
+	for i, fn := range fns {
+		if err := ctx.Err(); err != nil {
+			return nil, &StageError{Stage: stage, Index: i, Err: err}
+		}
+		next, err := fn(ctx, doc, ops)
+		if err != nil {
+			return nil, &StageError{Stage: stage, Index: i, Err: err}
+		}
+		ops = next
+	}
+	return ops, nil
+}
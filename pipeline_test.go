@@ -0,0 +1,72 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	var events []PipelineEvent
+	errInvalid := errors.New("invalid")
+	pl := &Pipeline{
+		Policies: []StageFunc{func(_ context.Context, _ any, ops []Operation) ([]Operation, error) {
+			var r []Operation
+			for _, op := range ops {
+				if !isPathPrefix("/secret", *op.Path) {
+					r = append(r, op)
+				}
+			}
+			return r, nil
+		}},
+		Transforms: []StageFunc{func(_ context.Context, _ any, ops []Operation) ([]Operation, error) {
+			return MigratePatch(ops, Migration{From: "/old", To: "/new"})
+		}},
+		Validators: []func(context.Context, any) error{func(_ context.Context, doc any) error {
+			if _, ok := doc.(map[string]any)["bad"]; ok {
+				return errInvalid
+			}
+			return nil
+		}},
+		Emitters: []func(context.Context, PipelineEvent) error{func(_ context.Context, e PipelineEvent) error {
+			events = append(events, e)
+			return nil
+		}},
+	}
+	doc := map[string]any{"a": 1.0}
+	ops := mustOperations(t, `[{"op":"add","path":"/secret","value":1},{"op":"add","path":"/old","value":2}]`)
+	out, err := pl.Run(context.Background(), doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"a": 1.0, "new": 2.0}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatal("expected", jsonstring(expected), "got", jsonstring(out))
+	}
+	if len(doc) != 1 {
+		t.Fatal("document is modified")
+	}
+	if len(events) != 1 || len(events[0].Operations) != 1 || events[0].Result.Applied() != 1 {
+		t.Fatal("unexpected events", events)
+	}
+
+	_, err = pl.Run(context.Background(), doc, mustOperations(t, `[{"op":"add","path":"/bad","value":1}]`))
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != StageValidate || !errors.Is(err, errInvalid) {
+		t.Fatal("expected validate error, got", err)
+	}
+	_, err = pl.Run(context.Background(), doc, mustOperations(t, `[{"op":"remove","path":"/x/y"}]`))
+	if !errors.As(err, &se) || se.Stage != StageApply {
+		t.Fatal("expected apply error, got", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pl.Run(ctx, doc, ops)
+	if !errors.As(err, &se) || se.Stage != StagePolicy || !errors.Is(err, context.Canceled) {
+		t.Fatal("expected policy error, got", err)
+	}
+}
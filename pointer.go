@@ -0,0 +1,136 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"strings"
+	"sync"
+)
+
+// defaultPointerCacheSize is the default number of compiled pointers cached by Patch.
+const defaultPointerCacheSize = 4096
+
+// CompiledPointer is a JSONPointer whose tokens are split and unescaped once,
+// so it is cheap to use it again and again.
+type CompiledPointer struct {
+	origin string
+	tokens []string
+}
+
+// CompilePointer checks and compiles a json pointer.
+func CompilePointer(s string) (CompiledPointer, error) {
+	if err := NewJSONPointer(s).Check(); err != nil {
+		return CompiledPointer{}, err
+	}
+	return compilePointer(s), nil
+}
+
+func compilePointer(s string) CompiledPointer {
+	c := CompiledPointer{origin: s}
+	if s != "" {
+		c.tokens = strings.Split(s[1:], "/")
+		for i, token := range c.tokens {
+			c.tokens[i] = UnescapeToken(token)
+		}
+	}
+	return c
+}
+
+// String returns the json pointer as a string.
+func (c CompiledPointer) String() string {
+	return c.origin
+}
+
+// Pointer returns the JSONPointer of c.
+func (c CompiledPointer) Pointer() JSONPointer {
+	return NewJSONPointer(c.origin)
+}
+
+// IsTheWholeDocument return true if the pointer points to the whole document.
+func (c CompiledPointer) IsTheWholeDocument() bool {
+	return c.origin == ""
+}
+
+// Path return the unescaped tokens of the pointer, they are shared and must not be modified.
+func (c CompiledPointer) Path() []string {
+	return c.tokens[:len(c.tokens):len(c.tokens)]
+}
+
+// ParentPath return the tokens of the parent, they are shared and must not be modified.
+func (c CompiledPointer) ParentPath() []string {
+	if len(c.tokens) == 0 {
+		return nil
+	}
+	n := len(c.tokens) - 1
+	return c.tokens[:n:n]
+}
+
+// LastToken return the last token of the pointer.
+func (c CompiledPointer) LastToken() string {
+	if len(c.tokens) == 0 {
+		return ""
+	}
+	return c.tokens[len(c.tokens)-1]
+}
+
+// SameParent return true if the parent of c is the same as the parent of o.
+func (c CompiledPointer) SameParent(o CompiledPointer) bool {
+	a, b := c.ParentPath(), o.ParentPath()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WithPointerCache set the max number of compiled pointers cached by Patch,
+// the standard operations look up the pointers of path and from in the cache
+// instead of parsing them on every apply, which helps a patch applied many times.
+// The default size is 4096, and size 0 disables the cache.
+func WithPointerCache(size int) Option {
+	return func(o *Patch) {
+		o.pointers = nil
+		if size > 0 {
+			o.pointers = newPointerCache(size)
+		}
+	}
+}
+
+// pointerCache caches compiled pointers, it is cleared when it is full.
+type pointerCache struct {
+	mu       sync.RWMutex
+	size     int
+	pointers map[string]CompiledPointer
+}
+
+func newPointerCache(size int) *pointerCache {
+	return &pointerCache{size: size, pointers: map[string]CompiledPointer{}}
+}
+
+// pointer returns the compiled pointer of s which is checked already.
+func (p *Patch) pointer(s string) CompiledPointer {
+	c := p.pointers
+	if c == nil {
+		return compilePointer(s)
+	}
+	c.mu.RLock()
+	v, ok := c.pointers[s]
+	c.mu.RUnlock()
+	if ok {
+		return v
+	}
+	v = compilePointer(s)
+	c.mu.Lock()
+	if len(c.pointers) >= c.size {
+		c.pointers = map[string]CompiledPointer{}
+	}
+	c.pointers[s] = v
+	c.mu.Unlock()
+	return v
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompilePointer(t *testing.T) {
+	c, err := CompilePointer("/a~1b/c~0d/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Path(), []string{"a/b", "c~d", "0"}) || c.LastToken() != "0" ||
+		!reflect.DeepEqual(c.ParentPath(), []string{"a/b", "c~d"}) || c.String() != "/a~1b/c~0d/0" {
+		t.Fatal("unexpected compiled pointer", c.Path())
+	}
+	// appending to the parent must not change the shared tokens.
+	_ = append(c.ParentPath(), "x")
+	if c.LastToken() != "0" {
+		t.Fatal("tokens are changed")
+	}
+	root, err := CompilePointer("")
+	if err != nil || !root.IsTheWholeDocument() || len(root.Path()) != 0 || root.ParentPath() != nil {
+		t.Fatal("unexpected root pointer", err)
+	}
+	if !c.SameParent(compilePointer("/a~1b/c~0d/1")) || c.SameParent(root) {
+		t.Fatal("unexpected SameParent")
+	}
+	if _, err := CompilePointer("a"); err == nil {
+		t.Fatal("expected bad pointer")
+	}
+}
+
+func TestPointerCache(t *testing.T) {
+	doc := []byte(`{"a":{"b":1}}`)
+	ops := mustOperations(t, `[{"op":"replace","path":"/a/b","value":2},{"op":"copy","from":"/a/b","path":"/c"}]`)
+	for _, p := range []*Patch{New(), New(WithPointerCache(1)), New(WithPointerCache(0))} {
+		for i := 0; i < 3; i++ {
+			b, err := p.Apply(doc, ops)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != `{"a":{"b":2},"c":2}`+"\n" {
+				t.Fatal("unexpected document", string(b))
+			}
+		}
+	}
+	p := New(WithPointerCache(2))
+	for _, s := range []string{"/a", "/b", "/c"} {
+		p.pointer(s)
+	}
+	if len(p.pointers.pointers) > 2 {
+		t.Fatal("cache exceeds its size", len(p.pointers.pointers))
+	}
+}
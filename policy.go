@@ -0,0 +1,214 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrPolicyViolation is returned if a patch violates the policy of Patch.
+var ErrPolicyViolation = errors.New("policy violation")
+
+// Policy is the rules of patches, usually loaded from a json document by LoadPolicy.
+// The policy document is json only, since the package has no dependencies,
+// convert a yaml document to json before loading it.
+//
+// Paths are json pointers, a "*" token matches any token and
+// a trailing "**" token matches any number of tokens.
+type Policy struct {
+	// AllowedOps are the allowed operations, all operations are allowed if it is empty.
+	AllowedOps []string `json:"allowedOps,omitempty"`
+	// Allow are the paths operations may write, all paths are allowed if it is empty.
+	Allow []string `json:"allow,omitempty"`
+	// Deny are the paths operations must not touch, including their ancestors and descendants.
+	// Copying from them is denied as well as writing them.
+	Deny []string `json:"deny,omitempty"`
+	// MaxOperations is the max number of operations of a patch, 0 means no limit.
+	MaxOperations int `json:"maxOperations,omitempty"`
+	// RequireTests are the paths every patch must test before any other operation,
+	// by the test operation or the test extensions like "test-lt".
+	RequireTests []string `json:"requireTests,omitempty"`
+}
+
+// LoadPolicy reads a json policy document from r, yaml is not supported.
+// Unknown fields are rejected.
+func LoadPolicy(r io.Reader) (*Policy, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var pol Policy
+	if err := dec.Decode(&pol); err != nil {
+		return nil, fmt.Errorf("load policy: %w", err)
+	}
+	return &pol, nil
+}
+
+// WithPolicy set the policy checked by Check, so it applies to every apply.
+// A violation returns an error wraps ErrPolicyViolation.
+func WithPolicy(pol *Policy) Option {
+	return func(o *Patch) {
+		o.policy = pol
+	}
+}
+
+// Check returns an error wraps ErrPolicyViolation if ops violates the policy.
+func (pol *Policy) Check(ops []Operation) error {
+	c := pol.checker()
+	for _, op := range ops {
+		if err := c.next(op); err != nil {
+			return err
+		}
+	}
+	return c.finish()
+}
+
+// policyChecker checks operations one by one, so a stream of operations can be checked before all of them arrive.
+type policyChecker struct {
+	pol *Policy
+	n   int
+	// tested are the paths of the leading test operations and test extensions,
+	// it is nil after the first other operation.
+	tested map[string]bool
+}
+
+func (pol *Policy) checker() *policyChecker {
+	return &policyChecker{pol: pol, tested: map[string]bool{}}
+}
+
+func (c *policyChecker) next(op Operation) error {
+	if err := c.count(op); err != nil {
+		return err
+	}
+	if op.OP == nil || op.Path == nil {
+		return nil
+	}
+	if isTestOP(*op.OP) {
+		if c.tested != nil {
+			c.tested[*op.Path] = true
+		}
+		return nil
+	}
+	if err := c.finish(); err != nil {
+		return err
+	}
+	c.tested = nil
+	if isCompositeOP(*op.OP) {
+		// the nested operations are checked when they are applied.
+		return nil
+	}
+	return c.pol.checkPaths(op)
+}
+
+// count counts op and its nested operations and checks whether they are allowed.
+// The nested operations of a foreach operation are counted once, not once per element.
+func (c *policyChecker) count(op Operation) error {
+	c.n++
+	if c.pol.MaxOperations > 0 && c.n > c.pol.MaxOperations {
+		return fmt.Errorf("%w: operations exceeds %d", ErrPolicyViolation, c.pol.MaxOperations)
+	}
+	if op.OP == nil || op.Path == nil {
+		return nil
+	}
+	if len(c.pol.AllowedOps) > 0 && !containsString(c.pol.AllowedOps, *op.OP) {
+		return fmt.Errorf("%w: operation %s is not allowed", ErrPolicyViolation, *op.OP)
+	}
+	for _, sub := range nestedOperations(op) {
+		if err := c.count(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPaths checks the paths op writes and copies from.
+// JSONPath expressions are skipped, their matches are checked when they are applied.
+func (pol *Policy) checkPaths(op Operation) error {
+	for _, path := range writePaths(op) {
+		if isJSONPathExpr(path) {
+			continue
+		}
+		if err := pol.checkPath(path); err != nil {
+			return err
+		}
+	}
+	if *op.OP == opCopy && op.From != nil && !isJSONPathExpr(*op.From) {
+		return pol.checkDeny(*op.From)
+	}
+	return nil
+}
+
+// isJSONPathExpr returns true if path is a JSONPath expression rather than a json pointer.
+func isJSONPathExpr(path string) bool {
+	return strings.HasPrefix(path, "$")
+}
+
+// finish checks the required tests if no other operation is checked.
+func (c *policyChecker) finish() error {
+	if c.tested == nil {
+		return nil
+	}
+	for _, path := range c.pol.RequireTests {
+		if !c.tested[path] {
+			return fmt.Errorf("%w: a test of %s is required", ErrPolicyViolation, path)
+		}
+	}
+	return nil
+}
+
+func (pol *Policy) checkPath(path string) error {
+	if err := pol.checkDeny(path); err != nil {
+		return err
+	}
+	if len(pol.Allow) == 0 {
+		return nil
+	}
+	tokens := NewJSONPointer(path).Path()
+	for _, a := range pol.Allow {
+		if matchGlob(NewJSONPointer(a).Path(), tokens) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not allowed", ErrPolicyViolation, path)
+}
+
+// checkDeny returns an error if path is, or is an ancestor or a descendant of a denied path.
+func (pol *Policy) checkDeny(path string) error {
+	tokens := NewJSONPointer(path).Path()
+	for _, d := range pol.Deny {
+		pattern := NewJSONPointer(d).Path()
+		if relatedTokens(pattern, tokens) || isDescendant(pattern, tokens) {
+			return fmt.Errorf("%w: %s is denied by %s", ErrPolicyViolation, path, d)
+		}
+	}
+	return nil
+}
+
+// isDescendant returns true if tokens is a descendant of a path matches pattern.
+func isDescendant(pattern, tokens []string) bool {
+	if n := len(pattern); n > 0 && pattern[n-1] == "**" {
+		pattern = pattern[:n-1]
+	}
+	return len(tokens) > len(pattern) && matchTokens(pattern, tokens[:len(pattern)])
+}
+
+// matchGlob returns true if tokens matches pattern.
+func matchGlob(pattern, tokens []string) bool {
+	if n := len(pattern); n > 0 && pattern[n-1] == "**" {
+		return len(tokens) >= n-1 && matchTokens(pattern[:n-1], tokens[:n-1])
+	}
+	return matchTokens(pattern, tokens)
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	pol, err := LoadPolicy(strings.NewReader(`{
+		"allowedOps": ["test", "add", "replace", "move", "copy"],
+		"allow": ["/spec/**", "/labels/*"],
+		"deny": ["/spec/secret"],
+		"maxOperations": 4,
+		"requireTests": ["/version"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithPolicy(pol))
+	doc := []byte(`{"version":1,"spec":{"a":1,"secret":"x"},"labels":{}}`)
+	cases := []struct {
+		ops string
+		ok  bool
+	}{
+		{`[{"op":"test","path":"/version","value":1},{"op":"replace","path":"/spec/a","value":2},{"op":"add","path":"/labels/x","value":"y"}]`, true},
+		{`[{"op":"replace","path":"/spec/a","value":2}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"remove","path":"/spec/a"}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"replace","path":"/spec/secret","value":"y"}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"replace","path":"/spec","value":{}}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"move","from":"/spec/secret","path":"/spec/b"}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"replace","path":"/spec/secret/x","value":"y"}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"copy","from":"/spec/secret","path":"/spec/b"}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"copy","from":"/spec/a","path":"/spec/b"}]`, true},
+		{`[{"op":"test","path":"/version","value":1},{"op":"add","path":"/labels/x/y","value":1}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"add","path":"/other","value":1}]`, false},
+		{`[{"op":"test","path":"/version","value":1},{"op":"test","path":"/spec/a","value":1},{"op":"test","path":"/spec/a","value":1},{"op":"test","path":"/spec/a","value":1},{"op":"test","path":"/spec/a","value":1}]`, false},
+	}
+	for _, c := range cases {
+		_, err := p.Apply(doc, mustOperations(t, c.ops))
+		if c.ok && err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if !c.ok && !errors.Is(err, ErrPolicyViolation) {
+			t.Fatal(c.ops, "expected policy violation, got", err)
+		}
+	}
+	if _, err := LoadPolicy(strings.NewReader(`{"allowOps":["add"]}`)); err == nil {
+		t.Fatal("expected error of unknown field")
+	}
+}
+
+func TestPolicyPatterns(t *testing.T) {
+	doc := []byte(`{"secret":{"k":1},"public":{"k":2},"users":[{"name":"alice","k":3},{"name":"bob","k":4}]}`)
+	pol := &Policy{Deny: []string{"/secret", "/users/1"}}
+	cases := []struct {
+		p   *Patch
+		ops string
+		ok  bool
+	}{
+		{New(WithPolicy(pol), WithWildcards(true)), `[{"op":"remove","path":"/*/k"}]`, false},
+		{New(WithPolicy(pol), WithRecursiveWildcards(true)), `[{"op":"remove","path":"/**/k"}]`, false},
+		{New(WithPolicy(pol), WithElementSelectors(true)), `[{"op":"remove","path":"/users/[name=bob]/k"}]`, false},
+		{New(WithPolicy(pol), WithElementSelectors(true)), `[{"op":"remove","path":"/users/[name=alice]/k"}]`, true},
+		{New(WithPolicy(pol), WithJSONPathPaths(true)), `[{"op":"copy","from":"$.secret.k","path":"/public/x"}]`, false},
+		{New(WithPolicy(pol), WithJSONPathPaths(true)), `[{"op":"copy","from":"$.public.k","path":"/public/x"}]`, true},
+		{New(WithPolicy(pol), WithElementSelectors(true)), `[{"op":"copy","from":"/users/[name=bob]/k","path":"/public/x"}]`, false},
+		{New(WithPolicy(pol), WithElementSelectors(true)), `[{"op":"copy","from":"/users/[name=alice]/k","path":"/public/x"}]`, true},
+	}
+	for _, c := range cases {
+		out, err := c.p.Apply(doc, mustOperations(t, c.ops))
+		if c.ok && err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if !c.ok && !errors.Is(err, ErrPolicyViolation) {
+			t.Fatal(c.ops, "expected policy violation, got", err, string(out))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrJobPanic is returned in JobResult if applying a job panics.
+var ErrJobPanic = errors.New("job panic")
+
+// Job is a patch to apply to an independent document.
+type Job struct {
+	ID         string
+	Doc        []byte
+	Operations []Operation
+	// Timeout overrides the Timeout of JobPool if it's not zero.
+	Timeout time.Duration
+}
+
+// JobResult is the result of a Job.
+type JobResult struct {
+	ID  string
+	Doc []byte
+	Err error
+}
+
+// JobPool applies jobs with bounded concurrency.
+type JobPool struct {
+	// Patch applies the jobs. New() is used if it's nil.
+	Patch *Patch
+	// Workers is the number of concurrent jobs, 1 is used if it's less than 1.
+	Workers int
+	// Timeout is the timeout of every job, no timeout if it's zero.
+	Timeout time.Duration
+}
+
+// ApplyJobs applies the jobs from jobs and sends their results to results
+// until jobs is closed or ctx is done. It returns after all the started jobs are finished
+// and never closes results.
+// A job exceeded its timeout gets context.DeadlineExceeded, though the work keeps running
+// in background until the apply returns.
+// A panic in a job is recovered and reported as ErrJobPanic.
+func (jp *JobPool) ApplyJobs(ctx context.Context, jobs <-chan Job, results chan<- JobResult) error {
+	p := jp.Patch
+	if p == nil {
+		p = New()
+	}
+	n := jp.Workers
+	if n < 1 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job, ok := <-jobs:
+					if !ok {
+						return
+					}
+					r := jp.run(ctx, p, job)
+					select {
+					case results <- r:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (jp *JobPool) run(ctx context.Context, p *Patch, job Job) JobResult {
+	timeout := jp.Timeout
+	if job.Timeout != 0 {
+		timeout = job.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan JobResult, 1)
+	go func() {
+		r := JobResult{ID: job.ID}
+		defer func() {
+			if v := recover(); v != nil {
+				r.Doc = nil
+				r.Err = fmt.Errorf("%w: %v", ErrJobPanic, v)
+			}
+			done <- r
+		}()
+		r.Doc, r.Err = p.Apply(job.Doc, job.Operations)
+	}()
+	select {
+	case r := <-done:
+		return r
+	case <-ctx.Done():
+		return JobResult{ID: job.ID, Err: ctx.Err()}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type panicExtension struct{}
+
+func (panicExtension) OP() string { return "panic" }
+
+func (panicExtension) Apply(*Patch, *any, Operation) error { panic("boom") }
+
+func (panicExtension) Check(*Patch, Operation) error { return nil }
+
+type sleepExtension struct{}
+
+func (sleepExtension) OP() string { return "sleep" }
+
+func (sleepExtension) Apply(*Patch, *any, Operation) error {
+	time.Sleep(100 * time.Millisecond)
+	return nil
+}
+
+func (sleepExtension) Check(*Patch, Operation) error { return nil }
+
+func TestJobPool(t *testing.T) {
+	pool := &JobPool{
+		Patch:   New(WithExtension(panicExtension{}), WithExtension(sleepExtension{})),
+		Workers: 4,
+		Timeout: 10 * time.Millisecond,
+	}
+	jobs := make(chan Job)
+	results := make(chan JobResult, 100)
+	go func() {
+		for i := 0; i < 20; i++ {
+			jobs <- Job{
+				ID:         strconv.Itoa(i),
+				Doc:        []byte(`{}`),
+				Operations: mustOperations(t, `[{"op":"add","path":"/a","value":`+strconv.Itoa(i)+`}]`),
+				Timeout:    time.Second,
+			}
+		}
+		jobs <- Job{ID: "panic", Doc: []byte(`{}`), Operations: mustOperations(t, `[{"op":"panic","path":""}]`)}
+		jobs <- Job{ID: "sleep", Doc: []byte(`{}`), Operations: mustOperations(t, `[{"op":"sleep","path":""}]`)}
+		close(jobs)
+	}()
+	if err := pool.ApplyJobs(context.Background(), jobs, results); err != nil {
+		t.Fatal(err)
+	}
+	close(results)
+	n := 0
+	for r := range results {
+		n++
+		switch r.ID {
+		case "panic":
+			if !errors.Is(r.Err, ErrJobPanic) {
+				t.Fatal("expected panic error, got", r.Err)
+			}
+		case "sleep":
+			if !errors.Is(r.Err, context.DeadlineExceeded) {
+				t.Fatal("expected timeout, got", r.Err)
+			}
+		default:
+			if r.Err != nil {
+				t.Fatal(r.Err)
+			}
+			if string(r.Doc) != `{"a":`+r.ID+"}\n" {
+				t.Fatal("unexpected document", r.ID, string(r.Doc))
+			}
+		}
+	}
+	if n != 22 {
+		t.Fatal("expected 22 results, got", n)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// randomAttempts is the number of tries to generate a valid operation
+// before falling back to a test of the whole document.
+const randomAttempts = 16
+
+// GenerateRandomPatch generates n random operations that can be applied to doc in order.
+// All paths and array indices are valid at the time the operation is applied.
+// The result is deterministic for the same doc, n and seed. doc is not modified.
+// It returns nil if n is not positive.
+func GenerateRandomPatch(doc any, n int, seed int64) []Operation {
+	return GenerateWeightedPatch(doc, n, seed, nil)
+}
+
+// GenerateWeightedPatch is GenerateRandomPatch whose operation kinds follow weights,
+// which maps the standard operations to their weights, e.g. {"add": 2, "test": 1}.
+// Kinds without a positive weight are never generated, unless no kind has a positive weight,
+// then all kinds are equally likely as GenerateRandomPatch does.
+// It always returns n operations, a test of the whole document is generated
+// if no valid operation is found after several tries. It returns nil if n is not positive.
+func GenerateWeightedPatch(doc any, n int, seed int64, weights map[string]int) []Operation {
+	if n <= 0 {
+		return nil
+	}
+	var (
+		r   = rand.New(rand.NewSource(seed))
+		p   = New()
+		cur = deepCopy(doc)
+		ops = make([]Operation, 0, n)
+	)
+	for len(ops) < n {
+		op, next, ok := randomStep(r, p, cur, weights)
+		if !ok {
+			op = newOperation(opTest, "", deepCopy(cur), nil)
+			next = cur
+		}
+		ops = append(ops, op)
+		cur = next
+	}
+	return ops
+}
+
+// randomStep generates an operation and applies it to a copy of cur.
+func randomStep(r *rand.Rand, p *Patch, cur any, weights map[string]int) (Operation, any, bool) {
+	var nodes []string
+	_ = walk(cur, "", func(ptr string, _ any) error {
+		nodes = append(nodes, ptr)
+		return nil
+	})
+	for i := 0; i < randomAttempts; i++ {
+		op, ok := randomOperation(r, cur, nodes, weights)
+		if !ok {
+			continue
+		}
+		next := deepCopy(cur)
+		if err := p.applyAny(&next, []Operation{op}); err != nil {
+			continue
+		}
+		return op, next, true
+	}
+	return Operation{}, nil, false
+}
+
+// randomKinds are the kinds of operations generated, in the order of their weights are summed.
+var randomKinds = []string{opAdd, opRemove, opReplace, opMove, opCopy, opTest}
+
+func randomOperation(r *rand.Rand, doc any, nodes []string, weights map[string]int) (Operation, bool) {
+	node := nodes[r.Intn(len(nodes))]
+	switch randomKind(r, weights) {
+	case opAdd:
+		path, ok := randomTarget(r, doc, nodes, "")
+		return newOperation(opAdd, path, randomValue(r, 2), nil), ok
+	case opRemove:
+		return newOperation(opRemove, node, nil, nil), node != ""
+	case opReplace:
+		return newOperation(opReplace, node, randomValue(r, 2), nil), true
+	case opMove:
+		path, ok := randomTarget(r, doc, nodes, node)
+		return newOperation(opMove, path, nil, &node), ok && node != ""
+	case opCopy:
+		path, ok := randomTarget(r, doc, nodes, "")
+		return newOperation(opCopy, path, nil, &node), ok
+	default:
+		value, _, err := New().VisitPath(&doc, NewJSONPointer(node).Path()...)
+		return newOperation(opTest, node, deepCopy(value), nil), err == nil
+	}
+}
+
+// randomKind picks a kind of randomKinds by weights.
+func randomKind(r *rand.Rand, weights map[string]int) string {
+	total := 0
+	for _, k := range randomKinds {
+		if w := weights[k]; w > 0 {
+			total += w
+		}
+	}
+	if total == 0 {
+		return randomKinds[r.Intn(len(randomKinds))]
+	}
+	n := r.Intn(total)
+	for _, k := range randomKinds {
+		w := weights[k]
+		if w <= 0 {
+			continue
+		}
+		if n < w {
+			return k
+		}
+		n -= w
+	}
+	return opTest
+}
+
+// randomTarget returns a path where a new member can be added.
+// The target is never inside of the exclude path.
+func randomTarget(r *rand.Rand, doc any, nodes []string, exclude string) (string, bool) {
+	parent := nodes[r.Intn(len(nodes))]
+	if exclude != "" && (parent == exclude || strings.HasPrefix(parent, exclude+"/")) {
+		return "", false
+	}
+	v, _, err := New().VisitPath(&doc, NewJSONPointer(parent).Path()...)
+	if err != nil {
+		return "", false
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		return parent + "/" + EscapeToken("k"+strconv.Itoa(r.Intn(1000))), true
+	case []any:
+		if exclude != "" && NewJSONPointer(exclude).SameParent(NewJSONPointer(parent+"/-")) {
+			// moving inside of the same array, the array is shorter after removal.
+			if len(v) <= 1 {
+				return "", false
+			}
+			return parent + "/" + strconv.Itoa(r.Intn(len(v)-1)), true
+		}
+		if len(v) == 0 || r.Intn(4) == 0 {
+			return parent + "/-", true
+		}
+		return parent + "/" + strconv.Itoa(r.Intn(len(v))), true
+	default:
+		return "", false
+	}
+}
+
+func randomValue(r *rand.Rand, depth int) any {
+	n := 5
+	if depth > 0 {
+		n = 7
+	}
+	switch r.Intn(n) {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 0
+	case 2:
+		return float64(r.Intn(1000))
+	case 3, 4:
+		return "s" + strconv.Itoa(r.Intn(1000))
+	case 5:
+		a := make([]any, r.Intn(3))
+		for i := range a {
+			a[i] = randomValue(r, depth-1)
+		}
+		return a
+	default:
+		m := map[string]any{}
+		for i := r.Intn(3); i > 0; i-- {
+			m["k"+strconv.Itoa(r.Intn(1000))] = randomValue(r, depth-1)
+		}
+		return m
+	}
+}
+
+func newOperation(op, path string, value any, from *string) Operation {
+	o := Operation{OP: &op, Path: &path, From: from}
+	switch op {
+	case opAdd, opReplace, opTest:
+		o.Value = &value
+	}
+	return o
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateRandomPatch(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a":[1,2,{"b":"c"}],"d/e":{"f":null},"g":"h"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	origin := deepCopy(doc)
+	for seed := int64(0); seed < 50; seed++ {
+		ops := GenerateRandomPatch(doc, 20, seed)
+		if len(ops) != 20 {
+			t.Fatal("expected 20 operations, got", len(ops))
+		}
+		if !reflect.DeepEqual(ops, GenerateRandomPatch(doc, 20, seed)) {
+			t.Fatal("expected same patch for seed", seed)
+		}
+		o := deepCopy(doc)
+		if err := New().ApplyAny(&o, ops); err != nil {
+			t.Fatal("seed", seed, err)
+		}
+	}
+	if !reflect.DeepEqual(doc, origin) {
+		t.Fatal("document is modified")
+	}
+	for _, n := range []int{0, -1} {
+		if ops := GenerateRandomPatch(doc, n, 1); ops != nil {
+			t.Fatal("expected no operation for", n, ops)
+		}
+	}
+}
+
+func TestGenerateWeightedPatch(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a":[1,2,{"b":"c"}],"g":"h"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	ops := GenerateWeightedPatch(doc, 50, 1, map[string]int{opAdd: 1, opRemove: 0})
+	if len(ops) != 50 {
+		t.Fatal("expected 50 operations, got", len(ops))
+	}
+	for _, op := range ops {
+		if *op.OP != opAdd && *op.Path != "" {
+			t.Fatal("unexpected operation", *op.OP, *op.Path)
+		}
+	}
+	if !reflect.DeepEqual(GenerateRandomPatch(doc, 20, 1), GenerateWeightedPatch(doc, 20, 1, nil)) {
+		t.Fatal("expected same patch without weights")
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// WithRawEngine set the RawEngine option.
+// The default value is false.
+// If RawEngine is true, Apply edits the raw json bytes in place instead of decoding the whole document,
+// which is much cheaper when a patch touches a few members of a huge document.
+// The formatting of the untouched parts of the document is kept as is and
+// JSONPrefix and JSONIndent are ignored,
+// while the output ends with a newline as the output of the standard engine does.
+// The raw engine is used only if all operations are standard RFC6902 operations.
+func WithRawEngine(on bool) Option {
+	return func(o *Patch) {
+		o.RawEngine = on
+	}
+}
+
+func (p *Patch) canApplyRaw(ops []Operation) bool {
+	if !p.RawEngine || p.OriginalArrayIndex || p.IntermediateAppend || p.hooksWrites() || len(p.embedded) > 0 {
+		return false
+	}
+	for _, op := range ops {
+		if op.OP == nil || op.Path == nil || p.hasPattern(*op.Path) {
+			return false
+		}
+		// a JSONPath from is resolved against the decoded document.
+		if op.From != nil && p.hasPattern(*op.From) {
+			return false
+		}
+		switch *op.OP {
+		case opAdd, opRemove, opReplace, opMove, opCopy, opTest:
+			if _, ok := p.extensions[*op.OP].(rawExtension); !ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// rawExtension is implemented by the standard extensions that are supported by the raw engine.
+type rawExtension interface {
+	applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error)
+}
+
+func (p *Patch) applyRaw(b []byte, ops []Operation) ([]byte, error) {
+	ops, err := p.fromURIFragments(ops)
+	if err != nil {
+		return nil, p.localize(err)
+	}
+	if err := p.Check(ops); err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		return nil, errors.New("invalid json document")
+	}
+	doc := bytes.TrimSpace(b)
+	for _, op := range ops {
+		ext := p.extensions[*op.OP]
+		next, err := ext.(rawExtension).applyRaw(p, doc, op)
+		if err != nil {
+			if !p.StrictPathExists && errors.Is(err, ErrNotExists) {
+				continue
+			}
+			return nil, p.operationError(ext, op, err)
+		}
+		doc = next
+	}
+	return doc, nil
+}
+
+func (p *Patch) rawEncode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(p.JSONEscapeHTML)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// rawMember is a member of an object or an element of an array in a raw document.
+type rawMember struct {
+	// start is the offset of the key of an object member, or the offset of the value of an array element.
+	start    int
+	key      string
+	valStart int
+	valEnd   int
+}
+
+// rawLocate returns the offset of the value at tokens.
+func (p *Patch) rawLocate(b []byte, tokens []string) (start, end int, err error) {
+	i := rawSkipSpace(b, 0)
+	for _, token := range tokens {
+		members, _, err := rawMembers(b, i)
+		if err != nil {
+			return 0, 0, err
+		}
+		k, err := p.rawFind(b[i], members, token)
+		if err != nil {
+			return 0, 0, err
+		}
+		if k < 0 {
+			return 0, 0, ErrNotExists
+		}
+		i = members[k].valStart
+	}
+	return i, rawSkipValue(b, i), nil
+}
+
+// rawFind returns the index of the member token, or -1 if not exists.
+func (p *Patch) rawFind(kind byte, members []rawMember, token string) (int, error) {
+	if kind == '{' {
+		for k, m := range members {
+			if m.key == token {
+				return k, nil
+			}
+		}
+		return -1, nil
+	}
+	i, err := p.ParseArrayIndex(len(members), token)
+	if err != nil {
+		return 0, err
+	}
+	if i == len(members) {
+		return -1, nil
+	}
+	return i, nil
+}
+
+func (p *Patch) rawAdd(b []byte, path JSONPointer, value []byte) ([]byte, error) {
+	if path.IsTheWholeDocument() {
+		return value, nil
+	}
+	start, _, err := p.rawLocate(b, path.ParentPath())
+	if err != nil {
+		return nil, errPathNotExists(path.origin, err)
+	}
+	members, closing, err := rawMembers(b, start)
+	if err != nil {
+		return nil, err
+	}
+	key := path.LastToken()
+	if b[start] == '{' {
+		for _, m := range members {
+			if m.key == key {
+				return rawSplice(b, m.valStart, m.valEnd, value), nil
+			}
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value = append(append(k, ':'), value...)
+		return rawInsert(b, members, closing, len(members), value), nil
+	}
+	i, err := p.ParseArrayIndex(len(members), key)
+	if err != nil {
+		return nil, err
+	}
+	return rawInsert(b, members, closing, i, value), nil
+}
+
+// rawInsert inserts value before the i-th member of a container.
+func rawInsert(b []byte, members []rawMember, closing, i int, value []byte) []byte {
+	switch {
+	case len(members) == 0:
+		return rawSplice(b, closing, closing, value)
+	case i == len(members):
+		end := members[len(members)-1].valEnd
+		return rawSplice(b, end, end, append([]byte{','}, value...))
+	default:
+		start := members[i].start
+		return rawSplice(b, start, start, append(value, ','))
+	}
+}
+
+func (p *Patch) rawRemove(b []byte, path JSONPointer) ([]byte, error) {
+	if path.IsTheWholeDocument() {
+		return nil, errors.New("can not remove the whole document")
+	}
+	start, _, err := p.rawLocate(b, path.ParentPath())
+	if err != nil {
+		return nil, errPathNotExists(path.origin, err)
+	}
+	members, _, err := rawMembers(b, start)
+	if err != nil {
+		return nil, err
+	}
+	k, err := p.rawFind(b[start], members, path.LastToken())
+	if err != nil {
+		return nil, ErrNotExists
+	}
+	if k < 0 {
+		return nil, ErrNotExists
+	}
+	switch {
+	case k < len(members)-1:
+		return rawSplice(b, members[k].start, members[k+1].start, nil), nil
+	case k > 0:
+		return rawSplice(b, members[k-1].valEnd, members[k].valEnd, nil), nil
+	default:
+		return rawSplice(b, members[k].start, members[k].valEnd, nil), nil
+	}
+}
+
+func (p *Patch) rawReplace(b []byte, path JSONPointer, value []byte) ([]byte, error) {
+	if path.IsTheWholeDocument() {
+		return value, nil
+	}
+	start, end, err := p.rawLocate(b, path.Path())
+	if errors.Is(err, ErrNotExists) && !p.StrictPathExists {
+		// replace works as add on objects if StrictPathExists is false.
+		parent, _, perr := p.rawLocate(b, path.ParentPath())
+		if perr == nil && b[parent] == '{' {
+			return p.rawAdd(b, path, value)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rawSplice(b, start, end, value), nil
+}
+
+func rawSplice(b []byte, start, end int, value []byte) []byte {
+	n := make([]byte, 0, len(b)-(end-start)+len(value))
+	n = append(n, b[:start]...)
+	n = append(n, value...)
+	return append(n, b[end:]...)
+}
+
+// rawMembers returns the members of the container at offset i and the offset of its closing bracket.
+func rawMembers(b []byte, i int) ([]rawMember, int, error) {
+	if i >= len(b) || (b[i] != '{' && b[i] != '[') {
+		return nil, 0, newError(CodeBadType, map[string]any{"action": "visit", "type": rawKind(b, i)}, nil)
+	}
+	object := b[i] == '{'
+	var members []rawMember
+	i = rawSkipSpace(b, i+1)
+	for b[i] != '}' && b[i] != ']' {
+		m := rawMember{start: i}
+		if object {
+			end := rawSkipString(b, i)
+			key, err := rawKey(b[i:end])
+			if err != nil {
+				return nil, 0, err
+			}
+			m.key = key
+			i = rawSkipSpace(b, end)
+			i = rawSkipSpace(b, i+1) // skip colon
+		}
+		m.valStart = i
+		m.valEnd = rawSkipValue(b, i)
+		members = append(members, m)
+		i = rawSkipSpace(b, m.valEnd)
+		if b[i] == ',' {
+			i = rawSkipSpace(b, i+1)
+		}
+	}
+	return members, i, nil
+}
+
+func rawKey(b []byte) (string, error) {
+	if bytes.IndexByte(b, '\\') < 0 {
+		return string(b[1 : len(b)-1]), nil
+	}
+	var s string
+	err := json.Unmarshal(b, &s)
+	return s, err
+}
+
+func rawKind(b []byte, i int) string {
+	if i >= len(b) {
+		return "<nil>"
+	}
+	switch b[i] {
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "<nil>"
+	default:
+		return "float64"
+	}
+}
+
+func rawSkipSpace(b []byte, i int) int {
+	for i < len(b) {
+		switch b[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// rawSkipString returns the offset after the string starts at i.
+func rawSkipString(b []byte, i int) int {
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// rawSkipValue returns the offset after the value starts at i.
+func rawSkipValue(b []byte, i int) int {
+	switch b[i] {
+	case '"':
+		return rawSkipString(b, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(b); i++ {
+			switch b[i] {
+			case '"':
+				i = rawSkipString(b, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return i
+	default:
+		for i < len(b) {
+			switch b[i] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				return i
+			}
+			i++
+		}
+		return i
+	}
+}
+
+func (addExtension) applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error) {
+	value, err := p.rawEncode(*op.Value)
+	if err != nil {
+		return nil, err
+	}
+	return p.rawAdd(doc, NewJSONPointer(*op.Path), value)
+}
+
+func (removeExtension) applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error) {
+	return p.rawRemove(doc, NewJSONPointer(*op.Path))
+}
+
+func (replaceExtension) applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error) {
+	value, err := p.rawEncode(*op.Value)
+	if err != nil {
+		return nil, err
+	}
+	return p.rawReplace(doc, NewJSONPointer(*op.Path), value)
+}
+
+func (moveExtension) applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error) {
+	path, from := *op.Path, *op.From
+	if path == from {
+		return doc, nil
+	}
+	if isPathPrefix(from, path) {
+		return nil, fmt.Errorf("can not move %s into its child %s", from, path)
+	}
+	start, end, err := p.rawLocate(doc, NewJSONPointer(from).Path())
+	if err != nil {
+		return nil, errPathNotExists(from, err)
+	}
+	value := append([]byte(nil), doc[start:end]...)
+	doc, err = p.rawRemove(doc, NewJSONPointer(from))
+	if err != nil {
+		return nil, err
+	}
+	return p.rawAdd(doc, NewJSONPointer(path), value)
+}
+
+func (copyExtension) applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error) {
+	start, end, err := p.rawLocate(doc, NewJSONPointer(*op.From).Path())
+	if err != nil {
+		return nil, errPathNotExists(*op.From, err)
+	}
+	value := append([]byte(nil), doc[start:end]...)
+	return p.rawAdd(doc, NewJSONPointer(*op.Path), value)
+}
+
+func (testExtension) applyRaw(p *Patch, doc []byte, op Operation) ([]byte, error) {
+	start, end, err := p.rawLocate(doc, NewJSONPointer(*op.Path).Path())
+	if err != nil {
+		if p.StrictPathExists {
+			return nil, errPathNotExists(*op.Path, err)
+		}
+		return nil, ErrStop
+	}
+	var value any
+	if err := json.Unmarshal(doc[start:end], &value); err != nil {
+		return nil, err
+	}
+	if EqualAny(value, *op.Value) {
+		return doc, nil
+	}
+	return nil, ErrStop
+}
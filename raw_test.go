@@ -0,0 +1,142 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRawEngine(t *testing.T) {
+	cases := []struct {
+		doc    string
+		patch  string
+		expect string
+	}{
+		{
+			doc:    `{ "a" : 1 , "b": [1, 2] }`,
+			patch:  `[{"op":"add","path":"/c","value":{"d":"<e>"}}]`,
+			expect: `{ "a" : 1 , "b": [1, 2],"c":{"d":"<e>"} }`,
+		},
+		{
+			doc:    `{"a":1,"b":[1,2]}`,
+			patch:  `[{"op":"add","path":"/b/0","value":0},{"op":"add","path":"/b/-","value":3}]`,
+			expect: `{"a":1,"b":[0,1,2,3]}`,
+		},
+		{
+			doc:    `{"a":1,"b":2,"c":3}`,
+			patch:  `[{"op":"remove","path":"/b"},{"op":"remove","path":"/c"},{"op":"remove","path":"/a"}]`,
+			expect: `{}`,
+		},
+		{
+			doc:    `{"a":{"x\"y":"z"},"b":[]}`,
+			patch:  `[{"op":"move","path":"/b/-","from":"/a/x\"y"},{"op":"copy","path":"/c","from":"/b"}]`,
+			expect: `{"a":{},"b":["z"],"c":["z"]}`,
+		},
+		{
+			doc:    `[1,{"a":[true,null]}]`,
+			patch:  `[{"op":"test","path":"/1/a","value":[true,null]},{"op":"replace","path":"/0","value":"x"}]`,
+			expect: `["x",{"a":[true,null]}]`,
+		},
+	}
+	p := New(WithRawEngine(true))
+	for _, c := range cases {
+		var ops []Operation
+		if err := json.Unmarshal([]byte(c.patch), &ops); err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.Apply([]byte(c.doc), ops)
+		if err != nil {
+			t.Fatal(c.patch, err)
+		}
+		if string(got) != c.expect+"\n" {
+			t.Fatal("expected", c.expect, "got", string(got))
+		}
+	}
+}
+
+func TestRawEngineOutputParity(t *testing.T) {
+	doc := []byte(`{"a":[1,2,{"b":"c"}],"d":{"f":null},"g":"<h>"}`)
+	ops := mustOperations(t, `[{"op":"replace","path":"/g","value":"<i>"},{"op":"remove","path":"/a/1"},{"op":"test","path":"/d/f","value":null}]`)
+	for _, escape := range []bool{false, true} {
+		expect, err := New(WithJSONEscapeHTML(escape)).Apply(doc, ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := New(WithJSONEscapeHTML(escape), WithRawEngine(true)).Apply(doc, ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(expect) {
+			t.Fatalf("expected %q, got %q", expect, got)
+		}
+	}
+}
+
+func TestRawEngineJSONPathFrom(t *testing.T) {
+	ops := mustOperations(t, `[{"op":"copy","from":"$.a","path":"/b"}]`)
+	for _, p := range []*Patch{New(WithJSONPathPaths(true)), New(WithJSONPathPaths(true), WithRawEngine(true))} {
+		out, err := p.Apply([]byte(`{"a":1}`), ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := Equal(out, []byte(`{"a":1,"b":1}`)); err != nil || !ok {
+			t.Fatal("bad output", string(out), err)
+		}
+	}
+}
+
+func TestRawEngineErrors(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"test","path":"/a","value":2}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(WithRawEngine(true)).Apply([]byte(`{"a":1}`), ops); !errors.Is(err, ErrStop) {
+		t.Fatal("expected stop, got", err)
+	}
+	if err := json.Unmarshal([]byte(`[{"op":"remove","path":"/a/b"}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(WithRawEngine(true)).Apply([]byte(`{"a":1}`), ops); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := New(WithRawEngine(true)).Apply([]byte(`{"a":`), ops); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRawEngineRandom(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"a":[1,2,{"b":"c"}],"d/e":{"f":null},"g":"h"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := New(WithRawEngine(true))
+	for seed := int64(0); seed < 200; seed++ {
+		ops := GenerateRandomPatch(doc, 10, seed)
+		expect, err := New().Apply(b, copyOperations(ops))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := raw.Apply(b, ops)
+		if err != nil {
+			t.Fatal(seed, err)
+		}
+		var e, g any
+		if err := json.Unmarshal(expect, &e); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(got, &g); err != nil {
+			t.Fatal(seed, string(got), err)
+		}
+		if !reflect.DeepEqual(e, g) {
+			t.Fatal("seed", seed, "expected", string(expect), "got", string(got))
+		}
+	}
+}
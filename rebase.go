@@ -0,0 +1,147 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrPatchConflict is returned by Rebase if the patches conflict.
+var ErrPatchConflict = errors.New("patches conflict")
+
+// Conflicts returns the conflicts of patches a and b created against the same document.
+// Two operations conflict if a path of one equals to or is an ancestor of a path of the other,
+// unless both are test operations or test extensions.
+// Ours and Theirs of a conflict are the operations of a and b, Path is the shorter path.
+//
+// Array indices are compared after shifting by the inserts and removes of the other patch,
+// and tokens look like an array index are treated as an array index.
+func Conflicts(a, b []Operation) []Conflict {
+	_, conflicts := rebase(a, b)
+	return conflicts
+}
+
+// Rebase transforms a to apply after b, both are created against the same document.
+// The array indices of a are shifted by the inserts and removes of b.
+// It returns an error wraps ErrPatchConflict if Conflicts is not empty.
+func Rebase(a, b []Operation) ([]Operation, error) {
+	ops, conflicts := rebase(a, b)
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %d conflicts, the first is at %s", ErrPatchConflict, len(conflicts), conflicts[0].Path)
+	}
+	return ops, nil
+}
+
+// rebase transforms every operation of a past b, while b is transformed past it
+// so b is always at the same base of the next operation of a.
+func rebase(a, b []Operation) ([]Operation, []Conflict) {
+	ops := copyOperations(a)
+	theirs := append([]Operation(nil), b...)
+	var conflicts []Conflict
+	for i := range ops {
+		x := ops[i]
+		for j, y := range theirs {
+			if path, ok := overlap(x, y); ok {
+				conflicts = append(conflicts, Conflict{Path: path, Ours: a[i], Theirs: b[j]})
+				continue
+			}
+			theirs[j] = shiftOperation(y, x)
+			x = shiftOperation(x, y)
+		}
+		ops[i] = x
+	}
+	return ops, conflicts
+}
+
+// overlap returns the shorter path if a path of x equals to or is an ancestor of a path of y.
+func overlap(x, y Operation) (string, bool) {
+	if x.OP != nil && y.OP != nil && isTestOP(*x.OP) && isTestOP(*y.OP) {
+		return "", false
+	}
+	for _, p := range touchedPaths(x) {
+		for _, q := range touchedPaths(y) {
+			if isPathPrefix(p, q) {
+				return p, true
+			}
+			if isPathPrefix(q, p) {
+				return q, true
+			}
+		}
+	}
+	return "", false
+}
+
+func touchedPaths(op Operation) []string {
+	var r []string
+	if op.Path != nil {
+		r = append(r, *op.Path)
+	}
+	if op.From != nil {
+		r = append(r, *op.From)
+	}
+	return r
+}
+
+// shiftOperation returns x with array indices shifted by the inserts and removes of y.
+func shiftOperation(x, y Operation) Operation {
+	if y.OP == nil || y.Path == nil {
+		return x
+	}
+	var edits []arrayEdit
+	switch *y.OP {
+	case opAdd, opCopy:
+		edits = []arrayEdit{{*y.Path, 1}}
+	case opRemove:
+		edits = []arrayEdit{{*y.Path, -1}}
+	case opMove:
+		if y.From != nil {
+			edits = []arrayEdit{{*y.From, -1}, {*y.Path, 1}}
+		}
+	}
+	for _, e := range edits {
+		if x.Path != nil {
+			path := e.shift(*x.Path)
+			x.Path = &path
+		}
+		if x.From != nil {
+			from := e.shift(*x.From)
+			x.From = &from
+		}
+	}
+	return x
+}
+
+// arrayEdit is an insert or a remove of an array element at path.
+type arrayEdit struct {
+	path  string
+	delta int
+}
+
+// shift returns ptr with the index of the edited array shifted.
+func (e arrayEdit) shift(ptr string) string {
+	at := NewJSONPointer(e.path).Path()
+	if len(at) == 0 || !indexRE.MatchString(at[len(at)-1]) {
+		return ptr
+	}
+	parent := len(at) - 1
+	tokens := NewJSONPointer(ptr).Path()
+	if len(tokens) <= parent || !indexRE.MatchString(tokens[parent]) {
+		return ptr
+	}
+	for k := 0; k < parent; k++ {
+		if tokens[k] != at[k] {
+			return ptr
+		}
+	}
+	i, _ := strconv.Atoi(at[parent])
+	j, _ := strconv.Atoi(tokens[parent])
+	if j > i || (j == i && e.delta > 0) {
+		tokens[parent] = strconv.Itoa(j + e.delta)
+		return joinPointer(tokens)
+	}
+	return ptr
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebase(t *testing.T) {
+	cases := []struct {
+		doc, a, b string
+		expect    string
+	}{
+		{
+			`{"arr":[0,1,2,3]}`,
+			`[{"op":"replace","path":"/arr/2","value":"x"},{"op":"add","path":"/arr/4","value":"y"}]`,
+			`[{"op":"remove","path":"/arr/0"},{"op":"add","path":"/arr/0","value":"a"},{"op":"add","path":"/arr/0","value":"b"}]`,
+			`[{"op":"replace","path":"/arr/3","value":"x"},{"op":"add","path":"/arr/5","value":"y"}]`,
+		},
+		{
+			`{"arr":[0,1,2],"o":{}}`,
+			`[{"op":"move","from":"/arr/2","path":"/o/x"}]`,
+			`[{"op":"move","from":"/arr/0","path":"/arr/1"},{"op":"add","path":"/o/y","value":1}]`,
+			`[{"op":"move","path":"/o/x","from":"/arr/2"}]`,
+		},
+		{
+			`{"arr":[0,1,2],"n":1}`,
+			`[{"op":"test","path":"/n","value":1},{"op":"remove","path":"/arr/2"}]`,
+			`[{"op":"test","path":"/n","value":1},{"op":"remove","path":"/arr/0"}]`,
+			`[{"op":"test","path":"/n","value":1},{"op":"remove","path":"/arr/1"}]`,
+		},
+	}
+	p := New()
+	for _, c := range cases {
+		a, b := mustOperations(t, c.a), mustOperations(t, c.b)
+		if conflicts := Conflicts(a, b); len(conflicts) != 0 {
+			t.Fatal(c.a, c.b, "unexpected conflicts", conflicts)
+		}
+		rebased, err := Rebase(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(jsonstring(rebased)); got != c.expect {
+			t.Fatal(c.a, c.b, "expected", c.expect, "got", got)
+		}
+		// b then rebased a equals to a then b rebased on a.
+		rebasedB, err := Rebase(b, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := p.Apply([]byte(c.doc), append(copyOperations(b), copyOperations(rebased)...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := p.Apply([]byte(c.doc), append(copyOperations(a), copyOperations(rebasedB)...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(x) != string(y) {
+			t.Fatal(c.a, c.b, "diverged", string(x), string(y))
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	a := mustOperations(t, `[{"op":"replace","path":"/a/b","value":1},{"op":"test","path":"/t","value":1},{"op":"add","path":"/arr/1","value":1}]`)
+	b := mustOperations(t, `[{"op":"remove","path":"/a"},{"op":"replace","path":"/t","value":2},{"op":"remove","path":"/arr/0"},{"op":"replace","path":"/arr/0","value":0}]`)
+	conflicts := Conflicts(a, b)
+	expect := []Conflict{
+		{Path: "/a", Ours: a[0], Theirs: b[0]},
+		{Path: "/t", Ours: a[1], Theirs: b[1]},
+		{Path: "/arr/0", Ours: a[2], Theirs: b[3]},
+	}
+	if !reflect.DeepEqual(conflicts, expect) {
+		t.Fatal("expected", jsonstring(expect), "got", jsonstring(conflicts))
+	}
+	if _, err := Rebase(a, b); !errors.Is(err, ErrPatchConflict) {
+		t.Fatal("expected conflict, got", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Recorder records the changes of a document and emits them as operations,
+// so a patch can be produced by mutating the document imperatively.
+// It's not safe for concurrent use.
+type Recorder struct {
+	snapshot any
+	doc      any
+	opts     []DiffOption
+}
+
+// NewRecorder creates a recorder of doc, a value decoded by encoding/json.
+// doc is snapshotted, then it can be changed by the helpers of the recorder or directly.
+// opts are passed to CreatePatchAny by Operations.
+func NewRecorder(doc any, opts ...DiffOption) *Recorder {
+	return &Recorder{snapshot: deepCopy(doc), doc: doc, opts: opts}
+}
+
+// Doc returns the current document.
+func (r *Recorder) Doc() any {
+	return r.doc
+}
+
+// Get returns the value at path, the elements of path are object keys or array indices,
+// e.g. Get("users", 0, "name").
+// It returns an error wraps ErrNotExists if the value not exists.
+func (r *Recorder) Get(path ...any) (any, error) {
+	ptr, err := recorderPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	return ptr.Resolve(r.doc)
+}
+
+// Set sets the value at path as JSONPointer.Set does.
+func (r *Recorder) Set(value any, path ...any) error {
+	ptr, err := recorderPointer(path)
+	if err != nil {
+		return err
+	}
+	return ptr.Set(&r.doc, value)
+}
+
+// Delete deletes the value at path.
+func (r *Recorder) Delete(path ...any) error {
+	ptr, err := recorderPointer(path)
+	if err != nil {
+		return err
+	}
+	return ptr.Delete(&r.doc)
+}
+
+// Append appends the value to the array at path.
+func (r *Recorder) Append(value any, path ...any) error {
+	ptr, err := recorderPointer(path)
+	if err != nil {
+		return err
+	}
+	v, err := ptr.Resolve(r.doc)
+	if err != nil {
+		return err
+	}
+	if _, ok := v.([]any); !ok {
+		return errBadType("append", v)
+	}
+	return ptr.Join("-").Set(&r.doc, value)
+}
+
+// Operations returns the operations transform the snapshot into the current document.
+func (r *Recorder) Operations() []Operation {
+	return CreatePatchAny(r.snapshot, r.doc, r.opts...)
+}
+
+// Commit returns the Operations and takes a new snapshot of the current document,
+// so the next Operations only contains the later changes.
+func (r *Recorder) Commit() []Operation {
+	ops := r.Operations()
+	r.snapshot = deepCopy(r.doc)
+	return ops
+}
+
+func recorderPointer(path []any) (JSONPointer, error) {
+	tokens := make([]string, len(path))
+	for i, e := range path {
+		switch e := e.(type) {
+		case string:
+			tokens[i] = e
+		case int:
+			tokens[i] = strconv.Itoa(e)
+		default:
+			return JSONPointer{}, fmt.Errorf("path element must be a string or an int, got %T", e)
+		}
+	}
+	return NewJSONPointerFromTokens(tokens...), nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	doc := map[string]any{"users": []any{map[string]any{"name": "a"}}, "n": 1.0, "old": true}
+	r := NewRecorder(doc)
+	if v, err := r.Get("users", 0, "name"); err != nil || v != "a" {
+		t.Fatal("bad get", v, err)
+	}
+	steps := []error{
+		r.Set("b", "users", 0, "name"),
+		r.Append(map[string]any{"name": "c"}, "users"),
+		r.Delete("old"),
+		r.Set(map[string]any{}, "a/b"),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// direct changes are recorded too.
+	doc["n"] = 2.0
+	expect := `[{"op":"remove","path":"/old"},{"op":"add","path":"/a~1b","value":{}},{"op":"replace","path":"/n","value":2},{"op":"replace","path":"/users/0/name","value":"b"},{"op":"add","path":"/users/1","value":{"name":"c"}}]`
+	ops := r.Commit()
+	if got := strings.TrimSpace(jsonstring(ops)); got != expect {
+		t.Fatal("expected", expect, "got", got)
+	}
+	if ops := r.Operations(); len(ops) != 0 {
+		t.Fatal("expected no operations after commit", jsonstring(ops))
+	}
+
+	if err := r.Append(1, "n"); err == nil {
+		t.Fatal("expected error of appending to a number")
+	}
+	if _, err := r.Get("missing"); !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected not exists, got", err)
+	}
+	if err := r.Set(1, 1.5); err == nil {
+		t.Fatal("expected error of bad path element")
+	}
+	if !reflect.DeepEqual(r.Doc(), doc) {
+		t.Fatal("bad document", r.Doc())
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Redacted is the value used by MaskRedacted.
+const Redacted = "<redacted>"
+
+// MaskRedacted is a mask function replaces any value with Redacted.
+func MaskRedacted(any) any {
+	return Redacted
+}
+
+type redaction struct {
+	pattern []string
+	mask    func(v any) any
+}
+
+// WithRedaction registers a redaction policy.
+// Values at path are replaced by mask whenever operations are rendered by Describe
+// and RedactOperations, which are used for error messages.
+// A token of path may be "*" to match any member or index.
+func WithRedaction(path string, mask func(v any) any) Option {
+	return func(o *Patch) {
+		o.redactions = append(o.redactions, redaction{
+			pattern: NewJSONPointer(path).Path(),
+			mask:    mask,
+		})
+	}
+}
+
+// Describe returns a human readable description of the operation with values redacted.
+func (p *Patch) Describe(op Operation) string {
+	if op.OP == nil || op.Path == nil {
+		return fmt.Sprintf("invalid operation op=%v path=%v", ptrString(op.OP), ptrString(op.Path))
+	}
+	desc := p.description(p.extensions[*op.OP], op)
+	if op.Value != nil {
+		b, err := canonicalJSON(*p.redactOperation(op).Value)
+		if err != nil {
+			return fmt.Sprintf("%s value=%v", desc, err)
+		}
+		desc = fmt.Sprintf("%s value=%s", desc, b)
+	}
+	return p.annotate(desc, op)
+}
+
+func (p *Patch) description(ext Extension, op Operation) string {
+	if v, ok := ext.(Descriptor); ok {
+		return v.Description(p, op)
+	}
+	return fmt.Sprintf("%s %s", *op.OP, *op.Path)
+}
+
+func ptrString(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
+
+// RedactOperations returns a copy of ops whose values are redacted, it is useful to write audit records or logs.
+// The nested operations of if and foreach are redacted by their paths in the document,
+// the nested paths of foreach match the redacted paths of any element.
+func (p *Patch) RedactOperations(ops []Operation) []Operation {
+	r := make([]Operation, len(ops))
+	for i, op := range ops {
+		r[i] = p.redactOperation(op)
+	}
+	return r
+}
+
+func (p *Patch) redactOperation(op Operation) Operation {
+	if op.Path == nil || op.Value == nil {
+		return op
+	}
+	if op.OP != nil && isCompositeOP(*op.OP) {
+		// fn never fails.
+		r, _ := rewriteNested(op, func(sub Operation) (Operation, error) {
+			return p.redactOperation(sub), nil
+		})
+		return r
+	}
+	v := p.Redact(*op.Path, *op.Value)
+	op.Value = &v
+	return op
+}
+
+// Redact returns a copy of the value at path with the redaction policies applied.
+// The value is returned as is if no policy is registered.
+func (p *Patch) Redact(path string, v any) any {
+	if len(p.redactions) == 0 {
+		return v
+	}
+	at := NewJSONPointer(path).Path()
+	for _, r := range p.redactions {
+		// the value is inside of a redacted value.
+		if len(at) > len(r.pattern) && matchWildTokens(r.pattern, at[:len(r.pattern)]) {
+			return r.mask(v)
+		}
+	}
+	return p.redactNode(v, at)
+}
+
+func (p *Patch) redactNode(v any, at []string) any {
+	for _, r := range p.redactions {
+		if matchWildTokens(r.pattern, at) {
+			return r.mask(v)
+		}
+	}
+	switch n := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(n))
+		for k, e := range n {
+			c[k] = p.redactNode(e, append(at[:len(at):len(at)], k))
+		}
+		return c
+	case []any:
+		c := make([]any, len(n))
+		for i, e := range n {
+			c[i] = p.redactNode(e, append(at[:len(at):len(at)], strconv.Itoa(i)))
+		}
+		return c
+	default:
+		return v
+	}
+}
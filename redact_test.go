@@ -0,0 +1,74 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[
+		{"op":"add","path":"/users/0","value":{"name":"a","password":"p1"}},
+		{"op":"replace","path":"/token","value":"t1"},
+		{"op":"add","path":"/token/x"}
+	]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	p := New(
+		WithRedaction("/users/*/password", MaskRedacted),
+		WithRedaction("/token", MaskRedacted),
+	)
+	desc := p.Describe(ops[0])
+	if desc != `add /users/0 value={"name":"a","password":"<redacted>"}` {
+		t.Fatal("bad description", desc)
+	}
+	redacted := p.RedactOperations(ops)
+	if *redacted[1].Value != Redacted || *ops[1].Value != "t1" {
+		t.Fatal("bad redaction", *redacted[1].Value, *ops[1].Value)
+	}
+	err := p.Check(ops)
+	if err == nil || strings.Contains(err.Error(), "p1") {
+		t.Fatal("bad check error", err)
+	}
+	var doc any = map[string]any{"users": []any{}, "token": "t0"}
+	err = p.ApplyAny(&doc, ops[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing Operation
+	if err := json.Unmarshal([]byte(`{"op":"add","path":"/token/x","value":"t2"}`), &missing); err != nil {
+		t.Fatal(err)
+	}
+	if desc := p.Describe(missing); strings.Contains(desc, "t2") {
+		t.Fatal("value inside of a redacted path is not redacted", desc)
+	}
+}
+
+func TestRedactComposite(t *testing.T) {
+	p := New(WithRedaction("/users/*/password", MaskRedacted), WithExtension(IfExtension{}), WithExtension(ForeachExtension{}))
+	ops := mustOperations(t, `[
+		{"op":"foreach","path":"/users","value":[{"op":"add","path":"/password","value":"hunter2"}]},
+		{"op":"if","path":"/users/0","value":{"test":[{"op":"test","path":"/name","value":"a"}],"then":[{"op":"replace","path":"/password","value":"hunter3"}]}}
+	]`)
+	b, err := json.Marshal(p.RedactOperations(ops))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{"hunter2", "hunter3"} {
+		if strings.Contains(string(b), plain) {
+			t.Fatal(plain, "is not redacted", string(b))
+		}
+		for _, op := range ops {
+			if desc := p.Describe(op); strings.Contains(desc, plain) {
+				t.Fatal(plain, "is not redacted", desc)
+			}
+		}
+	}
+	if !strings.Contains(string(b), `"value":"a"`) {
+		t.Fatal("bad redaction", string(b))
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ResolveStruct returns the value at the JSONPointer of a go value, struct fields are matched
+// by their json tags as encoding/json does, including the fields of embedded structs.
+// Pointers and interfaces are dereferenced, maps must have string keys and
+// slices and arrays are indexed by array indices.
+// The value is settable if v is a pointer and the path does not pass a map.
+// It returns an error wraps ErrNotExists if the value not exists.
+func (p JSONPointer) ResolveStruct(v any) (reflect.Value, error) {
+	if err := p.Check(); err != nil {
+		return reflect.Value{}, err
+	}
+	rv := reflect.ValueOf(v)
+	for _, token := range p.Path() {
+		next, err := resolveToken(indirectValue(rv), token)
+		if err != nil {
+			return reflect.Value{}, errPathNotExists(p.origin, err)
+		}
+		rv = next
+	}
+	return indirectValue(rv), nil
+}
+
+// indirectValue dereferences pointers and interfaces until a nil or a concrete value.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+func resolveToken(v reflect.Value, token string) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if f, ok := structField(v, token); ok {
+			return f, nil
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("%w: map key must be string: %s", ErrNotExists, v.Type())
+		}
+		if e := v.MapIndex(reflect.ValueOf(token).Convert(v.Type().Key())); e.IsValid() {
+			return e, nil
+		}
+	case reflect.Slice, reflect.Array:
+		if !indexRE.MatchString(token) {
+			return reflect.Value{}, fmt.Errorf("%w: %v", ErrNotExists, errBadArrayIndex(token))
+		}
+		if i, err := strconv.Atoi(token); err == nil && i < v.Len() {
+			return v.Index(i), nil
+		}
+	case reflect.Invalid, reflect.Pointer, reflect.Interface:
+		// a nil value.
+	default:
+		return reflect.Value{}, fmt.Errorf("%w: cannot visit type: %s", ErrNotExists, v.Type())
+	}
+	return reflect.Value{}, ErrNotExists
+}
+
+// structField returns the field of v whose json name is name,
+// a field of v is preferred to a field of an embedded struct, and an exact match is preferred
+// to a case-insensitive match as encoding/json does.
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	var (
+		fold     reflect.Value
+		embedded []reflect.Value
+	)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && tagName == "" {
+			if e := indirectValue(v.Field(i)); e.Kind() == reflect.Struct {
+				embedded = append(embedded, e)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if tagName == "" {
+			tagName = f.Name
+		}
+		if tagName == name {
+			return v.Field(i), true
+		}
+		if !fold.IsValid() && strings.EqualFold(tagName, name) {
+			fold = v.Field(i)
+		}
+	}
+	if fold.IsValid() {
+		return fold, true
+	}
+	for _, e := range embedded {
+		if f, ok := structField(e, name); ok {
+			return f, true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+type resolveBase struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind"`
+}
+
+type resolveItem struct {
+	Name string `json:"name,omitempty"`
+}
+
+type resolveDoc struct {
+	resolveBase
+	Kind   string `json:"kind"`
+	Title  string `json:"title"`
+	Hidden string `json:"-"`
+	Plain  int
+	Items  []resolveItem           `json:"items"`
+	Meta   map[string]any          `json:"meta"`
+	Next   *resolveDoc             `json:"next"`
+	ByKey  map[string]*resolveItem `json:"by_key"`
+	hidden int
+}
+
+func TestResolveStruct(t *testing.T) {
+	doc := &resolveDoc{
+		resolveBase: resolveBase{ID: 1, Kind: "base"},
+		Kind:        "doc",
+		Title:       "t",
+		Items:       []resolveItem{{Name: "a"}, {Name: "b"}},
+		Meta:        map[string]any{"x": []any{1.0}},
+		ByKey:       map[string]*resolveItem{"k": {Name: "c"}},
+	}
+	cases := []struct {
+		path   string
+		expect any
+	}{
+		{"/id", 1},
+		{"/kind", "doc"},
+		{"/title", "t"},
+		{"/TITLE", "t"},
+		{"/Plain", 0},
+		{"/items/1/name", "b"},
+		{"/meta/x/0", 1.0},
+		{"/by_key/k/name", "c"},
+	}
+	for _, c := range cases {
+		v, err := NewJSONPointer(c.path).ResolveStruct(doc)
+		if err != nil {
+			t.Fatal(c.path, err)
+		}
+		if v.Interface() != c.expect {
+			t.Fatal(c.path, "unexpected value", v.Interface())
+		}
+	}
+
+	v, err := NewJSONPointer("/items/0/name").ResolveStruct(doc)
+	if err != nil || !v.CanSet() {
+		t.Fatal("field must be settable", err)
+	}
+	v.SetString("z")
+	if doc.Items[0].Name != "z" {
+		t.Fatal("field is not set")
+	}
+	v, err = NewJSONPointer("").ResolveStruct(doc)
+	if err != nil || v.Interface().(resolveDoc).Title != "t" {
+		t.Fatal("unexpected root", err)
+	}
+
+	for _, path := range []string{"/Hidden", "/hidden", "/items/2", "/items/-", "/next/title", "/meta/y", "/title/a"} {
+		if _, err := NewJSONPointer(path).ResolveStruct(doc); !errors.Is(err, ErrNotExists) {
+			t.Fatal(path, "expected not exists, got", err)
+		}
+	}
+}
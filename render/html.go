@@ -0,0 +1,105 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"html"
+)
+
+// Layout is the layout of the HTML view.
+type Layout int
+
+// Layouts of the HTML view.
+const (
+	// Inline shows a single tree with removed and replaced values inline.
+	Inline Layout = iota
+	// SideBySide shows the document before and after the patch in two columns.
+	SideBySide
+)
+
+// HTML renders the change tree as HTML.
+// Every node is a li element with the class of its kind, e.g. "jp-added", so it can be styled.
+func HTML(n *Node, layout Layout) []byte {
+	var buf bytes.Buffer
+	if layout == SideBySide {
+		buf.WriteString(`<table class="jp-diff"><tr><td class="jp-before">`)
+		writeSide(&buf, n, true)
+		buf.WriteString(`</td><td class="jp-after">`)
+		writeSide(&buf, n, false)
+		buf.WriteString(`</td></tr></table>`)
+		return buf.Bytes()
+	}
+	buf.WriteString(`<ul class="jp-diff">`)
+	writeInline(&buf, n)
+	buf.WriteString(`</ul>`)
+	return buf.Bytes()
+}
+
+func writeInline(buf *bytes.Buffer, n *Node) {
+	openItem(buf, n)
+	switch n.Kind {
+	case Modified:
+		buf.WriteString(`<ul>`)
+		for _, c := range n.Children {
+			writeInline(buf, c)
+		}
+		buf.WriteString(`</ul>`)
+	case Added:
+		writeValue(buf, "ins", n.After)
+	case Removed:
+		writeValue(buf, "del", n.Before)
+	case Replaced:
+		writeValue(buf, "del", n.Before)
+		buf.WriteString(" ")
+		writeValue(buf, "ins", n.After)
+	default:
+		writeValue(buf, "span", n.After)
+	}
+	buf.WriteString(`</li>`)
+}
+
+// writeSide renders one side of the document, nodes missing on the side are skipped.
+func writeSide(buf *bytes.Buffer, n *Node, before bool) {
+	buf.WriteString(`<ul>`)
+	writeSideNode(buf, n, before)
+	buf.WriteString(`</ul>`)
+}
+
+func writeSideNode(buf *bytes.Buffer, n *Node, before bool) {
+	if (before && n.Kind == Added) || (!before && n.Kind == Removed) {
+		return
+	}
+	openItem(buf, n)
+	if n.Kind == Modified {
+		buf.WriteString(`<ul>`)
+		for _, c := range n.Children {
+			writeSideNode(buf, c, before)
+		}
+		buf.WriteString(`</ul>`)
+	} else if before {
+		writeValue(buf, "span", n.Before)
+	} else {
+		writeValue(buf, "span", n.After)
+	}
+	buf.WriteString(`</li>`)
+}
+
+func openItem(buf *bytes.Buffer, n *Node) {
+	buf.WriteString(`<li class="jp-`)
+	buf.WriteString(n.Kind.String())
+	buf.WriteString(`">`)
+	if n.Key != "" {
+		buf.WriteString(`<span class="jp-key">`)
+		buf.WriteString(html.EscapeString(n.Key))
+		buf.WriteString(`</span>: `)
+	}
+}
+
+func writeValue(buf *bytes.Buffer, tag string, v any) {
+	buf.WriteString("<" + tag + ` class="jp-value">`)
+	buf.WriteString(html.EscapeString(jsonString(v)))
+	buf.WriteString("</" + tag + ">")
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package render renders the effects of a json patch on a document for human review.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+// Kind is the kind of change of a node.
+type Kind int
+
+// Kinds of change.
+const (
+	Unchanged Kind = iota
+	Added
+	Removed
+	Replaced
+	// Modified is a container that some of its children are changed.
+	Modified
+)
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Replaced:
+		return "replaced"
+	case Modified:
+		return "modified"
+	default:
+		return "unchanged"
+	}
+}
+
+// Node is a node of the change tree of a document.
+type Node struct {
+	// Key is the member name or the array index of the node, it is empty for the root.
+	Key  string
+	Kind Kind
+	// Before is the value before the patch, it is nil for added nodes.
+	Before any
+	// After is the value after the patch, it is nil for removed nodes.
+	After any
+	// Children are the children of a modified container.
+	Children []*Node
+	// Array is true if the node is a modified array.
+	Array bool
+}
+
+// Apply applies ops to a copy of doc with p and returns the change tree.
+// doc is not modified.
+func Apply(p *jsonpatch.Patch, doc any, ops []jsonpatch.Operation) (*Node, error) {
+	after, _, err := p.Simulate(doc, ops)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(doc, after), nil
+}
+
+// Diff returns the change tree from before to after.
+// Array elements are compared by index.
+func Diff(before, after any) *Node {
+	return diff("", before, after)
+}
+
+func diff(key string, before, after any) *Node {
+	n := &Node{Key: key, Before: before, After: after}
+	switch b := before.(type) {
+	case map[string]any:
+		a, ok := after.(map[string]any)
+		if !ok {
+			break
+		}
+		for _, k := range unionKeys(b, a) {
+			bv, bok := b[k]
+			av, aok := a[k]
+			n.add(member(k, bv, bok, av, aok))
+		}
+		return n
+	case []any:
+		a, ok := after.([]any)
+		if !ok {
+			break
+		}
+		n.Array = true
+		for i := 0; i < len(b) || i < len(a); i++ {
+			var bv, av any
+			if i < len(b) {
+				bv = b[i]
+			}
+			if i < len(a) {
+				av = a[i]
+			}
+			n.add(member(strconv.Itoa(i), bv, i < len(b), av, i < len(a)))
+		}
+		return n
+	}
+	if !reflect.DeepEqual(before, after) {
+		n.Kind = Replaced
+	}
+	return n
+}
+
+func member(key string, before any, hasBefore bool, after any, hasAfter bool) *Node {
+	switch {
+	case !hasBefore:
+		return &Node{Key: key, Kind: Added, After: after}
+	case !hasAfter:
+		return &Node{Key: key, Kind: Removed, Before: before}
+	default:
+		return diff(key, before, after)
+	}
+}
+
+func (n *Node) add(c *Node) {
+	n.Children = append(n.Children, c)
+	if c.Kind != Unchanged {
+		n.Kind = Modified
+	}
+}
+
+func unionKeys(a, b map[string]any) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonString(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err.Error()
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
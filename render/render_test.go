@@ -0,0 +1,68 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func apply(t *testing.T, doc, patch string) *Node {
+	t.Helper()
+	var (
+		d   any
+		ops []jsonpatch.Operation
+	)
+	if err := json.Unmarshal([]byte(doc), &d); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(patch), &ops); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Apply(jsonpatch.New(), d, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDiff(t *testing.T) {
+	n := apply(t, `{"a":1,"b":[1,2],"c":"<x>"}`, `[
+		{"op":"replace","path":"/a","value":2},
+		{"op":"remove","path":"/b/1"},
+		{"op":"add","path":"/d","value":true}
+	]`)
+	if n.Kind != Modified || len(n.Children) != 4 {
+		t.Fatalf("bad root: %+v", n)
+	}
+	kinds := []Kind{Replaced, Modified, Unchanged, Added}
+	for i, c := range n.Children {
+		if c.Kind != kinds[i] {
+			t.Fatal(c.Key, "expected", kinds[i], "got", c.Kind)
+		}
+	}
+	if b := n.Children[1]; !b.Array || b.Children[1].Kind != Removed {
+		t.Fatalf("bad array: %+v", b)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	n := apply(t, `{"a":1,"c":"<x>"}`, `[{"op":"replace","path":"/a","value":2}]`)
+	inline := string(HTML(n, Inline))
+	for _, s := range []string{
+		`<li class="jp-replaced"><span class="jp-key">a</span>: <del class="jp-value">1</del> <ins class="jp-value">2</ins></li>`,
+		`&#34;&lt;x&gt;&#34;`,
+	} {
+		if !strings.Contains(inline, s) {
+			t.Fatal("expected", s, "in", inline)
+		}
+	}
+	side := string(HTML(n, SideBySide))
+	if !strings.Contains(side, `<td class="jp-before"><ul><li class="jp-modified"><ul><li class="jp-replaced"><span class="jp-key">a</span>: <span class="jp-value">1</span>`) {
+		t.Fatal("bad side by side view", side)
+	}
+}
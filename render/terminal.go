@@ -0,0 +1,86 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// Terminal renders the change tree as an indented document for terminals.
+// Every line starts with a marker: "+" for added, "-" for removed and "~" for replaced values,
+// replaced values are shown as before → after.
+// If color is true, the lines are colored with ANSI escape codes,
+// removals in red, additions in green and replacements in yellow.
+func Terminal(n *Node, color bool) []byte {
+	t := terminal{color: color}
+	t.node(n, "", 0, false)
+	return t.buf.Bytes()
+}
+
+type terminal struct {
+	buf   bytes.Buffer
+	color bool
+}
+
+func (t *terminal) node(n *Node, key string, depth int, comma bool) {
+	switch n.Kind {
+	case Added:
+		t.line('+', ansiGreen, depth, key+jsonString(n.After), comma)
+	case Removed:
+		t.line('-', ansiRed, depth, key+jsonString(n.Before), comma)
+	case Replaced:
+		t.line('~', ansiYellow, depth, key+jsonString(n.Before)+" → "+jsonString(n.After), comma)
+	case Modified:
+		open, end := "{", "}"
+		if n.Array {
+			open, end = "[", "]"
+		}
+		t.line(' ', "", depth, key+open, false)
+		for i, c := range n.Children {
+			var k string
+			if !n.Array {
+				k = quote(c.Key) + ": "
+			}
+			t.node(c, k, depth+1, i < len(n.Children)-1)
+		}
+		t.line(' ', "", depth, end, comma)
+	default:
+		t.line(' ', "", depth, key+jsonString(n.After), comma)
+	}
+}
+
+func (t *terminal) line(marker byte, color string, depth int, s string, comma bool) {
+	if t.color && color != "" {
+		t.buf.WriteString(color)
+	}
+	t.buf.WriteByte(marker)
+	t.buf.WriteByte(' ')
+	t.buf.WriteString(strings.Repeat("  ", depth))
+	t.buf.WriteString(s)
+	if comma {
+		t.buf.WriteByte(',')
+	}
+	if t.color && color != "" {
+		t.buf.WriteString(ansiReset)
+	}
+	t.buf.WriteByte('\n')
+}
+
+func quote(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return s
+	}
+	return string(b)
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerminal(t *testing.T) {
+	n := apply(t, `{"a":1,"b":[1,2]}`, `[
+		{"op":"replace","path":"/a","value":2},
+		{"op":"remove","path":"/b/1"},
+		{"op":"add","path":"/c","value":{"d":true}}
+	]`)
+	expect := strings.Join([]string{
+		`  {`,
+		`~   "a": 1 → 2,`,
+		`    "b": [`,
+		`      1,`,
+		`-     2`,
+		`    ],`,
+		`+   "c": {"d":true}`,
+		`  }`,
+		``,
+	}, "\n")
+	if got := string(Terminal(n, false)); got != expect {
+		t.Fatalf("expected\n%s\ngot\n%s", expect, got)
+	}
+	colored := string(Terminal(n, true))
+	if !strings.Contains(colored, ansiRed+"-     2"+ansiReset) || !strings.Contains(colored, ansiGreen+"+ ") {
+		t.Fatalf("bad colored output\n%q", colored)
+	}
+}
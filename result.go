@@ -0,0 +1,69 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+// OperationResult is the result of an applied operation.
+type OperationResult struct {
+	// Index is the index of the operation in the patch.
+	Index int
+	OP    string
+	Path  string
+	// Skipped is true if the operation is skipped because the path does not exist.
+	// It happens only if StrictPathExists is false.
+	Skipped bool
+	// Label and Comment are the annotations of the operation if Annotations is true.
+	Label   string
+	Comment string
+}
+
+// Result is a report of applying a patch.
+type Result struct {
+	Operations []OperationResult
+}
+
+// Applied returns the number of operations that are not skipped.
+func (r *Result) Applied() int {
+	var n int
+	for _, o := range r.Operations {
+		if !o.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// Skipped returns the number of skipped operations.
+func (r *Result) Skipped() int {
+	return len(r.Operations) - r.Applied()
+}
+
+func (r *Result) record(i int, op Operation, skipped, annotations bool) {
+	if r == nil {
+		return
+	}
+	res := OperationResult{
+		Index:   i,
+		OP:      *op.OP,
+		Path:    *op.Path,
+		Skipped: skipped,
+	}
+	if annotations {
+		res.Label = annotation(op.Label)
+		res.Comment = annotation(op.Comment)
+	}
+	r.Operations = append(r.Operations, res)
+}
+
+// Simulate apply the operations to a copy of doc and returns the projected document and the result report.
+// Neither doc nor ops is modified, even if the patch fails.
+func (p *Patch) Simulate(doc any, ops []Operation) (any, *Result, error) {
+	o := deepCopy(doc)
+	r := &Result{}
+	// the values are inserted by reference, copy them so the later operations do not modify ops.
+	if err := p.apply(&o, copyOperations(ops), r); err != nil {
+		return nil, r, err
+	}
+	return o, r, nil
+}
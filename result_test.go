@@ -0,0 +1,56 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	var (
+		doc any
+		ops []Operation
+	)
+	if err := json.Unmarshal([]byte(`{"a":{"b":[1,2]}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`[
+		{"op":"add","path":"/a/b/-","value":3},
+		{"op":"remove","path":"/x/y"},
+		{"op":"replace","path":"/a/c","value":1}
+	]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	origin := deepCopy(doc)
+	got, r, err := New(WithStrictPathExists(false)).Simulate(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, origin) {
+		t.Fatal("document is modified")
+	}
+	expect := map[string]any{"a": map[string]any{"b": []any{1.0, 2.0, 3.0}, "c": 1.0}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatal("expected", expect, "got", got)
+	}
+	if r.Applied() != 2 || r.Skipped() != 1 || !r.Operations[1].Skipped {
+		t.Fatalf("bad result: %+v", r)
+	}
+}
+
+func TestSimulateKeepsOperations(t *testing.T) {
+	ops := mustOperations(t, `[{"op":"add","path":"/a","value":{"x":1}},{"op":"remove","path":"/a/x"}]`)
+	got, _, err := New().Simulate(map[string]any{}, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]any{"a": map[string]any{}}) {
+		t.Fatal("unexpected document", got)
+	}
+	if !reflect.DeepEqual(*ops[0].Value, map[string]any{"x": 1.0}) {
+		t.Fatal("operation is modified", *ops[0].Value)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBadResumeToken is returned if a resume token is not created by the same patch and document.
+var ErrBadResumeToken = errors.New("bad resume token")
+
+// ResumeToken is the position a Resumable apply stopped at.
+// It can be encoded as json and stored until the apply continues.
+type ResumeToken struct {
+	// Index is the index of the next operation.
+	Index int `json:"index"`
+	// Patch is the canonical hash of the operations.
+	Patch string `json:"patch"`
+	// Document is the canonical hash of the document the apply stopped with.
+	Document string `json:"document"`
+}
+
+// Resumable applies a large patch in chunks, every call stops cleanly at a limit
+// and returns a ResumeToken to continue from later.
+// The OriginalArrayIndex option is not supported since its state is not kept across calls.
+type Resumable struct {
+	// Patch applies the operations. New() is used if it's nil.
+	Patch *Patch
+	// MaxOperations is the max number of operations applied by a call, 0 means no limit.
+	MaxOperations int
+	// Timeout is the max duration of a call, 0 means no limit.
+	// The call also stops if ctx is done.
+	Timeout time.Duration
+}
+
+// Apply applies ops to the json document doc from token, or from the first operation if token is nil.
+// It returns the document and a nil token if all operations are applied,
+// otherwise the document with the applied operations and the token to continue with.
+// doc must be the document returned with token, otherwise an error wraps ErrBadResumeToken is returned.
+// The whole patch is checked on every call, and operations of a call are applied atomically.
+func (r *Resumable) Apply(ctx context.Context, doc []byte, ops []Operation, token *ResumeToken) ([]byte, *ResumeToken, error) {
+	p := r.Patch
+	if p == nil {
+		p = New()
+	}
+	if p.OriginalArrayIndex {
+		return nil, nil, errors.New("resumable apply does not support OriginalArrayIndex")
+	}
+	ops, err := p.fromURIFragments(ops)
+	if err != nil {
+		return nil, nil, p.localize(err)
+	}
+	if err := p.Check(ops); err != nil {
+		return nil, nil, err
+	}
+	patchHash, err := canonicalHash(ops)
+	if err != nil {
+		return nil, nil, err
+	}
+	var o any
+	if err := json.Unmarshal(doc, &o); err != nil {
+		return nil, nil, err
+	}
+	start, err := resumeIndex(token, patchHash, o, len(ops))
+	if err != nil {
+		return nil, nil, err
+	}
+	end, err := r.apply(ctx, p, &o, ops, start)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	if err := p.EncodeOptions().encode(&buf, o); err != nil {
+		return nil, nil, err
+	}
+	p.route(ops[start:end])
+	if end == len(ops) {
+		return buf.Bytes(), nil, nil
+	}
+	docHash, err := canonicalHash(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), &ResumeToken{Index: end, Patch: patchHash, Document: docHash}, nil
+}
+
+// resumeIndex returns the index of the first operation to apply.
+func resumeIndex(token *ResumeToken, patchHash string, doc any, n int) (int, error) {
+	if token == nil {
+		return 0, nil
+	}
+	if token.Patch != patchHash {
+		return 0, fmt.Errorf("%w: the patch is changed", ErrBadResumeToken)
+	}
+	if token.Index < 0 || token.Index > n {
+		return 0, fmt.Errorf("%w: index %d out of range", ErrBadResumeToken, token.Index)
+	}
+	docHash, err := canonicalHash(doc)
+	if err != nil {
+		return 0, err
+	}
+	if token.Document != docHash {
+		return 0, fmt.Errorf("%w: the document is changed", ErrBadResumeToken)
+	}
+	return token.Index, nil
+}
+
+// apply applies the operations from start until a limit and returns the index of the next operation.
+func (r *Resumable) apply(ctx context.Context, p *Patch, o *any, ops []Operation, start int) (int, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	for i := start; i < len(ops); i++ {
+		if ctx.Err() != nil || (r.MaxOperations > 0 && i-start >= r.MaxOperations) {
+			return i, nil
+		}
+		op := ops[i]
+		ext := p.extensions[*op.OP]
+		err := p.applyOne(o, ext, op, nil)
+		if err != nil && !(!p.StrictPathExists && errors.Is(err, ErrNotExists)) {
+			return 0, p.operationError(ext, op, err)
+		}
+	}
+	return len(ops), nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResumable(t *testing.T) {
+	ctx := context.Background()
+	var ops []Operation
+	for i := 0; i < 10; i++ {
+		ops = append(ops, newOperation(opAdd, "/a/-", float64(i), nil))
+	}
+	r := &Resumable{MaxOperations: 3}
+	doc := []byte(`{"a":[]}`)
+	var (
+		token *ResumeToken
+		calls int
+		err   error
+	)
+	for {
+		doc, token, err = r.Apply(ctx, doc, ops, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls++
+		if token == nil {
+			break
+		}
+		if token.Index != calls*3 {
+			t.Fatal("bad index", token.Index)
+		}
+		// the token survives a round trip of json.
+		b, _ := json.Marshal(token)
+		token = nil
+		if err := json.Unmarshal(b, &token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 4 {
+		t.Fatal("expected 4 calls, got", calls)
+	}
+	if s := strings.TrimSpace(string(doc)); s != `{"a":[0,1,2,3,4,5,6,7,8,9]}` {
+		t.Fatal("bad document", s)
+	}
+
+	out, token, err := r.Apply(ctx, []byte(`{"a":[]}`), ops, nil)
+	if err != nil || token == nil {
+		t.Fatal(token, err)
+	}
+	if _, _, err := r.Apply(ctx, []byte(`{"a":[]}`), ops, token); !errors.Is(err, ErrBadResumeToken) {
+		t.Fatal("expected bad token of changed document, got", err)
+	}
+	if _, _, err := r.Apply(ctx, out, ops[1:], token); !errors.Is(err, ErrBadResumeToken) {
+		t.Fatal("expected bad token of changed patch, got", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	out, token, err = r.Apply(cancelled, []byte(`{"a":[]}`), ops, nil)
+	if err != nil || token == nil || token.Index != 0 || strings.TrimSpace(string(out)) != `{"a":[]}` {
+		t.Fatal("expected to stop before the first operation", string(out), token, err)
+	}
+
+	bad := append(copyOperations(ops[:2]), newOperation(opRemove, "/b", nil, nil))
+	if _, _, err := (&Resumable{}).Apply(ctx, []byte(`{"a":[]}`), bad, nil); err == nil || !strings.Contains(err.Error(), "/b") {
+		t.Fatal("expected error of missing member, got", err)
+	}
+}
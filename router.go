@@ -0,0 +1,142 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"sync"
+)
+
+// RouteFunc receives the operations of a successful apply related to its pattern.
+type RouteFunc func(ops []Operation)
+
+// Router dispatches applied operations to the callbacks registered on pointer patterns.
+// It's safe for concurrent use.
+type Router struct {
+	mu     sync.RWMutex
+	routes []*route
+}
+
+type route struct {
+	pattern []string
+	fn      RouteFunc
+}
+
+// WithRouter set the router notified after every successful Apply, ApplyAny, ApplyTo and ApplyEncoded.
+// Simulate never notifies the router.
+func WithRouter(r *Router) Option {
+	return func(o *Patch) {
+		o.router = r
+	}
+}
+
+// NewRouter creates an empty Router.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers fn on pattern and returns a function to unregister it.
+// pattern is a json pointer, a "*" token matches any token and
+// a trailing "**" token matches any number of tokens, e.g. "/spec/containers/**".
+// An operation is related to the pattern if its path or from matches the pattern,
+// or it is an ancestor of the pattern, which replaces the whole subtree.
+func (r *Router) Handle(pattern string, fn RouteFunc) (cancel func()) {
+	rt := &route{pattern: NewJSONPointer(pattern).Path(), fn: fn}
+	r.mu.Lock()
+	r.routes = append(r.routes, rt)
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, v := range r.routes {
+			if v == rt {
+				r.routes = append(r.routes[:i:i], r.routes[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Dispatch calls the callbacks with the operations related to their patterns.
+// Callbacks with no related operation are not called.
+// A "*" token of the operations matches any token,
+// and an operation with a "**" token is related to the patterns below the tokens before it.
+func (r *Router) Dispatch(ops []Operation) {
+	r.dispatch(nil, ops)
+}
+
+// dispatch is Dispatch but the patterns of the paths of ops are resolved by the options of p,
+// e.g. selectors and JSONPath expressions.
+func (r *Router) dispatch(p *Patch, ops []Operation) {
+	r.mu.RLock()
+	routes := append([]*route(nil), r.routes...)
+	r.mu.RUnlock()
+	for _, rt := range routes {
+		var matched []Operation
+		for _, op := range ops {
+			if rt.related(p, op) {
+				matched = append(matched, op)
+			}
+		}
+		if len(matched) > 0 {
+			rt.fn(matched)
+		}
+	}
+}
+
+func (rt *route) related(p *Patch, op Operation) bool {
+	if op.Path != nil && relatedTokens(rt.pattern, routeTokens(p, *op.Path)) {
+		return true
+	}
+	return op.From != nil && relatedTokens(rt.pattern, routeTokens(p, *op.From))
+}
+
+// routeTokens returns the tokens of path whose patterns are replaced by "*" and "**".
+func routeTokens(p *Patch, path string) []string {
+	if p == nil {
+		return NewJSONPointer(path).Path()
+	}
+	if p.isJSONPath(path) {
+		jp, err := compileJSONPath(path)
+		if err != nil {
+			// the expression is checked before applied, any node may be written otherwise.
+			return []string{"**"}
+		}
+		return jp.pattern()
+	}
+	tokens := NewJSONPointer(path).Path()
+	for i, token := range tokens {
+		if p.isSelector(token) {
+			tokens[i] = "*"
+		}
+	}
+	return tokens
+}
+
+// relatedTokens returns true if tokens matches pattern or tokens is an ancestor of pattern.
+func relatedTokens(pattern, tokens []string) bool {
+	// tokens may match any node below the tokens before a "**".
+	for i, token := range tokens {
+		if token == "**" {
+			tokens = tokens[:i]
+			break
+		}
+	}
+	if n := len(pattern); n > 0 && pattern[n-1] == "**" {
+		pattern = pattern[:n-1]
+		if len(tokens) >= len(pattern) {
+			return matchWildTokens(pattern, tokens[:len(pattern)])
+		}
+	}
+	if len(tokens) > len(pattern) {
+		return false
+	}
+	return matchWildTokens(pattern[:len(tokens)], tokens)
+}
+
+func (p *Patch) route(ops []Operation) {
+	if p.router != nil {
+		p.router.dispatch(p, ops)
+	}
+}
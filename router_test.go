@@ -0,0 +1,94 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+	got := map[string][]string{}
+	handle := func(pattern string) func() {
+		return r.Handle(pattern, func(ops []Operation) {
+			for _, op := range ops {
+				got[pattern] = append(got[pattern], *op.Path)
+			}
+		})
+	}
+	handle("/spec/containers/**")
+	handle("/spec/*/name")
+	cancel := handle("/status")
+	cancel()
+
+	p := New(WithRouter(r))
+	doc := []byte(`{"spec":{"containers":[{"name":"a"}],"volumes":[]},"status":{}}`)
+	_, err := p.Apply(doc, mustOperations(t, `[
+		{"op":"replace","path":"/spec/containers/0/name","value":"b"},
+		{"op":"add","path":"/spec/volumes/-","value":1},
+		{"op":"add","path":"/status/ready","value":true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got["/spec/containers/**"]) != 1 || got["/spec/containers/**"][0] != "/spec/containers/0/name" {
+		t.Fatal("unexpected routes", got)
+	}
+
+	got = map[string][]string{}
+	_, err = p.Apply(doc, mustOperations(t, `[{"op":"replace","path":"/spec","value":{"x":{"name":1}}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got["/spec/containers/**"]) != 1 || len(got["/spec/*/name"]) != 1 {
+		t.Fatal("expected ancestor operation routed", got)
+	}
+
+	got = map[string][]string{}
+	var o any = map[string]any{}
+	if _, _, err := p.Simulate(o, mustOperations(t, `[{"op":"add","path":"/spec","value":1}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ApplyAny(&o, mustOperations(t, `[{"op":"test","path":"/x","value":1}]`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(got) != 0 {
+		t.Fatal("unexpected routes", got)
+	}
+}
+
+func TestRouterPatterns(t *testing.T) {
+	r := NewRouter()
+	got := map[string]int{}
+	for _, pattern := range []string{"/secret/k", "/public/k", "/users/0/name", "/other"} {
+		pattern := pattern
+		r.Handle(pattern, func(ops []Operation) { got[pattern] += len(ops) })
+	}
+	doc := []byte(`{"secret":{"k":1},"public":{"k":2},"users":[{"id":1,"name":"a"}],"other":0}`)
+	cases := []struct {
+		opt   Option
+		ops   string
+		paths []string
+	}{
+		{WithWildcards(true), `[{"op":"remove","path":"/*/k"}]`, []string{"/secret/k", "/public/k"}},
+		{WithRecursiveWildcards(true), `[{"op":"replace","path":"/**/name","value":"b"}]`, []string{"/secret/k", "/public/k", "/users/0/name", "/other"}},
+		{WithElementSelectors(true), `[{"op":"replace","path":"/users/[id=1]/name","value":"b"}]`, []string{"/users/0/name"}},
+		{WithJSONPathPaths(true), `[{"op":"replace","path":"$.secret.k","value":3}]`, []string{"/secret/k"}},
+		{WithJSONPathPaths(true), `[{"op":"replace","path":"$.users[*].name","value":"b"}]`, []string{"/users/0/name"}},
+	}
+	for _, c := range cases {
+		got = map[string]int{}
+		if _, err := New(c.opt, WithRouter(r)).Apply(doc, mustOperations(t, c.ops)); err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if len(got) != len(c.paths) {
+			t.Fatal(c.ops, "unexpected routes", got)
+		}
+		for _, path := range c.paths {
+			if got[path] != 1 {
+				t.Fatal(c.ops, "expected route", path, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// WithElementSelectors set the ElementSelectors option.
+// The default value is false.
+// If ElementSelectors is true, a "[key=value]" token of a path selects the elements of an array
+// which are objects have the member key equals to value, e.g. "/items/[id=42]/name".
+// The value matches a string member as is, or other members by their json encoding,
+// so "[id=42]" matches both {"id":42} and {"id":"42"}.
+// A selector matches every selected element, they are applied as the matches of "*", see WithWildcards.
+// from of move and copy may contain selectors, and must match exactly one location.
+// Selectors are immune to the reorder of arrays between reading and patching, which breaks positional indices.
+func WithElementSelectors(on bool) Option {
+	return func(o *Patch) {
+		o.ElementSelectors = on
+	}
+}
+
+func (p *Patch) isSelector(token string) bool {
+	_, _, ok := parseSelector(token)
+	return p.ElementSelectors && ok
+}
+
+// parseSelector returns the key and value of a "[key=value]" token.
+func parseSelector(token string) (key, value string, ok bool) {
+	if len(token) < 3 || token[0] != '[' || token[len(token)-1] != ']' {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(token[1:len(token)-1], "=")
+	if !ok || key == "" {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+func matchSelector(element any, key, value string) bool {
+	m, ok := element.(map[string]any)
+	if !ok {
+		return false
+	}
+	v, ok := m[key]
+	if !ok {
+		return false
+	}
+	if s, ok := v.(string); ok {
+		return s == value
+	}
+	b, err := json.Marshal(v)
+	return err == nil && string(b) == value
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestElementSelectors(t *testing.T) {
+	doc := []byte(`{"items":[{"id":41,"name":"a"},{"id":42,"name":"b"},{"id":"42","name":"c"},3,{"id":true}]}`)
+	cases := []struct {
+		ops    string
+		expect string
+	}{
+		{`[{"op":"replace","path":"/items/[id=41]/name","value":"x"}]`,
+			`{"items":[{"id":41,"name":"x"},{"id":42,"name":"b"},{"id":"42","name":"c"},3,{"id":true}]}`},
+		{`[{"op":"remove","path":"/items/[id=42]"}]`, `{"items":[{"id":41,"name":"a"},3,{"id":true}]}`},
+		{`[{"op":"add","path":"/items/[id=true]/name","value":"t"}]`,
+			`{"items":[{"id":41,"name":"a"},{"id":42,"name":"b"},{"id":"42","name":"c"},3,{"id":true,"name":"t"}]}`},
+		{`[{"op":"remove","path":"/items/[id=0]"}]`, string(doc)},
+		{`[{"op":"remove","path":"/[id=41]"}]`, string(doc)},
+		{`[{"op":"copy","from":"/items/[id=41]/name","path":"/name"}]`,
+			`{"items":[{"id":41,"name":"a"},{"id":42,"name":"b"},{"id":"42","name":"c"},3,{"id":true}],"name":"a"}`},
+		{`[{"op":"move","from":"/items/[id=41]","path":"/items/-"}]`,
+			`{"items":[{"id":42,"name":"b"},{"id":"42","name":"c"},3,{"id":true},{"id":41,"name":"a"}]}`},
+	}
+	p := New(WithElementSelectors(true))
+	for _, c := range cases {
+		b, err := p.Apply(doc, mustOperations(t, c.ops))
+		if err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if ok, err := Equal(b, []byte(c.expect)); err != nil || !ok {
+			t.Fatal(c.ops, "unexpected document", string(b))
+		}
+	}
+
+	_, err := p.Apply(doc, mustOperations(t, `[{"op":"copy","from":"/items/[id=42]","path":"/a"}]`))
+	if !errors.Is(err, errPatternFrom) {
+		t.Fatal("expected from error, got", err)
+	}
+	_, err = New(WithElementSelectors(true), WithStrictPathExists(true)).Apply(doc, mustOperations(t, `[{"op":"copy","from":"/items/[id=0]","path":"/a"}]`))
+	if !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected not exists, got", err)
+	}
+
+	b, err := New().Apply([]byte(`{"[id=1]":1}`), mustOperations(t, `[{"op":"remove","path":"/[id=1]"}]`))
+	if err != nil || string(b) != `{}`+"\n" {
+		t.Fatal("selector must be a plain key without ElementSelectors", string(b), err)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// ErrBadSignature is returned if the signature of a patch does not match.
+var ErrBadSignature = errors.New("bad patch signature")
+
+// SignedPatch is a patch with a signature.
+type SignedPatch struct {
+	Operations []Operation `json:"operations"`
+	// BaseHash is the hex encoded sha256 of the canonical encoding of the document
+	// that the patch is created against. It is empty if the patch is not bound to a document.
+	BaseHash string `json:"baseHash,omitempty"`
+	// Signature is the hex encoded HMAC-SHA256 of the operations and the base hash.
+	Signature string `json:"signature"`
+}
+
+// SignPatch signs ops with key.
+func SignPatch(ops []Operation, key []byte) (SignedPatch, error) {
+	return signPatch(ops, "", key)
+}
+
+// SignPatchFor signs ops with key and binds the signature to the base document doc.
+func SignPatchFor(ops []Operation, doc any, key []byte) (SignedPatch, error) {
+	h, err := canonicalHash(doc)
+	if err != nil {
+		return SignedPatch{}, err
+	}
+	return signPatch(ops, h, key)
+}
+
+func signPatch(ops []Operation, base string, key []byte) (SignedPatch, error) {
+	sig, err := patchSignature(ops, base, key)
+	if err != nil {
+		return SignedPatch{}, err
+	}
+	return SignedPatch{Operations: ops, BaseHash: base, Signature: sig}, nil
+}
+
+// VerifyPatch verifies the signature of sp with key.
+// ErrBadSignature is returned if the signature does not match.
+func VerifyPatch(sp SignedPatch, key []byte) error {
+	sig, err := patchSignature(sp.Operations, sp.BaseHash, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(sig), []byte(sp.Signature)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// VerifyPatchFor verifies the signature of sp with key and that sp is created against doc.
+func VerifyPatchFor(sp SignedPatch, doc any, key []byte) error {
+	if err := VerifyPatch(sp, key); err != nil {
+		return err
+	}
+	h, err := canonicalHash(doc)
+	if err != nil {
+		return err
+	}
+	if sp.BaseHash != h {
+		return errors.New("patch is not created against the document")
+	}
+	return nil
+}
+
+func patchSignature(ops []Operation, base string, key []byte) (string, error) {
+	b, err := canonicalJSON(ops)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	mac.Write([]byte{0})
+	mac.Write([]byte(base))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// canonicalJSON returns the canonical encoding of o.
+// Object members are sorted by key, no insignificant whitespace and no HTML escaping.
+func canonicalJSON(o any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(o); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+func canonicalHash(o any) (string, error) {
+	b, err := canonicalJSON(o)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// Hash returns the hex encoded sha256 of the canonical encoding of doc.
+// Equal documents always have the same hash, which is suitable for ETags and cache keys.
+func Hash(doc any) (string, error) {
+	return canonicalHash(doc)
+}
+
+// HashAt returns the Hash of the subtree of doc at pointer.
+// It returns ErrNotExists if pointer not exists.
+func HashAt(doc any, pointer string) (string, error) {
+	v, err := NewJSONPointer(pointer).Resolve(doc)
+	if err != nil {
+		return "", err
+	}
+	return canonicalHash(v)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSignPatch(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"add","path":"/a","value":{"y":1,"x":2}}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	key := []byte("secret")
+	doc := map[string]any{"b": 1.0}
+	sp, err := SignPatchFor(ops, doc, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got SignedPatch
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPatchFor(got, doc, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPatch(got, []byte("other")); !errors.Is(err, ErrBadSignature) {
+		t.Fatal("expected bad signature, got", err)
+	}
+	if err := VerifyPatchFor(got, map[string]any{}, key); err == nil {
+		t.Fatal("expected error for another document")
+	}
+	*got.Operations[0].Path = "/b"
+	if err := VerifyPatch(got, key); !errors.Is(err, ErrBadSignature) {
+		t.Fatal("expected bad signature, got", err)
+	}
+}
+
+func TestHash(t *testing.T) {
+	var a, b any
+	if err := json.Unmarshal([]byte(`{"x":{"y":[1,"<"]},"z":1}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{ "z": 2, "x": { "y": [1, "<"] } }`), &b); err != nil {
+		t.Fatal(err)
+	}
+	ha, err := Hash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb, err := Hash(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ha == hb {
+		t.Fatal("expected different hashes")
+	}
+	xa, err := HashAt(a, "/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xb, err := HashAt(b, "/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xa != xb {
+		t.Fatal("expected same subtree hash", xa, xb)
+	}
+	if root, _ := HashAt(a, ""); root != ha {
+		t.Fatal("expected root hash equals to Hash", root, ha)
+	}
+	if _, err := HashAt(a, "/x/w"); !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected ErrNotExists, got", err)
+	}
+}
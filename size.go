@@ -0,0 +1,94 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrDocumentTooLarge is returned if the output of Apply exceeds the MaxDocumentSize option.
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// WithMaxDocumentSize set the MaxDocumentSize option.
+// The default value is 0, which means no limit.
+// Apply rejects the output if its actual size exceeds n bytes, so it is never returned or stored.
+// A patch is not rejected by EstimateSize, which is not a bound of the output size,
+// call it before Apply to predict the size instead.
+func WithMaxDocumentSize(n int) Option {
+	return func(o *Patch) {
+		o.MaxDocumentSize = n
+	}
+}
+
+// EstimateSize estimates the size of the compact output of applying ops to the json document b.
+// Sizes of removed, replaced and copied values are measured in b,
+// so the estimate is close for operations touching different parts of the document.
+// It's neither an upper nor a lower bound, e.g. an add and a remove of the same member
+// are both counted, and b is not normalized as the output is.
+func (p *Patch) EstimateSize(b []byte, ops []Operation) (int, error) {
+	if !json.Valid(b) {
+		return 0, errors.New("invalid json document")
+	}
+	size := len(b)
+	for _, op := range ops {
+		if op.OP == nil || op.Path == nil {
+			continue
+		}
+		path := NewJSONPointer(*op.Path)
+		switch *op.OP {
+		case opAdd:
+			size += p.valueSize(op.Value) + memberSize(path)
+		case opRemove:
+			if n := p.rawSize(b, path); n > 0 {
+				size -= n + memberSize(path)
+			}
+		case opReplace:
+			size += p.valueSize(op.Value) - p.rawSize(b, path)
+		case opCopy:
+			if op.From != nil {
+				size += p.rawSize(b, NewJSONPointer(*op.From)) + memberSize(path)
+			}
+		}
+	}
+	return size, nil
+}
+
+func (p *Patch) valueSize(v *any) int {
+	if v == nil {
+		return 0
+	}
+	b, err := p.rawEncode(*v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// rawSize returns the size of the value at path in b, or 0 if not exists.
+func (p *Patch) rawSize(b []byte, path JSONPointer) int {
+	start, end, err := p.rawLocate(b, path.Path())
+	if err != nil {
+		return 0
+	}
+	return end - start
+}
+
+// memberSize returns the size of the key, quotes, colon and comma of the member at path.
+// Array elements are counted as objects members.
+func memberSize(path JSONPointer) int {
+	if path.IsTheWholeDocument() {
+		return 0
+	}
+	return len(path.LastToken()) + 4
+}
+
+func (p *Patch) checkOutputSize(out []byte) error {
+	if p.MaxDocumentSize > 0 && len(out) > p.MaxDocumentSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrDocumentTooLarge, len(out), p.MaxDocumentSize)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	doc := []byte(`{"a":[1,2],"b":{"c":"long string"},"d":true}`)
+	ops := mustOperations(t, `[
+		{"op":"add","path":"/e","value":{"f":1}},
+		{"op":"remove","path":"/d"},
+		{"op":"replace","path":"/b/c","value":"s"},
+		{"op":"copy","from":"/a","path":"/g"},
+		{"op":"add","path":"/a/-","value":3}
+	]`)
+	p := New()
+	n, err := p.EstimateSize(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.Apply(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the estimate counts array elements as object members.
+	if actual := len(out) - 1; n < actual || n > actual+4 {
+		t.Fatal("bad estimate", n, "actual", actual, string(out))
+	}
+	if _, err := p.EstimateSize([]byte(`{`), ops); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestMaxDocumentSize(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+	ops := mustOperations(t, `[{"op":"add","path":"/b","value":"0123456789"}]`)
+	if _, err := New(WithMaxDocumentSize(30)).Apply(doc, ops); err != nil {
+		t.Fatal(err)
+	}
+	_, err := New(WithMaxDocumentSize(20)).Apply(doc, ops)
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatal("expected ErrDocumentTooLarge, got", err)
+	}
+	// the indented output is checked.
+	_, err = New(WithMaxDocumentSize(30), WithJSONIndent("", "    ")).Apply(doc, ops)
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatal("expected ErrDocumentTooLarge, got", err)
+	}
+	_, err = New(WithMaxDocumentSize(20)).ApplyEncoded(doc, ops, EncodeOptions{})
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatal("expected ErrDocumentTooLarge, got", err)
+	}
+	// an estimate over the limit does not reject a patch whose output is under the limit.
+	doc = []byte(`{"a":1,"c":"0123456789abc"}`)
+	ops = mustOperations(t, `[{"op":"add","path":"/b","value":"0123456789"},{"op":"remove","path":"/b"}]`)
+	if n, _ := New().EstimateSize(doc, ops); n <= 40 {
+		t.Fatal("expected an estimate over the limit, got", n)
+	}
+	if _, err := New(WithMaxDocumentSize(40)).Apply(doc, ops); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrVersionConflict is returned by Store.Save if the stored document is not at the expected version.
+var ErrVersionConflict = errors.New("version conflict")
+
+// Store loads and saves versioned json documents by key, e.g. etcd, Consul or DynamoDB.
+type Store interface {
+	// Load returns the document of key and its version.
+	Load(ctx context.Context, key string) (doc []byte, version int64, err error)
+	// Save saves the document of key only if the stored document is at expectedVersion,
+	// otherwise it must return an error wraps ErrVersionConflict.
+	Save(ctx context.Context, key string, doc []byte, expectedVersion int64) error
+}
+
+// ApplyToKey loads the document of key, applies ops and saves it back with compare-and-swap.
+// The read-patch-save loop is retried by the Retrier set by WithRetrier if the document is changed concurrently.
+// It returns the saved document, the operations are routed once it's saved.
+func (p *Patch) ApplyToKey(ctx context.Context, s Store, key string, ops []Operation) ([]byte, error) {
+	r := p.retrier
+	if r == nil {
+		r = &Retrier{}
+	}
+	var doc []byte
+	err := r.Do(ctx, func(ctx context.Context) error {
+		var err error
+		doc, err = p.applyToKeyOnce(ctx, s, key, ops)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apply to key %s: %w", key, err)
+	}
+	p.route(ops)
+	return doc, nil
+}
+
+func (p *Patch) applyToKeyOnce(ctx context.Context, s Store, key string, ops []Operation) ([]byte, error) {
+	doc, version, err := s.Load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	doc, err = p.applyJSON(doc, ops)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Save(ctx, key, doc, version); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// defaultCASAttempts is the default number of attempts of Retrier.
+const defaultCASAttempts = 5
+
+// Retrier retries a compare-and-swap loop.
+type Retrier struct {
+	// MaxAttempts is the max number of attempts, 5 is used if it is 0.
+	MaxAttempts int
+	// Backoff returns the delay before the attempt, attempt starts from 1.
+	// There is no delay if it is nil.
+	Backoff func(attempt int) time.Duration
+	// Retryable reports whether an error is a conflict worth to retry.
+	// Errors wrap ErrVersionConflict are retried if it is nil.
+	Retryable func(err error) bool
+}
+
+// WithRetrier set the Retrier used by ApplyToKey.
+func WithRetrier(r *Retrier) Option {
+	return func(o *Patch) {
+		o.retrier = r
+	}
+}
+
+// ErrTooManyAttempts is returned by Retrier.Do if all attempts are failed with retryable errors.
+var ErrTooManyAttempts = errors.New("too many attempts")
+
+// Do calls fn until it succeeds, fails with an error that is not retryable, or all attempts are used.
+// The last error is wrapped with ErrTooManyAttempts if all attempts are used.
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempts := r.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultCASAttempts
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 && r.Backoff != nil {
+			if err := sleepContext(ctx, r.Backoff(attempt-1)); err != nil {
+				return err
+			}
+		}
+		if err = fn(ctx); err == nil || !r.retryable(err) {
+			return err
+		}
+	}
+	return &attemptsError{err: err}
+}
+
+// attemptsError is ErrTooManyAttempts wraps the last error.
+type attemptsError struct {
+	err error
+}
+
+func (e *attemptsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTooManyAttempts, e.err)
+}
+
+func (e *attemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+func (e *attemptsError) Unwrap() error {
+	return e.err
+}
+
+func (r *Retrier) retryable(err error) bool {
+	if r.Retryable != nil {
+		return r.Retryable(err)
+	}
+	return errors.Is(err, ErrVersionConflict)
+}
+
+// ExponentialBackoff returns a backoff doubles the delay from base up to limit, with full jitter.
+func ExponentialBackoff(base, limit time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := limit
+		if attempt < 32 && base<<(attempt-1) < limit {
+			d = base << (attempt - 1)
+		}
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d) + 1))
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
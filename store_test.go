@@ -0,0 +1,128 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	mu       sync.Mutex
+	docs     map[string][]byte
+	versions map[string]int64
+	// beforeSave is called before a document is saved.
+	beforeSave func()
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{docs: map[string][]byte{}, versions: map[string]int64{}}
+}
+
+func (s *memoryStore) Load(_ context.Context, key string) ([]byte, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[key], s.versions[key], nil
+}
+
+func (s *memoryStore) Save(_ context.Context, key string, doc []byte, expectedVersion int64) error {
+	if s.beforeSave != nil {
+		s.beforeSave()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.versions[key] != expectedVersion {
+		return &ConflictError{Expected: expectedVersion, Actual: s.versions[key]}
+	}
+	s.docs[key] = doc
+	s.versions[key]++
+	return nil
+}
+
+func TestApplyToKey(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"add","path":"/n/-","value":1}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	s := newMemoryStore()
+	s.docs["k"] = []byte(`{"n":[]}`)
+	conflicts := 2
+	s.beforeSave = func() {
+		if conflicts > 0 {
+			conflicts--
+			s.mu.Lock()
+			s.versions["k"]++
+			s.mu.Unlock()
+		}
+	}
+	var routed int
+	router := NewRouter()
+	router.Handle("/n/**", func([]Operation) { routed++ })
+	p := New(WithRouter(router))
+	doc, err := p.ApplyToKey(context.Background(), s, "k", ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc) != "{\"n\":[1]}\n" || s.versions["k"] != 3 {
+		t.Fatal("bad document", string(doc), s.versions["k"])
+	}
+	// the attempts failed to save are not routed.
+	if routed != 1 {
+		t.Fatal("expected routed once, got", routed)
+	}
+
+	s.beforeSave = func() {
+		s.mu.Lock()
+		s.versions["k"]++
+		s.mu.Unlock()
+	}
+	_, err = p.ApplyToKey(context.Background(), s, "k", ops)
+	if !errors.Is(err, ErrVersionConflict) || !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatal("expected conflict, got", err)
+	}
+	if routed != 1 {
+		t.Fatal("expected routed once, got", routed)
+	}
+}
+
+func TestRetrier(t *testing.T) {
+	var delays []time.Duration
+	r := &Retrier{
+		MaxAttempts: 4,
+		Backoff: func(attempt int) time.Duration {
+			delays = append(delays, time.Duration(attempt))
+			return 0
+		},
+	}
+	calls := 0
+	err := r.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || len(delays) != 2 || delays[1] != 2 {
+		t.Fatal("bad retries", err, calls, delays)
+	}
+	bad := errors.New("bad")
+	calls = 0
+	err = r.Do(context.Background(), func(context.Context) error {
+		calls++
+		return bad
+	})
+	if err != bad || calls != 1 {
+		t.Fatal("expected no retry", err, calls)
+	}
+	b := ExponentialBackoff(time.Millisecond, 4*time.Millisecond)
+	for attempt := 1; attempt < 100; attempt++ {
+		if d := b(attempt); d < 0 || d > 4*time.Millisecond {
+			t.Fatal("bad backoff", attempt, d)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads the operations of a patch document one by one,
+// so a large patch is never buffered as a whole.
+type Decoder struct {
+	p       *Patch
+	dec     *json.Decoder
+	policy  *policyChecker
+	started bool
+	done    bool
+}
+
+// NewDecoder returns a decoder reads a patch document from r.
+// Operations are checked by p as Check does, including the ownership and the policy of p.
+func (p *Patch) NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{p: p, dec: json.NewDecoder(r)}
+	if p.policy != nil {
+		d.policy = p.policy.checker()
+	}
+	return d
+}
+
+// Next returns the next operation of the patch document.
+// It returns io.EOF after the last operation.
+func (d *Decoder) Next() (Operation, error) {
+	if d.done {
+		return Operation{}, io.EOF
+	}
+	if !d.started {
+		if err := d.expectDelim('['); err != nil {
+			return Operation{}, err
+		}
+		d.started = true
+	}
+	if !d.dec.More() {
+		if err := d.expectDelim(']'); err != nil {
+			return Operation{}, err
+		}
+		d.done = true
+		if d.policy != nil {
+			if err := d.policy.finish(); err != nil {
+				return Operation{}, err
+			}
+		}
+		return Operation{}, io.EOF
+	}
+	var op Operation
+	if err := d.dec.Decode(&op); err != nil {
+		return Operation{}, err
+	}
+	if d.p.URIFragment {
+		var err error
+		if op, err = fromURIFragment(op); err != nil {
+			return Operation{}, d.p.localize(err)
+		}
+	}
+	if err := d.p.checkOne(op); err != nil {
+		return Operation{}, err
+	}
+	if d.p.ownership != nil {
+		if err := d.p.ownership.Check(d.p.writer, []Operation{op}); err != nil {
+			return Operation{}, err
+		}
+	}
+	if d.policy != nil {
+		if err := d.policy.next(op); err != nil {
+			return Operation{}, err
+		}
+	}
+	return op, nil
+}
+
+func (d *Decoder) expectDelim(delim json.Delim) error {
+	t, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("patch document expects %s, got %v", delim, t)
+	}
+	return nil
+}
+
+// ApplyStream reads the patch document from r and applies every operation to o once it is decoded.
+// It returns the number of read operations.
+// Unlike ApplyAny, o is left partially patched if an operation fails,
+// so apply it to a copy if the patch must be atomic.
+// The router of p is not notified.
+func (p *Patch) ApplyStream(o *any, r io.Reader) (int, error) {
+	d := p.NewDecoder(r)
+	var tracker *indexTracker
+	if p.OriginalArrayIndex {
+		tracker = newIndexTracker()
+	}
+	for n := 0; ; n++ {
+		op, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		ext := p.extensions[*op.OP]
+		err = p.applyOne(o, ext, op, tracker)
+		if err != nil && !(!p.StrictPathExists && errors.Is(err, ErrNotExists)) {
+			return n, p.operationError(ext, op, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	d := New().NewDecoder(strings.NewReader(`[{"op":"add","path":"/a","value":1}, {"op":"remove","path":"/b"}]`))
+	var ops []Operation
+	for {
+		op, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops = append(ops, op)
+	}
+	expect := mustOperations(t, `[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/b"}]`)
+	if !reflect.DeepEqual(ops, expect) {
+		t.Fatal("expected", jsonstring(expect), "got", jsonstring(ops))
+	}
+	if _, err := d.Next(); !errors.Is(err, io.EOF) {
+		t.Fatal("expected EOF, got", err)
+	}
+
+	for _, s := range []string{
+		`{"op":"add"}`,
+		`[{"op":"bad","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"add","path":"/a","value":1}`,
+	} {
+		d := New().NewDecoder(strings.NewReader(s))
+		var err error
+		for err == nil {
+			_, err = d.Next()
+		}
+		if errors.Is(err, io.EOF) {
+			t.Fatal(s, "expected error")
+		}
+	}
+}
+
+func TestApplyStream(t *testing.T) {
+	var doc any = map[string]any{"a": []any{1.0}}
+	n, err := New().ApplyStream(&doc, strings.NewReader(`[
+		{"op":"add","path":"/a/-","value":2},
+		{"op":"copy","from":"/a","path":"/b"},
+		{"op":"test","path":"/b/1","value":2}
+	]`))
+	if err != nil || n != 3 {
+		t.Fatal(n, err)
+	}
+	if expect := map[string]any{"a": []any{1.0, 2.0}, "b": []any{1.0, 2.0}}; !reflect.DeepEqual(doc, expect) {
+		t.Fatal("expected", expect, "got", doc)
+	}
+
+	n, err = New().ApplyStream(&doc, strings.NewReader(`[{"op":"remove","path":"/b"},{"op":"remove","path":"/x"},{"op":"bad"}]`))
+	if err == nil || n != 1 {
+		t.Fatal("expected error at the second operation", n, err)
+	}
+	if _, ok := doc.(map[string]any)["b"]; ok {
+		t.Fatal("applied operations must be kept")
+	}
+
+	pol := &Policy{RequireTests: []string{"/a"}}
+	if _, err := New(WithPolicy(pol)).ApplyStream(&doc, strings.NewReader(`[{"op":"remove","path":"/a"}]`)); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatal("expected policy violation, got", err)
+	}
+}
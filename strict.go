@@ -0,0 +1,43 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+// WithRFC6902Strict turns on every behavior required by RFC6902 at once:
+// a path not exists and a failed test are errors, negative and symbolic array indices,
+// "#N" tokens, path patterns and URI fragment pointers are not supported,
+// pointers with invalid escapes like "~2" are rejected, operations other than
+// the six standard operations are rejected even if extensions are registered,
+// and ApplyAny applies atomically.
+// Options after it can still change the individual options.
+func WithRFC6902Strict() Option {
+	return func(o *Patch) {
+		o.RFC6902Strict = true
+		o.StrictPathExists = true
+		o.SupportNegativeArrayIndex = false
+		o.OriginalArrayIndex = false
+		o.URIFragment = false
+		o.Wildcards = false
+		o.RecursiveWildcards = false
+		o.ElementSelectors = false
+		o.JSONPathPaths = false
+		o.SymbolicArrayIndex = false
+		o.IntermediateAppend = false
+	}
+}
+
+// checkRFC6902 returns an error if op is not a standard operation or has invalid escapes.
+func checkRFC6902(op Operation) error {
+	switch *op.OP {
+	case opAdd, opRemove, opReplace, opMove, opCopy, opTest:
+	default:
+		return newError(CodeUnknownOperation, map[string]any{"op": *op.OP}, nil)
+	}
+	for _, ptr := range touchedPaths(op) {
+		if err := NewJSONPointer(ptr).CheckStrict(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
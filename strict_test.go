@@ -0,0 +1,42 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRFC6902Strict(t *testing.T) {
+	p := New(WithStrictPathExists(false), WithSupportNegativeArrayIndex(true), WithExtension(clearExtension{}), WithRFC6902Strict())
+	if !p.StrictPathExists || p.SupportNegativeArrayIndex {
+		t.Fatal("options must be reset")
+	}
+	doc := []byte(`{"a":[1,2],"b~c":1}`)
+	for _, s := range []string{
+		`[{"op":"remove","path":"/x"}]`,
+		`[{"op":"remove","path":"/a/-1"}]`,
+		`[{"op":"test","path":"/a/0","value":2}]`,
+		`[{"op":"remove","path":"/b~c"}]`,
+		`[{"op":"remove","path":"/b~2c"}]`,
+		`[{"op":"clear","path":"/a"}]`,
+		`[{"op":"move","from":"/b~","path":"/c"}]`,
+	} {
+		if _, err := p.Apply(doc, mustOperations(t, s)); err == nil {
+			t.Fatal(s, "expected error")
+		}
+	}
+	if _, err := p.Apply(doc, mustOperations(t, `[{"op":"move","from":"/b~0c","path":"/c~1d"}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var o any = map[string]any{"a": 1.0}
+	err := p.ApplyAny(&o, mustOperations(t, `[{"op":"add","path":"/b","value":1},{"op":"remove","path":"/x"}]`))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if expect := map[string]any{"a": 1.0}; !reflect.DeepEqual(o, expect) {
+		t.Fatal("expected rollback", o)
+	}
+}
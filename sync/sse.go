@@ -0,0 +1,95 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// SSEHandler returns a handler streaming the updates of d as server-sent events.
+// Every event has the version as id and the json encoded Update as data.
+// A client resumes from the Last-Event-ID header or the since query parameter,
+// the missed updates are sent before new ones.
+// The stream ends if the client falls behind more than buffer updates, so it can reconnect and resume.
+func SSEHandler(d *Document, buffer int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		since, resume, err := resumeVersion(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s := d.Subscribe(buffer)
+		defer s.Close()
+		var missed []Update
+		if resume {
+			if missed, err = d.Since(since); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		for _, u := range missed {
+			if err := writeEvent(w, u); err != nil {
+				return
+			}
+			since = u.Version
+		}
+		flusher.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case u, ok := <-s.C():
+				if !ok {
+					return
+				}
+				if u.Version <= since {
+					// already sent as a missed update.
+					continue
+				}
+				if err := writeEvent(w, u); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+func resumeVersion(r *http.Request) (uint64, bool, error) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("since")
+	}
+	if s == "" {
+		return 0, false, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("bad version: %s", s)
+	}
+	return v, true, nil
+}
+
+func writeEvent(w io.Writer, u Update) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: patch\ndata: %s\n\n", u.Version, b)
+	return err
+}
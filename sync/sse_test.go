@@ -0,0 +1,58 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package sync
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSSEHandler(t *testing.T) {
+	d := NewDocument(map[string]any{}, nil)
+	if _, err := d.Submit(0, operations(t, `[{"op":"add","path":"/a","value":1}]`)); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(SSEHandler(d, 10))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatal("bad content type", ct)
+	}
+	if _, err := d.Submit(1, operations(t, `[{"op":"add","path":"/b","value":2}]`)); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for version := uint64(1); version <= 2; version++ {
+		var u Update
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				if err := json.Unmarshal([]byte(line[len("data: "):]), &u); err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+		}
+		if u.Version != version || len(u.Patch) != 1 {
+			t.Fatal("bad update", u)
+		}
+	}
+}
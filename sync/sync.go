@@ -0,0 +1,232 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+// Package sync synchronizes a json document between a server and its clients with json patches.
+//
+// A Document accepts patches submitted by clients against a known version,
+// rebases them past the concurrent patches and broadcasts every applied patch to its subscribers.
+// A Replica follows the broadcast patches to maintain a local copy of the document.
+// The package is transport agnostic, Update is json encodable and can be sent
+// over a websocket or any other stream.
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdsync "sync"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+var (
+	// ErrConflict is returned if a patch conflicts with patches applied after its base version,
+	// see jsonpatch.Conflicts. It wraps jsonpatch.ErrVersionConflict, so it's classified as jsonpatch.ClassConflict.
+	ErrConflict = fmt.Errorf("patch conflicts with concurrent updates: %w", jsonpatch.ErrVersionConflict)
+	// ErrBadVersion is returned if a version is unknown to the document.
+	ErrBadVersion = errors.New("bad version")
+	// ErrOutOfOrder is returned if an update is applied to a replica out of order.
+	ErrOutOfOrder = errors.New("update out of order")
+)
+
+// Update is a patch applied to a document.
+type Update struct {
+	// Version is the version of the document after the patch is applied.
+	Version uint64                `json:"version"`
+	Patch   []jsonpatch.Operation `json:"patch"`
+}
+
+// Document is a server side document session.
+// It is safe for concurrent use.
+type Document struct {
+	mu          stdsync.Mutex
+	patch       *jsonpatch.Patch
+	doc         any
+	version     uint64
+	history     []Update
+	subscribers map[*Subscription]struct{}
+}
+
+// NewDocument creates a document session at version 0.
+// p is used to apply patches, jsonpatch.New() is used if p is nil.
+func NewDocument(doc any, p *jsonpatch.Patch) *Document {
+	if p == nil {
+		p = jsonpatch.New()
+	}
+	return &Document{
+		patch:       p,
+		doc:         doc,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Snapshot returns a copy of the document and its version.
+func (d *Document) Snapshot() (any, uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc, err := copyDocument(d.doc)
+	return doc, d.version, err
+}
+
+// Since returns the updates applied after version.
+func (d *Document) Since(version uint64) ([]Update, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if version > d.version {
+		return nil, fmt.Errorf("%w: %d", ErrBadVersion, version)
+	}
+	return append([]Update(nil), d.history[version:]...), nil
+}
+
+// Submit applies ops that is created against the base version of the document.
+// If the document has been updated after base, ops is rebased past those updates by jsonpatch.Rebase,
+// which shifts its array indices, and ErrConflict is returned if it conflicts with any of them.
+// The applied update, whose patch is the rebased ops, is broadcast to all subscribers.
+func (d *Document) Submit(base uint64, ops []jsonpatch.Operation) (Update, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if base > d.version {
+		return Update{}, fmt.Errorf("%w: %d", ErrBadVersion, base)
+	}
+	// the update is kept in history and broadcast, so it must not share values with the caller.
+	ops, err := copyOperations(ops)
+	if err != nil {
+		return Update{}, err
+	}
+	for _, u := range d.history[base:] {
+		if ops, err = jsonpatch.Rebase(ops, u.Patch); err != nil {
+			return Update{}, fmt.Errorf("%w: version %d: %v", ErrConflict, u.Version, err)
+		}
+	}
+	doc, _, err := d.patch.Simulate(d.doc, ops)
+	if err != nil {
+		return Update{}, err
+	}
+	d.doc = doc
+	d.version++
+	u := Update{Version: d.version, Patch: ops}
+	d.history = append(d.history, u)
+	for s := range d.subscribers {
+		select {
+		case s.c <- u:
+		default:
+			// the subscriber is too slow, drop it so it can resync from a snapshot.
+			d.unsubscribe(s)
+		}
+	}
+	return u, nil
+}
+
+// Subscription receives updates of a document.
+type Subscription struct {
+	d *Document
+	c chan Update
+}
+
+// Subscribe subscribes the updates applied after now.
+// If the subscriber does not keep up with buffer pending updates, it is closed.
+func (d *Document) Subscribe(buffer int) *Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := &Subscription{d: d, c: make(chan Update, buffer)}
+	d.subscribers[s] = struct{}{}
+	return s
+}
+
+// C returns the channel of updates, it is closed when the subscription is closed.
+func (s *Subscription) C() <-chan Update {
+	return s.c
+}
+
+// Close closes the subscription.
+func (s *Subscription) Close() {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.unsubscribe(s)
+}
+
+func (d *Document) unsubscribe(s *Subscription) {
+	if _, ok := d.subscribers[s]; !ok {
+		return
+	}
+	delete(d.subscribers, s)
+	close(s.c)
+}
+
+// Replica is a client side replica of a document.
+// It is safe for concurrent use.
+type Replica struct {
+	mu      stdsync.Mutex
+	patch   *jsonpatch.Patch
+	doc     any
+	version uint64
+}
+
+// NewReplica creates a replica from a snapshot of a document.
+// p is used to apply patches, jsonpatch.New() is used if p is nil.
+func NewReplica(doc any, version uint64, p *jsonpatch.Patch) *Replica {
+	if p == nil {
+		p = jsonpatch.New()
+	}
+	return &Replica{patch: p, doc: doc, version: version}
+}
+
+// Apply applies an update to the replica.
+// Updates that are already applied are ignored.
+func (r *Replica) Apply(u Update) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u.Version <= r.version {
+		return nil
+	}
+	if u.Version != r.version+1 {
+		return fmt.Errorf("%w: expect version %d, got %d", ErrOutOfOrder, r.version+1, u.Version)
+	}
+	doc, _, err := r.patch.Simulate(r.doc, u.Patch)
+	if err != nil {
+		return err
+	}
+	r.doc = doc
+	r.version = u.Version
+	return nil
+}
+
+// Follow applies updates from c until c is closed or an update fails.
+func (r *Replica) Follow(c <-chan Update) error {
+	for u := range c {
+		if err := r.Apply(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the replica document and its version.
+func (r *Replica) Snapshot() (any, uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, err := copyDocument(r.doc)
+	return doc, r.version, err
+}
+
+func copyDocument(o any) (any, error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	var c any
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// copyOperations returns a deep copy of ops.
+func copyOperations(ops []jsonpatch.Operation) ([]jsonpatch.Operation, error) {
+	b, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	var r []jsonpatch.Operation
+	err = json.Unmarshal(b, &r)
+	return r, err
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hanke0/jsonpatch"
+)
+
+func operations(t *testing.T, s string) []jsonpatch.Operation {
+	t.Helper()
+	var ops []jsonpatch.Operation
+	if err := json.Unmarshal([]byte(s), &ops); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func TestDocument(t *testing.T) {
+	d := NewDocument(map[string]any{"a": 1.0, "b": []any{1.0}}, nil)
+	snapshot, version, err := d.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReplica(snapshot, version, nil)
+	s := d.Subscribe(10)
+
+	if _, err := d.Submit(0, operations(t, `[{"op":"replace","path":"/a","value":2}]`)); err != nil {
+		t.Fatal(err)
+	}
+	// concurrent patch against version 0 on another path.
+	if _, err := d.Submit(0, operations(t, `[{"op":"add","path":"/b/-","value":2}]`)); err != nil {
+		t.Fatal(err)
+	}
+	// concurrent patch against version 0 on a changed path.
+	_, err = d.Submit(0, operations(t, `[{"op":"replace","path":"/a","value":3}]`))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatal("expected conflict, got", err)
+	}
+	// concurrent patch against version 1 on the same array is rebased.
+	if _, err := d.Submit(1, operations(t, `[{"op":"add","path":"/b/0","value":0}]`)); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if err := r.Follow(s.C()); err != nil {
+		t.Fatal(err)
+	}
+	got, version, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, _, _ := d.Snapshot()
+	if version != 3 || !reflect.DeepEqual(got, expect) {
+		t.Fatal("expected", expect, "got", version, got)
+	}
+	if !reflect.DeepEqual(expect, map[string]any{"a": 2.0, "b": []any{0.0, 1.0, 2.0}}) {
+		t.Fatal("bad document", expect)
+	}
+	updates, err := d.Since(1)
+	if err != nil || len(updates) != 2 || updates[0].Version != 2 {
+		t.Fatal("bad updates", updates, err)
+	}
+}
+
+func TestDocumentRebase(t *testing.T) {
+	d := NewDocument(map[string]any{"b": []any{map[string]any{"x": 1.0}, map[string]any{"x": 2.0}}}, nil)
+	s := d.Subscribe(10)
+	r := NewReplica(map[string]any{"b": []any{map[string]any{"x": 1.0}, map[string]any{"x": 2.0}}}, 0, nil)
+	if _, err := d.Submit(0, operations(t, `[{"op":"remove","path":"/b/0"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	u, err := d.Submit(0, operations(t, `[{"op":"replace","path":"/b/1/x","value":9}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *u.Patch[0].Path != "/b/0/x" {
+		t.Fatal("patch is not rebased", *u.Patch[0].Path)
+	}
+	if _, err := d.Submit(1, operations(t, `[{"op":"remove","path":"/b/0"}]`)); !errors.Is(err, ErrConflict) {
+		t.Fatal("expected conflict, got", err)
+	}
+	ops := operations(t, `[{"op":"add","path":"/c","value":{"x":1}},{"op":"remove","path":"/c/x"}]`)
+	if _, err := d.Submit(2, ops); err != nil {
+		t.Fatal(err)
+	}
+	*ops[0].Value = "changed"
+	s.Close()
+	if err := r.Follow(s.C()); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _ := r.Snapshot()
+	expect := map[string]any{"b": []any{map[string]any{"x": 9.0}}, "c": map[string]any{}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatal("expected", expect, "got", got)
+	}
+}
+
+func TestReplicaOutOfOrder(t *testing.T) {
+	r := NewReplica(map[string]any{}, 0, nil)
+	err := r.Apply(Update{Version: 2})
+	if !errors.Is(err, ErrOutOfOrder) {
+		t.Fatal("expected out of order, got", err)
+	}
+}
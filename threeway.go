@@ -0,0 +1,112 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Conflict is a path changed differently by two sides.
+// Ours and Theirs are the operations of both sides at Path.
+type Conflict struct {
+	Path   string
+	Ours   Operation
+	Theirs Operation
+}
+
+// Merge merges the changes from the json document base to ours and theirs.
+// Changes at different object members are combined, arrays and scalars are merged as a whole.
+// A path changed differently by both sides is a conflict, the merged document keeps ours
+// at that path and the conflict contains the operations of both sides against base.
+func Merge(base, ours, theirs []byte) ([]byte, []Conflict, error) {
+	var docs [3]any
+	for i, b := range [][]byte{base, ours, theirs} {
+		if err := json.Unmarshal(b, &docs[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	o, conflicts := MergeAny(docs[0], docs[1], docs[2])
+	var buf bytes.Buffer
+	if err := New().EncodeOptions().encode(&buf, o); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), conflicts, nil
+}
+
+// MergeAny is like Merge but the documents are values decoded by encoding/json.
+// The merged document shares values with ours and theirs.
+func MergeAny(base, ours, theirs any) (any, []Conflict) {
+	m := &merger{}
+	v := m.merge("", mergeSide{base, true}, mergeSide{ours, true}, mergeSide{theirs, true})
+	return v.value, m.conflicts
+}
+
+// mergeSide is a value of a side, ok is false if the value not exists.
+type mergeSide struct {
+	value any
+	ok    bool
+}
+
+func (s mergeSide) equal(o mergeSide) bool {
+	return s.ok == o.ok && (!s.ok || EqualAny(s.value, o.value))
+}
+
+type merger struct {
+	conflicts []Conflict
+}
+
+func (m *merger) merge(ptr string, base, ours, theirs mergeSide) mergeSide {
+	switch {
+	case ours.equal(theirs), base.equal(theirs):
+		return ours
+	case base.equal(ours):
+		return theirs
+	}
+	b, okb := base.value.(map[string]any)
+	o, oko := ours.value.(map[string]any)
+	t, okt := theirs.value.(map[string]any)
+	if okb && oko && okt {
+		return mergeSide{m.mergeObject(ptr, b, o, t), true}
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Path:   ptr,
+		Ours:   mergeOperation(ptr, base, ours),
+		Theirs: mergeOperation(ptr, base, theirs),
+	})
+	return ours
+}
+
+func (m *merger) mergeObject(ptr string, base, ours, theirs map[string]any) map[string]any {
+	keys := map[string]any{}
+	for _, o := range []map[string]any{base, ours, theirs} {
+		for k := range o {
+			keys[k] = nil
+		}
+	}
+	merged := make(map[string]any, len(keys))
+	for _, k := range sortedKeys(keys) {
+		b, okb := base[k]
+		o, oko := ours[k]
+		t, okt := theirs[k]
+		v := m.merge(ptr+"/"+EscapeToken(k), mergeSide{b, okb}, mergeSide{o, oko}, mergeSide{t, okt})
+		if v.ok {
+			merged[k] = v.value
+		}
+	}
+	return merged
+}
+
+// mergeOperation returns the operation changes base to side at ptr.
+func mergeOperation(ptr string, base, side mergeSide) Operation {
+	switch {
+	case !side.ok:
+		return newOperation(opRemove, ptr, nil, nil)
+	case !base.ok:
+		return newOperation(opAdd, ptr, deepCopy(side.value), nil)
+	default:
+		return newOperation(opReplace, ptr, deepCopy(side.value), nil)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		base, ours, theirs string
+		merged             string
+		conflicts          string
+	}{
+		{`{"a":1,"b":1}`, `{"a":2,"b":1}`, `{"a":1,"b":2}`, `{"a":2,"b":2}`, `null`},
+		{`{"a":1,"b":1}`, `{"b":1}`, `{"a":1,"b":1,"c":1}`, `{"b":1,"c":1}`, `null`},
+		{`{"a":{"x":1}}`, `{"a":{"x":1,"y":1}}`, `{"a":{"x":2}}`, `{"a":{"x":2,"y":1}}`, `null`},
+		{`{"a":1}`, `{"a":2}`, `{"a":2}`, `{"a":2}`, `null`},
+		{`{"a":[1]}`, `{"a":[1,2]}`, `{"a":[0,1]}`, `{"a":[1,2]}`,
+			`[{"Path":"/a","Ours":{"op":"replace","path":"/a","value":[1,2]},"Theirs":{"op":"replace","path":"/a","value":[0,1]}}]`},
+		{`{"a":1}`, `{}`, `{"a":2}`, `{}`,
+			`[{"Path":"/a","Ours":{"op":"remove","path":"/a"},"Theirs":{"op":"replace","path":"/a","value":2}}]`},
+		{`{}`, `{"a":1}`, `{"a":2}`, `{"a":1}`,
+			`[{"Path":"/a","Ours":{"op":"add","path":"/a","value":1},"Theirs":{"op":"add","path":"/a","value":2}}]`},
+		{`1`, `2`, `3`, `2`,
+			`[{"Path":"","Ours":{"op":"replace","path":"","value":2},"Theirs":{"op":"replace","path":"","value":3}}]`},
+	}
+	for _, c := range cases {
+		merged, conflicts, err := Merge([]byte(c.base), []byte(c.ours), []byte(c.theirs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(merged)); got != c.merged {
+			t.Fatal(c.base, c.ours, c.theirs, "expected", c.merged, "got", got)
+		}
+		if got := strings.TrimSpace(jsonstring(conflicts)); got != c.conflicts {
+			t.Fatal(c.base, c.ours, c.theirs, "expected", c.conflicts, "got", got)
+		}
+	}
+	if _, _, err := Merge([]byte(`{}`), []byte(`{`), []byte(`{}`)); err == nil {
+		t.Fatal("expected error of bad json")
+	}
+}
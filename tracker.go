@@ -0,0 +1,260 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WithOriginalArrayIndex set the OriginalArrayIndex option.
+// The default value is false.
+// If OriginalArrayIndex is true, a "#N" token of an array in paths refers to the element at index N
+// when the array is first touched by the patch, e.g. "/items/#3" stays on the same element
+// after earlier operations inserted or removed elements before it.
+// Referencing a removed element returns ErrNotExists.
+// Only the standard operations are tracked, other operations forget the arrays containing their paths.
+// The raw engine is not used if OriginalArrayIndex is true.
+func WithOriginalArrayIndex(on bool) Option {
+	return func(o *Patch) {
+		o.OriginalArrayIndex = on
+	}
+}
+
+// indexTracker tracks the elements of arrays during a patch.
+type indexTracker struct {
+	// arrays are keyed by the current pointers of the arrays,
+	// the values are the current indices of the original elements, -1 if removed.
+	arrays map[string][]int
+}
+
+func newIndexTracker() *indexTracker {
+	return &indexTracker{arrays: map[string][]int{}}
+}
+
+func (t *indexTracker) get(array string, size int) []int {
+	idx, ok := t.arrays[array]
+	if !ok {
+		idx = make([]int, size)
+		for i := range idx {
+			idx[i] = i
+		}
+		t.arrays[array] = idx
+	}
+	return idx
+}
+
+// translate replaces the "#N" tokens in the paths of op by the current indices.
+func (t *indexTracker) translate(p *Patch, o *any, op Operation) (Operation, error) {
+	if op.Path != nil {
+		path, err := t.resolve(p, o, *op.Path)
+		if err != nil {
+			return op, err
+		}
+		op.Path = &path
+	}
+	if op.From != nil {
+		from, err := t.resolve(p, o, *op.From)
+		if err != nil {
+			return op, err
+		}
+		op.From = &from
+	}
+	return op, nil
+}
+
+func (t *indexTracker) resolve(p *Patch, o *any, ptr string) (string, error) {
+	if !strings.Contains(ptr, "/#") {
+		return ptr, nil
+	}
+	tokens := NewJSONPointer(ptr).Path()
+	for i, token := range tokens {
+		if !strings.HasPrefix(token, "#") {
+			continue
+		}
+		c, _, err := p.VisitPath(o, tokens[:i]...)
+		if err != nil {
+			break
+		}
+		a, ok := c.([]any)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(token[1:])
+		if err != nil || n < 0 {
+			return "", errBadArrayIndex(token)
+		}
+		idx := t.get(joinPointer(tokens[:i]), len(a))
+		if n >= len(idx) || idx[n] < 0 {
+			return "", errPathNotExists(ptr, ErrNotExists)
+		}
+		tokens[i] = strconv.Itoa(idx[n])
+	}
+	return joinPointer(tokens), nil
+}
+
+// arrayElement returns the pointer of the array and the index if ptr is an element of an array in o.
+// If insert is true, ptr is an element just inserted.
+func (t *indexTracker) arrayElement(p *Patch, o *any, ptr string, insert bool) (string, int, bool) {
+	parts := NewJSONPointer(ptr)
+	if parts.IsTheWholeDocument() {
+		return "", 0, false
+	}
+	c, _, err := p.VisitPath(o, parts.ParentPath()...)
+	if err != nil {
+		return "", 0, false
+	}
+	a, ok := c.([]any)
+	if !ok {
+		return "", 0, false
+	}
+	size := len(a)
+	if insert {
+		size--
+	}
+	i, err := p.ParseArrayIndex(size, parts.LastToken())
+	if err != nil {
+		return "", 0, false
+	}
+	return joinPointer(parts.ParentPath()), i, true
+}
+
+// trackedElement is the array element removed by an operation, recorded before the operation is applied.
+type trackedElement struct {
+	array string
+	index int
+	ok    bool
+}
+
+// before records the element removed by op and starts tracking its array.
+func (t *indexTracker) before(p *Patch, o *any, op Operation) trackedElement {
+	var e trackedElement
+	switch *op.OP {
+	case opRemove:
+		e.array, e.index, e.ok = t.arrayElement(p, o, *op.Path, false)
+	case opMove:
+		e.array, e.index, e.ok = t.arrayElement(p, o, *op.From, false)
+	}
+	if e.ok {
+		if c, _, err := p.VisitPath(o, NewJSONPointer(e.array).Path()...); err == nil {
+			t.get(e.array, len(c.([]any)))
+		}
+	}
+	return e
+}
+
+// after records the effect of the applied op.
+func (t *indexTracker) after(p *Patch, o *any, op Operation, before trackedElement) {
+	path := *op.Path
+	if isTestOP(*op.OP) {
+		return
+	}
+	switch *op.OP {
+	case opAdd, opCopy:
+		t.inserted(p, o, path)
+	case opRemove:
+		t.removed(path, before)
+	case opReplace:
+		t.drop(path)
+	case opMove:
+		if *op.From == path {
+			return
+		}
+		sub := t.extract(*op.From)
+		orig := -1
+		if before.ok {
+			for k, cur := range t.arrays[before.array] {
+				if cur == before.index {
+					orig = k
+				}
+			}
+		}
+		t.removed(*op.From, before)
+		array, i, ok := t.inserted(p, o, path)
+		if ok && orig >= 0 && array == before.array {
+			// moved inside of the same array.
+			t.arrays[array][orig] = i
+		}
+		for suffix, idx := range sub {
+			t.arrays[path+suffix] = idx
+		}
+	default:
+		parts := NewJSONPointer(path)
+		if parts.IsTheWholeDocument() {
+			t.drop("")
+		} else {
+			t.drop(joinPointer(parts.ParentPath()))
+		}
+	}
+}
+
+func (t *indexTracker) inserted(p *Patch, o *any, path string) (string, int, bool) {
+	array, i, ok := t.arrayElement(p, o, path, true)
+	if !ok {
+		t.drop(path)
+		return "", 0, false
+	}
+	if c, _, err := p.VisitPath(o, NewJSONPointer(array).Path()...); err == nil {
+		t.get(array, len(c.([]any))-1)
+	}
+	t.shift(array, i, 1)
+	return array, i, true
+}
+
+func (t *indexTracker) removed(path string, before trackedElement) {
+	t.drop(path)
+	if before.ok {
+		t.shift(before.array, before.index, -1)
+	}
+}
+
+// shift moves the elements of array from index i by delta.
+// If delta is negative, the element at i is removed.
+func (t *indexTracker) shift(array string, i, delta int) {
+	idx := t.arrays[array]
+	for k, cur := range idx {
+		switch {
+		case delta < 0 && cur == i:
+			idx[k] = -1
+		case cur >= i && cur >= 0:
+			idx[k] = cur + delta
+		}
+	}
+	moved := map[string][]int{}
+	for key, v := range t.arrays {
+		if !strings.HasPrefix(key, array+"/") {
+			continue
+		}
+		token, tail, _ := strings.Cut(key[len(array)+1:], "/")
+		j, err := strconv.Atoi(token)
+		if err != nil || j < i {
+			continue
+		}
+		delete(t.arrays, key)
+		if tail != "" {
+			tail = "/" + tail
+		}
+		moved[array+"/"+strconv.Itoa(j+delta)+tail] = v
+	}
+	for key, v := range moved {
+		t.arrays[key] = v
+	}
+}
+
+// extract removes and returns the arrays under ptr keyed by their pointers relative to ptr.
+func (t *indexTracker) extract(ptr string) map[string][]int {
+	sub := map[string][]int{}
+	for key, v := range t.arrays {
+		if isPathPrefix(ptr, key) {
+			sub[key[len(ptr):]] = v
+			delete(t.arrays, key)
+		}
+	}
+	return sub
+}
+
+func (t *indexTracker) drop(ptr string) {
+	t.extract(ptr)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOriginalArrayIndex(t *testing.T) {
+	p := New(WithOriginalArrayIndex(true))
+	doc := []byte(`{"a":[0,1,2,3,[10,11]],"b":{"#1":"x"}}`)
+	cases := []struct {
+		ops      string
+		expected string
+	}{
+		{
+			`[{"op":"add","path":"/a/0","value":"n"},{"op":"replace","path":"/a/#2","value":"two"}]`,
+			`{"a":["n",0,1,"two",3,[10,11]],"b":{"#1":"x"}}`,
+		},
+		{
+			`[{"op":"remove","path":"/a/0"},{"op":"remove","path":"/a/#1"},{"op":"test","path":"/a/#3","value":3}]`,
+			`{"a":[2,3,[10,11]],"b":{"#1":"x"}}`,
+		},
+		{
+			`[{"op":"move","from":"/a/#4","path":"/a/0"},{"op":"add","path":"/a/#4/#0","value":9},{"op":"remove","path":"/a/#4/#1"}]`,
+			`{"a":[[9,10],0,1,2,3],"b":{"#1":"x"}}`,
+		},
+		{
+			`[{"op":"copy","from":"/a/#3","path":"/a/1"},{"op":"move","from":"/a/#0","path":"/a/2"},{"op":"test","path":"/a/#0","value":0},{"op":"replace","path":"/b/#1","value":"y"}]`,
+			`{"a":[3,1,0,2,3,[10,11]],"b":{"#1":"y"}}`,
+		},
+	}
+	for _, c := range cases {
+		b, err := p.Apply(doc, mustOperations(t, c.ops))
+		if err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if string(b) != c.expected+"\n" {
+			t.Fatal(c.ops, "expected", c.expected, "got", string(b))
+		}
+	}
+	_, err := New(WithOriginalArrayIndex(true), WithStrictPathExists(true)).Apply(doc, mustOperations(t, `[
+		{"op":"remove","path":"/a/1"},
+		{"op":"replace","path":"/a/#1","value":1}
+	]`))
+	if !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected ErrNotExists, got", err)
+	}
+	_, err = New().Apply(doc, mustOperations(t, `[{"op":"remove","path":"/a/#1"}]`))
+	if err == nil {
+		t.Fatal("expected error without the option")
+	}
+}
+
+func TestOriginalArrayIndexRandom(t *testing.T) {
+	var doc any = map[string]any{"a": []any{1.0, []any{2.0, 3.0}, map[string]any{"b": []any{4.0}}}}
+	for seed := int64(0); seed < 100; seed++ {
+		ops := GenerateRandomPatch(doc, 10, seed)
+		expected := deepCopy(doc)
+		if err := New().ApplyAny(&expected, copyOperations(ops)); err != nil {
+			t.Fatal(err)
+		}
+		got := deepCopy(doc)
+		if err := New(WithOriginalArrayIndex(true)).ApplyAny(&got, copyOperations(ops)); err != nil {
+			t.Fatal("seed", seed, err)
+		}
+		if jsonstring(got) != jsonstring(expected) {
+			t.Fatal("seed", seed, "expected", jsonstring(expected), "got", jsonstring(got))
+		}
+	}
+}
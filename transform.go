@@ -0,0 +1,19 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+// ValueTransformer returns the value to write at path instead of v.
+// v is a copy, so it can be modified in place.
+type ValueTransformer func(path JSONPointer, v any) (any, error)
+
+// WithValueTransformer add a transformer of the values written by add, replace, copy and move.
+// Transformers are called in the order they are added, an error stops the operation.
+// path is the path of the operation, it may be "-" for arrays.
+// The raw engine is not used if any transformer is added.
+func WithValueTransformer(fn ValueTransformer) Option {
+	return func(o *Patch) {
+		o.transformers = append(o.transformers, fn)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValueTransformer(t *testing.T) {
+	errNegative := errors.New("negative")
+	trim := func(_ JSONPointer, v any) (any, error) {
+		if s, ok := v.(string); ok {
+			return strings.TrimSpace(s), nil
+		}
+		return v, nil
+	}
+	var paths []string
+	check := func(path JSONPointer, v any) (any, error) {
+		paths = append(paths, path.String())
+		if n, ok := v.(float64); ok && n < 0 {
+			return nil, errNegative
+		}
+		return v, nil
+	}
+	p := New(WithValueTransformer(trim), WithValueTransformer(check), WithRawEngine(true))
+	doc := []byte(`{"a":" x ","arr":[" y "]}`)
+	got, err := p.Apply(doc, mustOperations(t, `[
+		{"op":"add","path":"/b","value":" b "},
+		{"op":"replace","path":"/a","value":" a "},
+		{"op":"copy","from":"/arr/0","path":"/c"},
+		{"op":"move","from":"/arr/0","path":"/arr/-"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(string(got)); s != `{"a":"a","arr":["y"],"b":"b","c":"y"}` {
+		t.Fatal("bad output", s)
+	}
+	if s := strings.Join(paths, ","); s != "/b,/a,/c,/arr/-" {
+		t.Fatal("bad paths", s)
+	}
+	_, err = p.Apply(doc, mustOperations(t, `[{"op":"add","path":"/n","value":-1}]`))
+	if !errors.Is(err, errNegative) {
+		t.Fatal("expected transformer error, got", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ErrTypeMismatch is returned if a value written by add, replace, copy or move has an unexpected type.
+var ErrTypeMismatch = errors.New("type mismatch")
+
+// JSONType is the type of a json value.
+type JSONType string
+
+// JSON types.
+const (
+	TypeNull    JSONType = "null"
+	TypeBoolean JSONType = "boolean"
+	TypeNumber  JSONType = "number"
+	TypeString  JSONType = "string"
+	TypeObject  JSONType = "object"
+	TypeArray   JSONType = "array"
+)
+
+// TypeOf returns the JSONType of a decoded json value, or an empty string if v is not a json value.
+func TypeOf(v any) JSONType {
+	switch v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case float64:
+		return TypeNumber
+	case string:
+		return TypeString
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	default:
+		return ""
+	}
+}
+
+type typeRule struct {
+	pattern []string
+	typ     JSONType
+}
+
+// WithTypes set the expected types of the values written by add, replace, copy and move.
+// The keys of types are json pointers, a "*" token matches any token.
+// The written values and their descendants are checked after the value transformers,
+// and ErrTypeMismatch is returned on mismatch.
+// If coerce is true, strings are converted to numbers or booleans, and numbers or booleans are converted to strings
+// before the check, e.g. "5" becomes 5 for a number.
+// The raw engine is not used if types is not empty.
+func WithTypes(types map[string]JSONType, coerce bool) Option {
+	return func(o *Patch) {
+		o.types = nil
+		for _, k := range sortedTypeKeys(types) {
+			o.types = append(o.types, typeRule{pattern: NewJSONPointer(k).Path(), typ: types[k]})
+		}
+		o.coerce = coerce
+	}
+}
+
+func sortedTypeKeys(types map[string]JSONType) []string {
+	keys := make([]string, 0, len(types))
+	for k := range types {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hooksWrites returns true if values written by operations are transformed or checked.
+func (p *Patch) hooksWrites() bool {
+	return len(p.types) > 0 || len(p.transformers) > 0
+}
+
+// writeValue returns the value to write at path.
+func (p *Patch) writeValue(path string, v any) (any, error) {
+	if !p.hooksWrites() {
+		return v, nil
+	}
+	v = deepCopy(v)
+	for _, fn := range p.transformers {
+		var err error
+		if v, err = fn(NewJSONPointer(path), v); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.checkType(NewJSONPointer(path).Path(), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (p *Patch) checkType(tokens []string, v *any) error {
+	for _, r := range p.types {
+		if !matchTokens(r.pattern, tokens) {
+			continue
+		}
+		if p.coerce {
+			*v = coerce(*v, r.typ)
+		}
+		if t := TypeOf(*v); t != r.typ {
+			return fmt.Errorf("%w: %s expected %s, got %s", ErrTypeMismatch, joinPointer(tokens), r.typ, t)
+		}
+	}
+	switch c := (*v).(type) {
+	case map[string]any:
+		for k := range c {
+			e := c[k]
+			if err := p.checkType(append(tokens[:len(tokens):len(tokens)], k), &e); err != nil {
+				return err
+			}
+			c[k] = e
+		}
+	case []any:
+		for i := range c {
+			if err := p.checkType(append(tokens[:len(tokens):len(tokens)], strconv.Itoa(i)), &c[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func coerce(v any, t JSONType) any {
+	switch t {
+	case TypeNumber:
+		if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+		}
+	case TypeBoolean:
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	case TypeString:
+		switch v := v.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	}
+	return v
+}
+
+func joinPointer(tokens []string) string {
+	var s string
+	for _, t := range tokens {
+		s += "/" + EscapeToken(t)
+	}
+	return s
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithTypes(t *testing.T) {
+	types := map[string]JSONType{
+		"/age":          TypeNumber,
+		"/items/*/done": TypeBoolean,
+		"/name":         TypeString,
+	}
+	ops := mustOperations(t, `[
+		{"op":"add","path":"/age","value":"5"},
+		{"op":"replace","path":"/name","value":12},
+		{"op":"add","path":"/items/-","value":{"done":"true"}}
+	]`)
+	doc := []byte(`{"age":1,"name":"x","items":[]}`)
+	b, err := New(WithTypes(types, true)).Apply(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"age":5,"items":[{"done":true}],"name":"12"}`+"\n" {
+		t.Fatal("unexpected document", string(b))
+	}
+	if *ops[0].Value != "5" {
+		t.Fatal("operation is modified")
+	}
+
+	_, err = New(WithTypes(types, false)).Apply(doc, ops)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatal("expected ErrTypeMismatch, got", err)
+	}
+	_, err = New(WithTypes(types, true), WithRawEngine(true)).Apply(doc, mustOperations(t, `[
+		{"op":"add","path":"/items/0","value":{"done":"maybe"}}
+	]`))
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatal("expected ErrTypeMismatch, got", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"fmt"
+)
+
+// ConflictError is returned if the version of a document is not the expected one.
+type ConflictError struct {
+	Expected int64
+	Actual   int64
+}
+
+// Error implements error.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict: expected %d, actual %d", e.Expected, e.Actual)
+}
+
+// Is returns true if target is ErrVersionConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// VersionedDocument is a document with a version for optimistic concurrency control.
+type VersionedDocument struct {
+	Version int64
+	Doc     any
+	// VersionPath is a json pointer where the version is mirrored inside of the document.
+	// If it is not empty, ApplyPatch prepends a test of the expected version and appends
+	// a replace of the new version, the patch itself can not write to it.
+	VersionPath string
+	// Patch is used to apply patches, New() is used if it is nil.
+	Patch *Patch
+}
+
+// ApplyPatch applies ops if the document is at expectedVersion and increases the version.
+// A *ConflictError is returned if the version does not match.
+// The document is not modified if an error is returned.
+func (d *VersionedDocument) ApplyPatch(expectedVersion int64, ops []Operation) error {
+	if d.Version != expectedVersion {
+		return &ConflictError{Expected: expectedVersion, Actual: d.Version}
+	}
+	p := d.Patch
+	if p == nil {
+		p = New()
+	}
+	if d.VersionPath != "" {
+		var err error
+		if ops, err = d.guardVersion(expectedVersion, ops); err != nil {
+			return err
+		}
+	}
+	doc, _, err := p.Simulate(d.Doc, ops)
+	if err != nil {
+		return err
+	}
+	d.Doc = doc
+	d.Version++
+	return nil
+}
+
+func (d *VersionedDocument) guardVersion(expected int64, ops []Operation) ([]Operation, error) {
+	for _, op := range ops {
+		if op.OP == nil || op.Path == nil {
+			continue
+		}
+		if *op.OP == opTest {
+			if *op.Path != d.VersionPath || op.Value == nil {
+				continue
+			}
+			if v, ok := (*op.Value).(float64); !ok || v != float64(expected) {
+				return nil, &ConflictError{Expected: expected, Actual: d.Version}
+			}
+			continue
+		}
+		if isTestOP(*op.OP) {
+			continue
+		}
+		if isPathPrefix(*op.Path, d.VersionPath) || (*op.OP == opMove && op.From != nil && isPathPrefix(*op.From, d.VersionPath)) {
+			return nil, fmt.Errorf("operation %s %s can not write to the version path %s", *op.OP, *op.Path, d.VersionPath)
+		}
+	}
+	r := make([]Operation, 0, len(ops)+2)
+	r = append(r, newOperation(opTest, d.VersionPath, float64(expected), nil))
+	r = append(r, ops...)
+	r = append(r, newOperation(opReplace, d.VersionPath, float64(expected+1), nil))
+	return r, nil
+}
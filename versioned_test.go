@@ -0,0 +1,47 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestVersionedDocument(t *testing.T) {
+	var ops []Operation
+	if err := json.Unmarshal([]byte(`[{"op":"add","path":"/a","value":1}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	d := &VersionedDocument{
+		Version:     3,
+		Doc:         map[string]any{"version": 3.0},
+		VersionPath: "/version",
+	}
+	var conflict *ConflictError
+	if err := d.ApplyPatch(2, ops); !errors.As(err, &conflict) || conflict.Actual != 3 {
+		t.Fatal("expected conflict, got", err)
+	}
+	if err := d.ApplyPatch(3, ops); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]any{"version": 4.0, "a": 1.0}
+	if d.Version != 4 || !reflect.DeepEqual(d.Doc, expect) {
+		t.Fatal("expected", expect, "got", d.Version, d.Doc)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"op":"remove","path":"/version"}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ApplyPatch(4, ops); err == nil {
+		t.Fatal("expected error writing the version path")
+	}
+	if err := json.Unmarshal([]byte(`[{"op":"test","path":"/version","value":3}]`), &ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ApplyPatch(4, ops); !errors.As(err, &conflict) {
+		t.Fatal("expected conflict, got", err)
+	}
+}
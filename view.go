@@ -0,0 +1,105 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"strings"
+)
+
+// View is a read only view of a document with a pending patch layered over it.
+// Reads of nodes untouched by the patch are served from the base document,
+// the patched document is materialized only when a touched node is read.
+// It is not safe for concurrent use.
+type View struct {
+	p    *Patch
+	base any
+	ops  []Operation
+	// writes are the pointers written by ops, the root is written in place of a pattern.
+	writes  []string
+	patched any
+	err     error
+	done    bool
+}
+
+// NewView creates a View of ops over base.
+// Neither base nor values returned by the view may be modified.
+func (p *Patch) NewView(base any, ops []Operation) (*View, error) {
+	if err := p.Check(ops); err != nil {
+		return nil, err
+	}
+	converted, err := p.fromURIFragments(ops)
+	if err != nil {
+		return nil, p.localize(err)
+	}
+	v := &View{p: p, base: base, ops: ops}
+	for _, op := range converted {
+		for _, w := range writePaths(op) {
+			// a pattern may match any node once the earlier operations are applied.
+			if p.hasPattern(w) {
+				w = ""
+			}
+			v.writes = append(v.writes, w)
+		}
+	}
+	return v, nil
+}
+
+// Get returns the value at pointer as if the patch is applied.
+// It returns the error of the patch if the patch is materialized and fails.
+func (v *View) Get(pointer string) (any, error) {
+	ptr := NewJSONPointer(pointer)
+	if err := ptr.Check(); err != nil {
+		return nil, err
+	}
+	doc := v.base
+	if v.touched(pointer) {
+		var err error
+		if doc, err = v.Result(); err != nil {
+			return nil, err
+		}
+	}
+	value, _, err := v.p.VisitPath(&doc, ptr.Path()...)
+	if err != nil {
+		return nil, errPathNotExists(pointer, err)
+	}
+	return value, nil
+}
+
+// Result materializes and returns the patched document.
+func (v *View) Result() (any, error) {
+	if !v.done {
+		v.patched, _, v.err = v.p.Simulate(v.base, v.ops)
+		v.done = true
+	}
+	return v.patched, v.err
+}
+
+// touched returns true if the node at ptr may be changed by the patch.
+// An operation touches its ancestors, its descendants and,
+// for arrays, the siblings shifted by it.
+func (v *View) touched(ptr string) bool {
+	if v.done {
+		return true
+	}
+	for _, w := range v.writes {
+		if isPathPrefix(w, ptr) || isPathPrefix(ptr, w) {
+			return true
+		}
+		parent := w[:strings.LastIndex(w, "/")]
+		if !isPathPrefix(parent, ptr) {
+			continue
+		}
+		// the type of the parent in base is valid unless an operation writes the parent,
+		// which is caught by the prefix check above.
+		c, _, err := v.p.VisitPath(&v.base, NewJSONPointer(parent).Path()...)
+		if err != nil {
+			return true
+		}
+		if _, ok := c.(map[string]any); !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestView(t *testing.T) {
+	var base any
+	if err := json.Unmarshal([]byte(`{"a":{"b":1,"c":2},"d":[1,2,3],"e":"f"}`), &base); err != nil {
+		t.Fatal(err)
+	}
+	origin := deepCopy(base)
+	v, err := New().NewView(base, mustOperations(t, `[
+		{"op":"replace","path":"/a/b","value":10},
+		{"op":"add","path":"/a/g","value":true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ptr, expected := range map[string]any{"/a/c": 2.0, "/e": "f", "/d/1": 2.0} {
+		got, err := v.Get(ptr)
+		if err != nil || got != expected {
+			t.Fatal("bad value", ptr, got, err)
+		}
+	}
+	if v.done {
+		t.Fatal("expected not materialized")
+	}
+	if got, err := v.Get("/a/b"); err != nil || got != 10.0 {
+		t.Fatal("bad value", got, err)
+	}
+	if got, err := v.Get("/a"); err != nil || !reflect.DeepEqual(got, map[string]any{"b": 10.0, "c": 2.0, "g": true}) {
+		t.Fatal("bad value", got, err)
+	}
+	if !reflect.DeepEqual(base, origin) {
+		t.Fatal("base is modified")
+	}
+
+	v, err = New().NewView(base, mustOperations(t, `[{"op":"remove","path":"/d/0"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("/d/1"); err != nil || got != 3.0 {
+		t.Fatal("expected shifted array element", got, err)
+	}
+	if _, err := v.Get("/d/2"); !errors.Is(err, ErrNotExists) {
+		t.Fatal("expected ErrNotExists, got", err)
+	}
+
+	v, err = New().NewView(base, mustOperations(t, `[{"op":"test","path":"/e","value":"x"},{"op":"add","path":"/a/x","value":1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("/e"); err != nil || got != "f" {
+		t.Fatal("bad value", got, err)
+	}
+	if _, err := v.Get("/a/x"); !errors.Is(err, ErrStop) {
+		t.Fatal("expected patch error, got", err)
+	}
+}
+
+func TestViewPatterns(t *testing.T) {
+	var base any
+	if err := json.Unmarshal([]byte(`{"items":[{"x":1},{"x":2}],"a":{"b":1}}`), &base); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		p      *Patch
+		ops    string
+		ptr    string
+		expect any
+	}{
+		{New(WithWildcards(true)), `[{"op":"replace","path":"/items/*/x","value":0}]`, "/items/0/x", 0.0},
+		{New(WithURIFragment(true)), `[{"op":"replace","path":"#/a/b","value":2}]`, "/a/b", 2.0},
+		{New(), `[{"op":"move","from":"/a/b","path":"/c"}]`, "/c", 1.0},
+	}
+	for _, c := range cases {
+		v, err := c.p.NewView(base, mustOperations(t, c.ops))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := v.Get(c.ptr); err != nil || got != c.expect {
+			t.Fatal(c.ops, "bad value", got, err)
+		}
+	}
+}
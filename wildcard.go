@@ -0,0 +1,193 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package jsonpatch
+
+import (
+	"errors"
+	"strconv"
+)
+
+var errPatternFrom = errors.New("from must not match multiple locations")
+
+// WithWildcards set the Wildcards option.
+// The default value is false.
+// If Wildcards is true, a "*" token of a path matches every member of an object or element of an array,
+// e.g. remove "/users/*/password" removes the password of all users.
+// An operation is applied to every match from the last one, so array removes do not shift the other matches,
+// and does nothing if nothing matches. The last token of a path may be a new member, like add "/users/*/flag",
+// and matches without the last member are skipped even if StrictPathExists is true.
+// from of move and copy must not contain wildcards.
+// The number of matches is limited by the MaxFanout option.
+// Objects can not have a member named "*" be patched if Wildcards is true.
+func WithWildcards(on bool) Option {
+	return func(o *Patch) {
+		o.Wildcards = on
+	}
+}
+
+// WithRecursiveWildcards set the RecursiveWildcards option.
+// The default value is false.
+// If RecursiveWildcards is true, a "**" token of a path matches any depth including zero,
+// e.g. remove "/**/debug" removes every debug member anywhere in the document.
+// Matches are applied as the matches of "*", see WithWildcards.
+// Objects can not have a member named "**" be patched if RecursiveWildcards is true.
+func WithRecursiveWildcards(on bool) Option {
+	return func(o *Patch) {
+		o.RecursiveWildcards = on
+	}
+}
+
+// ExpandPath returns the pointers of doc matched by path in document order,
+// object members are visited in key order.
+// A path without patterns is returned as is.
+func (p *Patch) ExpandPath(doc any, path string) ([]string, error) {
+	if p.isJSONPath(path) {
+		jp, err := compileJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		nodes := jp.eval(doc, true)
+		r := make([]string, len(nodes))
+		for i, n := range nodes {
+			r[i] = n.path
+		}
+		return uniqueStrings(r), nil
+	}
+	ptr := NewJSONPointer(path)
+	if err := ptr.Check(); err != nil {
+		return nil, err
+	}
+	if !p.hasPattern(path) {
+		return []string{path}, nil
+	}
+	var r []string
+	p.expand(doc, "", ptr.Path(), &r)
+	if p.RecursiveWildcards {
+		r = uniqueStrings(r)
+	}
+	return r, nil
+}
+
+// hasPattern returns true if path contains a token matching multiple locations.
+func (p *Patch) hasPattern(path string) bool {
+	if p.isJSONPath(path) {
+		return true
+	}
+	if !p.Wildcards && !p.RecursiveWildcards && !p.ElementSelectors {
+		return false
+	}
+	for _, token := range NewJSONPointer(path).Path() {
+		if p.isPattern(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWildcard returns true if path has a "*" or "**" pattern token.
+func (p *Patch) hasWildcard(path string) bool {
+	for _, token := range NewJSONPointer(path).Path() {
+		if (p.Wildcards && token == "*") || (p.RecursiveWildcards && token == "**") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Patch) isPattern(token string) bool {
+	return p.isSelector(token) || (p.Wildcards && token == "*") || (p.RecursiveWildcards && token == "**")
+}
+
+// expand appends the pointers below node matched by tokens to r, prefix is the pointer of node.
+func (p *Patch) expand(node any, prefix string, tokens []string, r *[]string) {
+	if len(tokens) == 0 {
+		*r = append(*r, prefix)
+		return
+	}
+	token, rest := tokens[0], tokens[1:]
+	switch {
+	case p.RecursiveWildcards && token == "**":
+		p.expand(node, prefix, rest, r)
+		eachChild(node, prefix, func(child any, path string) {
+			p.expand(child, path, tokens, r)
+		})
+	case p.isSelector(token):
+		key, value, _ := parseSelector(token)
+		if _, array := node.([]any); array {
+			eachChild(node, prefix, func(child any, path string) {
+				if matchSelector(child, key, value) {
+					p.expand(child, path, rest, r)
+				}
+			})
+		}
+	case p.isPattern(token):
+		eachChild(node, prefix, func(child any, path string) {
+			p.expand(child, path, rest, r)
+		})
+	case len(rest) == 0:
+		// the last member may not exist yet, but its parent must be able to have it.
+		_, object := node.(map[string]any)
+		_, array := node.([]any)
+		if object || (array && (isIndexToken(token) || p.isSymbolicIndex(token))) {
+			*r = append(*r, prefix+"/"+EscapeToken(token))
+		}
+	default:
+		if child, _, err := p.visitPathPart(node, token); err == nil {
+			p.expand(child, prefix+"/"+EscapeToken(token), rest, r)
+		}
+	}
+}
+
+// eachChild calls fn with the members of an object in key order or the elements of an array.
+func eachChild(node any, prefix string, fn func(child any, path string)) {
+	switch v := node.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			fn(v[k], prefix+"/"+EscapeToken(k))
+		}
+	case []any:
+		for i, e := range v {
+			fn(e, prefix+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+// uniqueStrings removes the duplicates of a, keeping the first one.
+func uniqueStrings(a []string) []string {
+	seen := make(map[string]bool, len(a))
+	r := a[:0]
+	for _, s := range a {
+		if !seen[s] {
+			seen[s] = true
+			r = append(r, s)
+		}
+	}
+	return r
+}
+
+// VisitPaths calls fn with every existing node matched by the path list in document order,
+// it stops at the first error returned by fn.
+// It works as VisitPath for a single node if the path list has no patterns.
+func (p *Patch) VisitPaths(o *any, fn func(path string, node any, set Setter) error, parts ...string) error {
+	pattern := joinPointer(parts)
+	paths, err := p.ExpandPath(*o, pattern)
+	if err != nil {
+		return err
+	}
+	multiple := p.hasPattern(pattern)
+	for _, path := range paths {
+		node, set, err := p.VisitPath(o, NewJSONPointer(path).Path()...)
+		if multiple && errors.Is(err, ErrNotExists) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(path, node, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 hanke. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWildcards(t *testing.T) {
+	doc := []byte(`{"users":[{"name":"a","password":"x"},{"name":"b"},{"name":"c","password":"y"}]}`)
+	cases := []struct {
+		ops    string
+		expect string
+	}{
+		{`[{"op":"remove","path":"/users/*/password"}]`, `{"users":[{"name":"a"},{"name":"b"},{"name":"c"}]}`},
+		{`[{"op":"add","path":"/users/*/flag","value":true}]`,
+			`{"users":[{"flag":true,"name":"a","password":"x"},{"flag":true,"name":"b"},{"flag":true,"name":"c","password":"y"}]}`},
+		{`[{"op":"remove","path":"/users/*"}]`, `{"users":[]}`},
+		{`[{"op":"remove","path":"/nothing/*"}]`, string(doc)},
+	}
+	p := New(WithWildcards(true))
+	for _, c := range cases {
+		b, err := p.Apply(doc, mustOperations(t, c.ops))
+		if err != nil {
+			t.Fatal(c.ops, err)
+		}
+		if ok, err := Equal(b, []byte(c.expect)); err != nil || !ok {
+			t.Fatal(c.ops, "unexpected document", string(b))
+		}
+	}
+
+	_, err := New(WithWildcards(true), WithMaxFanout(2)).Apply(doc, mustOperations(t, `[{"op":"remove","path":"/users/*/name"}]`))
+	var fe *FanoutError
+	if !errors.As(err, &fe) || fe.Matches != 3 || fe.Path != "/users/*/name" {
+		t.Fatal("expected fanout error, got", err)
+	}
+	_, err = p.Apply(doc, mustOperations(t, `[{"op":"copy","from":"/users/*","path":"/a"}]`))
+	if !errors.Is(err, errPatternFrom) {
+		t.Fatal("expected from error, got", err)
+	}
+
+	b, err := New().Apply([]byte(`{"*":1,"a":2}`), mustOperations(t, `[{"op":"remove","path":"/*"}]`))
+	if err != nil || string(b) != `{"a":2}`+"\n" {
+		t.Fatal("* must be a plain key without Wildcards", string(b), err)
+	}
+}
+
+func TestVisitPaths(t *testing.T) {
+	var doc any = map[string]any{"a": map[string]any{"x": 1.0}, "b": map[string]any{"y": 2.0}, "c": map[string]any{"x": 3.0}}
+	var paths []string
+	err := New(WithWildcards(true)).VisitPaths(&doc, func(path string, node any, set Setter) error {
+		paths = append(paths, path)
+		set(node.(float64) * 10)
+		return nil
+	}, "*", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/a/x", "/c/x"}) {
+		t.Fatal("unexpected paths", paths)
+	}
+	if !EqualAny(doc, map[string]any{"a": map[string]any{"x": 10.0}, "b": map[string]any{"y": 2.0}, "c": map[string]any{"x": 30.0}}) {
+		t.Fatal("unexpected document", doc)
+	}
+}
+
+func TestRecursiveWildcards(t *testing.T) {
+	doc := []byte(`{"debug":1,"a":{"debug":{"debug":2},"b":[{"debug":3},4,{"c":5}]}}`)
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		t.Fatal(err)
+	}
+	p := New(WithRecursiveWildcards(true))
+	paths, err := p.ExpandPath(v, "/**/debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"/debug", "/a/debug", "/a/b/0/debug", "/a/b/2/debug", "/a/debug/debug"}
+	if !reflect.DeepEqual(paths, expect) {
+		t.Fatal("unexpected paths", paths)
+	}
+	b, err := p.Apply(doc, mustOperations(t, `[{"op":"remove","path":"/**/debug"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := Equal(b, []byte(`{"a":{"b":[{},4,{"c":5}]}}`)); err != nil || !ok {
+		t.Fatal("unexpected document", string(b))
+	}
+	paths = nil
+	err = p.VisitPaths(&v, func(path string, _ any, _ Setter) error {
+		paths = append(paths, path)
+		return nil
+	}, "**", "**", "c")
+	if err != nil || !reflect.DeepEqual(paths, []string{"/a/b/2/c"}) {
+		t.Fatal("unexpected paths", paths, err)
+	}
+}